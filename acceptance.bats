@@ -18,6 +18,42 @@
   [ "$output" = "PASS - stdin contains a valid ReplicationController (bob)" ]
 }
 
+@test "Validate stdin in its place among files when - is passed with them" {
+  run bash -c "cat fixtures/kustomize/base/deployment.yaml | bin/kubeval --schema-location file://$PWD/fixtures/schemas fixtures/latest_tags.yaml - fixtures/kustomize/base/deployment.yaml"
+  [ "$status" -eq 0 ]
+  [ "${lines[0]}" = "PASS - fixtures/latest_tags.yaml contains a valid Deployment (web)" ]
+  [ "${lines[1]}" = "PASS - stdin contains a valid Deployment (web)" ]
+  [ "${lines[2]}" = "PASS - fixtures/kustomize/base/deployment.yaml contains a valid Deployment (web)" ]
+}
+
+@test "Pass when parsing a valid Kubernetes config YAML file from a process substitution" {
+  run bash -c "bin/kubeval <(cat fixtures/valid.yaml)"
+  [ "$status" -eq 0 ]
+  [[ "$output" == "PASS - /dev/fd/"*" contains a valid ReplicationController (bob)" ]]
+}
+
+@test "Pass when parsing a valid Kubernetes config YAML file from a process substitution passed to --directories" {
+  run bash -c "bin/kubeval -d <(cat fixtures/valid.yaml)"
+  [ "$status" -eq 0 ]
+  [[ "$output" == "PASS - /dev/fd/"*" contains a valid ReplicationController (bob)" ]]
+}
+
+@test "Pass when parsing a valid Kubernetes config YAML file on stdin from a named pipe" {
+  rm -f bin/manifests.fifo
+  mkfifo bin/manifests.fifo
+  cat fixtures/valid.yaml > bin/manifests.fifo &
+  run bash -c "bin/kubeval < bin/manifests.fifo"
+  rm -f bin/manifests.fifo
+  [ "$status" -eq 0 ]
+  [ "$output" = "PASS - stdin contains a valid ReplicationController (bob)" ]
+}
+
+@test "Fail with a relevant error when stdin is closed and no files are passed" {
+  run bash -c "bin/kubeval <&-"
+  [ "$status" -eq 1 ]
+  [[ "$output" == *"You must pass at least one file as an argument"* ]]
+}
+
 @test "Pass when parsing a valid Kubernetes config JSON file" {
   run bin/kubeval fixtures/valid.json
   [ "$status" -eq 0 ]
@@ -176,6 +212,73 @@
   [ "$output" = "PASS - fixtures/valid.yaml contains a valid ReplicationController (bob)" ]
 }
 
+@test "Uses JUnit output and strict schemas when --ci is supplied" {
+  run bin/kubeval --ci fixtures/extra_property.yaml
+  [ "$status" -eq 1 ]
+  [ "${lines[0]}" = '<?xml version="1.0" encoding="UTF-8"?>' ]
+  [ "${lines[1]}" = '<testsuites name="kubeval" tests="1" failures="1" errors="0" skipped="0">' ]
+  [[ "$output" == *'<failure message="DaemonSet (nginx-ds) is invalid">'* ]]
+}
+
+@test "Explicit flags take precedence over --ci" {
+  run bin/kubeval --ci --output stdout fixtures/valid.yaml
+  [ "$status" -eq 0 ]
+  [ "$output" = "PASS - fixtures/valid.yaml contains a valid ReplicationController (bob)" ]
+}
+
+@test "Fail when no files are found and --fail-on-no-files is supplied" {
+  mkdir -p bin/empty
+  run bin/kubeval --fail-on-no-files -d bin/empty
+  [ "$status" -eq 1 ]
+  [ "$output" = "ERR  - No files were found to validate" ]
+}
+
+@test "Fail when an unvalidated resource is found and --exit-on includes unvalidated" {
+  run bin/kubeval --ignore-missing-schemas --exit-on invalid,unvalidated fixtures/test_crd.yaml
+  [ "$status" -eq 1 ]
+}
+
+@test "Return relevant error for an unknown --exit-on status" {
+  run bin/kubeval --exit-on broken fixtures/valid.yaml
+  [ "$status" -eq 1 ]
+  [[ "$output" == "ERR  - Unknown status 'broken' passed to --exit-on"* ]]
+}
+
+@test "Fail when the new side of a diff is invalid and --diff is supplied" {
+  run bash -c "cat fixtures/kubectl_diff.diff | bin/kubeval --diff"
+  [ "$status" -eq 1 ]
+  [[ "$output" == *"apps.v1.Deployment.default.nginx contains an invalid Deployment"* ]]
+}
+
+@test "Validate files in a git repository when --git is supplied" {
+  rm -rf bin/repo
+  mkdir -p bin/repo/manifests
+  cp fixtures/invalid.yaml bin/repo/manifests/
+  git -C bin/repo init --quiet
+  git -C bin/repo add .
+  git -C bin/repo -c user.name=kubeval -c user.email=kubeval@example.com commit --quiet -m "Add manifests"
+  run bin/kubeval --git "file://$PWD/bin/repo" --path manifests
+  [ "$status" -eq 1 ]
+  [[ "$output" == *"manifests/invalid.yaml contains an invalid ReplicationController"* ]]
+}
+
+@test "Return relevant error when a kind is passed to both --only-kinds and --skip-kinds" {
+  run bin/kubeval --only-kinds ReplicationController --skip-kinds ReplicationController fixtures/valid.yaml
+  [ "$status" -eq 1 ]
+  [[ "$output" == "ERR  - Every kind passed to '--only-kinds' is also passed to '--skip-kinds'"* ]]
+}
+
+@test "Append a record of each run to the --audit-log" {
+  rm -f bin/audit.log
+  run bin/kubeval --audit-log bin/audit.log fixtures/invalid.yaml
+  [ "$status" -eq 1 ]
+  run bin/kubeval --audit-log bin/audit.log --skip-kinds ReplicationController fixtures/invalid.yaml
+  [ "$status" -eq 0 ]
+  [ "$(wc -l < bin/audit.log)" -eq 2 ]
+  [[ "$(head -n 1 bin/audit.log)" == *'"success":false'* ]]
+  [[ "$(tail -n 1 bin/audit.log)" == *'"success":true'* ]]
+}
+
 @test "Adjusts help string when invoked as a kubectl plugin" {
   ln -sf kubeval bin/kubectl-kubeval
 
@@ -183,3 +286,156 @@
   [ "$status" -eq 0 ]
   [[ ${lines[2]} == "  kubectl kubeval <file>"* ]]
 }
+
+@test "Return relevant error when serve is run without a socket" {
+  run bin/kubeval serve
+  [ "$status" -eq 1 ]
+  [ "$output" = "ERR  - A path must be passed to --socket" ]
+}
+
+@test "Pass when building a kustomization which uses a component" {
+  command -v kustomize || skip "kustomize is not installed"
+  run bin/kubeval --kustomize fixtures/kustomize/overlays/production --schema-location "file://$PWD/fixtures/schemas"
+  [ "$status" -eq 0 ]
+  [[ "$output" == *"contains a valid Deployment (web)"* ]]
+  [[ "$output" == *"contains a valid Service (web-metrics)"* ]]
+}
+
+@test "Validate the ConfigMaps and Secrets made by the generators of a kustomization" {
+  command -v kustomize || command -v kubectl || skip "neither kustomize nor kubectl is installed"
+  run bin/kubeval --kustomize fixtures/kustomize/overlays/generators --schema-location "file://$PWD/fixtures/schemas"
+  [ "$status" -eq 0 ]
+  [[ "$output" == *"PASS - fixtures/kustomize/overlays/generators contains a valid ConfigMap (web-config-"* ]]
+  [[ "$output" == *"PASS - fixtures/kustomize/overlays/generators contains a valid Secret (web-credentials-"* ]]
+  [[ "$output" == *"PASS - fixtures/kustomize/overlays/generators contains a valid Deployment (web)"* ]]
+}
+
+@test "Return relevant error when both --helm-chart and --kustomize are passed" {
+  run bin/kubeval --helm-chart mychart --kustomize fixtures/kustomize/overlays/production
+  [ "$status" -eq 1 ]
+  [ "$output" = "ERR  - Only one of --helm-chart and --kustomize can be used" ]
+}
+
+@test "Return relevant error when a kustomization references a remote base without --allow-remote-bases" {
+  run bin/kubeval --kustomize fixtures/kustomize/overlays/remote
+  [ "$status" -eq 1 ]
+  [ "$output" = "ERR  - Kustomization fixtures/kustomize/overlays/remote references remote base github.com/kubernetes-sigs/kustomize//examples/multibases?ref=v3.3.1, pass --allow-remote-bases to fetch it" ]
+}
+
+@test "Return relevant error when a kustomization references remote transformers or patches without --allow-remote-bases" {
+  run bin/kubeval --kustomize fixtures/kustomize/overlays/remote-transformer
+  [ "$status" -eq 1 ]
+  [ "${lines[0]}" = "ERR  - Kustomization fixtures/kustomize/overlays/remote-transformer references remote base https://raw.githubusercontent.com/example/platform/v1.0.0/transformers/labels.yaml, pass --allow-remote-bases to fetch it" ]
+  [ "${lines[1]}" = "ERR  - Kustomization fixtures/kustomize/overlays/remote-transformer references remote base https://raw.githubusercontent.com/example/platform/v1.0.0/patches/replicas.yaml, pass --allow-remote-bases to fetch it" ]
+}
+
+@test "Only validate files which changed since the --changed-only manifest was written" {
+  rm -f bin/hashes.json
+  run bin/kubeval --changed-only bin/hashes.json --schema-location "file://$PWD/fixtures/schemas" fixtures/kustomize/base/deployment.yaml
+  [ "$status" -eq 0 ]
+  [ "$output" = "PASS - fixtures/kustomize/base/deployment.yaml contains a valid Deployment (web)" ]
+  run bin/kubeval --changed-only bin/hashes.json --schema-location "file://$PWD/fixtures/schemas" fixtures/kustomize/base/deployment.yaml
+  [ "$status" -eq 0 ]
+  [ "$output" = "" ]
+}
+
+@test "Stop when a schema does not match --schema-checksums" {
+  run bin/kubeval --schema-location "file://$PWD/fixtures/schemas" --schema-checksums fixtures/schema_checksums.txt --ignore-missing-schemas fixtures/recommended_labels.yaml
+  [ "$status" -eq 1 ]
+  [[ "$output" == *"service-v1.json failed checksum verification: expected sha256 0000000000000000000000000000000000000000000000000000000000000000"* ]]
+}
+
+@test "Warn without failing when workloads exceed a ResourceQuota" {
+  run bin/kubeval --check-resource-quotas --ignore-missing-schemas fixtures/resource_quota.yaml
+  [ "$status" -eq 0 ]
+  [[ "$output" == *"WARN - fixtures/resource_quota.yaml: Workloads in namespace 'team-a' need requests.cpu of 2800m, more than ResourceQuota 'compute' allows (2)"* ]]
+}
+
+@test "Warn about changes to immutable fields with --check-immutable-fields" {
+  run bin/kubeval --schema-location "file://$PWD/fixtures/schemas" --check-immutable-fields --immutable-baseline fixtures/immutable_baseline.yaml fixtures/immutable/after.yaml
+  [ "$status" -eq 0 ]
+  [[ "$output" == *"WARN - fixtures/immutable/after.yaml: Service 'web' changes the immutable field spec.clusterIP from \"10.96.0.10\", in fixtures/immutable_baseline.yaml, to \"None\""* ]]
+}
+
+@test "Split documents on a --document-separator" {
+  run bin/kubeval --schema-location "file://$PWD/fixtures/schemas" --document-separator '# ---8<---' fixtures/custom_separator.yaml
+  [ "$status" -eq 0 ]
+  [ "$output" = $'PASS - fixtures/custom_separator.yaml contains a valid Secret (credentials)\nPASS - fixtures/custom_separator.yaml contains a valid Service (web)' ]
+}
+
+@test "Fail resources larger than --max-object-size with --check-object-size" {
+  run bin/kubeval --schema-location "file://$PWD/fixtures/schemas" --check-object-size --max-object-size 1Ki fixtures/object_size.yaml
+  [ "$status" -eq 1 ]
+  [[ "$output" == *"contains an invalid Secret (bundle) - data: Object is 1554 bytes serialized"* ]]
+}
+
+@test "Fail CRDs whose scale subresource refers to a field not in the schema with --check-crd-paths" {
+  run bin/kubeval --check-crd-paths --ignore-missing-schemas fixtures/crd_paths.yaml
+  [ "$status" -eq 1 ]
+  [[ "$output" == *"spec.versions.0.subresources.scale.specReplicasPath: Scale subresource specReplicasPath refers to .spec.replica, which is not a field in the schema of version v1"* ]]
+}
+
+@test "Report the pass rate of each kind with --output kind-report" {
+  run bin/kubeval --schema-location "file://$PWD/fixtures/schemas" -o kind-report fixtures/secret_valid_data.yaml fixtures/secret_invalid_data.yaml
+  [ "$status" -eq 1 ]
+  [ "$output" = $'Secret: 1/2 passed (50.0%), 1 failed\nTotal:  1/2 passed (50.0%), 1 failed' ]
+}
+
+@test "Validate the overlay for the environment passed to --env" {
+  command -v kustomize || skip "kustomize is not installed"
+  run bin/kubeval --kustomize fixtures/kustomize --env production --schema-location "file://$PWD/fixtures/schemas"
+  [ "$status" -eq 0 ]
+  [[ "$output" == *"PASS - production: fixtures/kustomize/overlays/production contains a valid Deployment (web)"* ]]
+}
+
+@test "Return relevant error when there is no overlay for the environment passed to --env" {
+  run bin/kubeval --kustomize fixtures/kustomize --env staging
+  [ "$status" -eq 1 ]
+  [ "$output" = "ERR  - No overlay for environment staging: fixtures/kustomize/overlays/staging is not a directory, see --env-overlay-pattern" ]
+}
+
+@test "Fix trivially fixable issues in place with --fix" {
+  cp fixtures/fixable.yaml "$BATS_TMPDIR/fixable.yaml"
+  run bin/kubeval --fix --ignore-missing-schemas --schema-location "file://$PWD/fixtures/schemas" "$BATS_TMPDIR/fixable.yaml"
+  [ "$status" -eq 0 ]
+  [[ "$output" == *"FIX  - $BATS_TMPDIR/fixable.yaml: line 21: Remove the status of Deployment 'web', which the API server ignores"* ]]
+  [[ "$output" == *"PASS - $BATS_TMPDIR/fixable.yaml contains a valid Deployment (default.web)"* ]]
+  run bin/kubeval --fix-dry-run "$BATS_TMPDIR/fixable.yaml"
+  [ "$status" -eq 0 ]
+  [ "$output" = "" ]
+}
+
+@test "Return relevant error when - is passed to --fix along with directories" {
+  run bash -c "cat fixtures/fixable.yaml | bin/kubeval --fix --filename fixtures/fixable.yaml -d fixtures/kustomize -"
+  [ "$status" -eq 1 ]
+  [ "$output" = "ERR  - --fix writes manifests read from stdin to stdout, so - can only be passed on its own" ]
+  git diff --exit-code fixtures/fixable.yaml
+}
+
+@test "Report fixes without making them with --fix-dry-run" {
+  run bin/kubeval --fix-dry-run fixtures/fixable.yaml
+  [ "$status" -eq 1 ]
+  [[ "$output" == *"FIX  - fixtures/fixable.yaml: line 2: Lower case the apiVersion Apps/V1 of Deployment 'web' to apps/v1"* ]]
+  git diff --exit-code fixtures/fixable.yaml
+}
+
+@test "Warn about containers without resource requests with --check-resource-requests" {
+  run bin/kubeval --check-resource-requests --schema-location "file://$PWD/fixtures/schemas" fixtures/resource_requests.yaml
+  [ "$status" -eq 0 ]
+  [[ "$output" == *"spec.template.spec.containers.2.resources: Container 'metrics' sets no resource requests; add requests for cpu and memory"* ]]
+  run bin/kubeval --check-resource-requests --error-on-keyword resource_requests --schema-location "file://$PWD/fixtures/schemas" fixtures/resource_requests.yaml
+  [ "$status" -eq 1 ]
+}
+
+@test "Validate only the resources in the API groups passed to --api-groups" {
+  run bin/kubeval --api-groups apps,core --schema-location "file://$PWD/fixtures/schemas" fixtures/api_groups.yaml
+  [ "$status" -eq 0 ]
+  [ "${lines[1]}" = "WARN - fixtures/api_groups.yaml containing a Job (web) was not validated against a schema" ]
+  [ "${lines[3]}" = "WARN - Resources skipped as outside the API groups passed to --api-groups (apps, core): 1" ]
+}
+
+@test "Validate Gatekeeper policy resources against the bundled schemas" {
+  run bin/kubeval --schema-location "file://$PWD/fixtures/schemas" fixtures/gatekeeper_policy.yaml
+  [ "$status" -eq 0 ]
+  [ "$output" = $'PASS - fixtures/gatekeeper_policy.yaml contains a valid ConstraintTemplate (k8srequiredlabels)\nPASS - fixtures/gatekeeper_policy.yaml contains a valid K8sRequiredLabels (deployments-must-have-team)' ]
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// environmentPlaceholder is replaced by the environment selected with --env
+// in the patterns locating the overlay or values file for it
+const environmentPlaceholder = "{env}"
+
+// environmentPath returns the path pattern leads to for env, relative to
+// dir, which is the kustomization or chart passed on the command line
+func environmentPath(dir string, pattern string, flag string, env string) (string, error) {
+	if !strings.Contains(pattern, environmentPlaceholder) {
+		return "", fmt.Errorf("The pattern passed to %s must contain %s, got '%s'", flag, environmentPlaceholder, pattern)
+	}
+	return filepath.Join(dir, strings.Replace(pattern, environmentPlaceholder, env, -1)), nil
+}
+
+// environmentOverlay returns the overlay of the kustomization in dir to
+// build for env, found by following pattern, such as overlays/{env}
+func environmentOverlay(dir string, pattern string, env string) (string, error) {
+	overlay, err := environmentPath(dir, pattern, "--env-overlay-pattern", env)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(overlay); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("No overlay for environment %s: %s is not a directory, see --env-overlay-pattern", env, overlay)
+	}
+	return overlay, nil
+}
+
+// environmentValues returns the values file of the chart in dir to render
+// it with for env, found by following pattern, such as values-{env}.yaml
+func environmentValues(dir string, pattern string, env string) (string, error) {
+	values, err := environmentPath(dir, pattern, "--env-values-pattern", env)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(values); err != nil || info.IsDir() {
+		return "", fmt.Errorf("No values file for environment %s: %s is not a file, see --env-values-pattern", env, values)
+	}
+	return values, nil
+}
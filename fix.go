@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/instrumenta/kubeval/kubeval"
+	"github.com/instrumenta/kubeval/log"
+)
+
+// checkFixFlags returns an error if --fix or --fix-dry-run are combined
+// with inputs they cannot fix, as there are no files to write them to
+func checkFixFlags(args []string) error {
+	if !fix && !fixDryRun {
+		return nil
+	}
+	if fix && fixDryRun {
+		return errors.New("Only one of --fix and --fix-dry-run can be used")
+	}
+	switch {
+	case helmChart != "" || kustomization != "":
+		return errors.New("--fix and --fix-dry-run fix manifests in files, so cannot be used with --helm-chart or --kustomize, which render them")
+	case gitURL != "":
+		return errors.New("--fix and --fix-dry-run fix manifests in files, so cannot be used with --git")
+	case diffInput:
+		return errors.New("--fix and --fix-dry-run fix manifests, so cannot be used with --diff")
+	case len(args) > 1 || len(directories) > 0:
+		for _, arg := range args {
+			if arg == "-" {
+				return errors.New("--fix writes manifests read from stdin to stdout, so - can only be passed on its own")
+			}
+		}
+	}
+	return nil
+}
+
+// reportFixes reports the fixes made to the manifests in the named file
+func reportFixes(name string, fixes []kubeval.Fix) {
+	for _, f := range fixes {
+		log.Fix(fmt.Sprintf("%s:", name), f.String())
+	}
+}
+
+// fixFiles makes the fixes of kubeval.FixManifests to the manifests in
+// files, reporting each of them. With --fix each file changed is written
+// back, while with --fix-dry-run nothing is written. The files are returned
+// with their fixed contents, to validate, along with the number of fixes.
+func fixFiles(files []kubeval.File) ([]kubeval.File, int, error) {
+	count := 0
+	fixed := make([]kubeval.File, 0, len(files))
+	for _, file := range files {
+		contents, err := file.Read()
+		if err != nil {
+			// left for validation to report
+			fixed = append(fixed, file)
+			continue
+		}
+		contents, fixes := kubeval.FixManifests(contents, config)
+		reportFixes(file.Name, fixes)
+		count += len(fixes)
+		// Only files read from disk are written back, as stdin is named
+		// by --filename, which may well name a file on disk too
+		if fix && len(fixes) > 0 && !file.Stdin {
+			info, err := os.Stat(file.Name)
+			if err != nil {
+				return nil, count, err
+			}
+			if err := ioutil.WriteFile(file.Name, contents, info.Mode()); err != nil {
+				return nil, count, fmt.Errorf("Failed to write the fixes to %s: %s", file.Name, err)
+			}
+		}
+		fixed = append(fixed, kubeval.NewFile(file.Name, contents))
+	}
+	return fixed, count, nil
+}
+
+// fixStdin makes the fixes of kubeval.FixManifests to the manifests read
+// from stdin, reporting each of them, and with --fix writes the fixed
+// manifests to stdout. The number of fixes is returned.
+func fixStdin(contents []byte) (int, error) {
+	fixed, fixes := kubeval.FixManifests(contents, config)
+	reportFixes(config.FileName, fixes)
+	if fixDryRun {
+		return len(fixes), nil
+	}
+	_, err := os.Stdout.Write(fixed)
+	return len(fixes), err
+}
+
+// fixDryRunExitCode is the exit code of --fix-dry-run, which fails when
+// there is anything to fix, so it can be used to check manifests are clean
+func fixDryRunExitCode(count int) int {
+	if count > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/instrumenta/kubeval/kubeval"
+)
+
+// gitCloneDir creates the temporary directory a repository at url is cloned
+// into. The caller removes it, and should arrange to do so before cloning,
+// so that it is also removed if kubeval is interrupted during the clone.
+func gitCloneDir(url string) (string, error) {
+	dir, err := ioutil.TempDir("", "kubeval-git-")
+	if err != nil {
+		return "", fmt.Errorf("Failed to create a directory to clone %s into: %s", url, err)
+	}
+	return dir, nil
+}
+
+// cloneGitRepository makes a shallow clone of the repository at url into
+// dir, see gitCloneDir, checking out ref or, if empty, the default branch.
+func cloneGitRepository(dir, url, ref string) error {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	// Fetching a single ref, rather than using clone --branch, also works
+	// for commits as well as branches and tags
+	commands := [][]string{
+		{"init", "--quiet"},
+		{"remote", "add", "origin", url},
+		{"fetch", "--quiet", "--depth", "1", "origin", ref},
+		{"checkout", "--quiet", "FETCH_HEAD"},
+	}
+	for _, args := range commands {
+		var stderr bytes.Buffer
+		git := exec.Command("git", args...)
+		git.Dir = dir
+		git.Stderr = &stderr
+		if err := git.Run(); err != nil {
+			message := strings.TrimSpace(stderr.String())
+			if message == "" {
+				message = err.Error()
+			}
+			return fmt.Errorf("Failed to clone %s at %s: %s", url, ref, message)
+		}
+	}
+	return nil
+}
+
+// gitDiscoverer finds the files at the given paths within a clone of a git
+// repository, naming them relative to the root of the repository.
+type gitDiscoverer struct {
+	dir                 string
+	paths               []string
+	ignoredPathPatterns []string
+}
+
+// Discover returns each file, and the YAML files found in each directory,
+// named by paths
+func (d *gitDiscoverer) Discover() ([]kubeval.File, error) {
+	var files, directories []string
+	for _, path := range d.paths {
+		fullPath := filepath.Join(d.dir, path)
+		if rel, err := filepath.Rel(d.dir, fullPath); err != nil || strings.HasPrefix(rel, "..") {
+			return nil, fmt.Errorf("Path %s is outside of the git repository", path)
+		}
+		info, err := os.Stat(fullPath)
+		if err != nil {
+			return nil, fmt.Errorf("Path %s not found in the git repository", path)
+		}
+		if info.IsDir() {
+			directories = append(directories, fullPath)
+		} else {
+			files = append(files, fullPath)
+		}
+	}
+
+	found, err := kubeval.NewFilesystemDiscoverer(files, directories, d.ignoredPathPatterns).Discover()
+	for i := range found {
+		if rel, err := filepath.Rel(d.dir, found[i].Name); err == nil {
+			found[i].Name = filepath.ToSlash(rel)
+		}
+	}
+	return found, err
+}
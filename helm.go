@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// helmTemplateErrorPattern matches the template file named in errors
+// reported by `helm template`, such as:
+// Error: template: mychart/templates/service.yaml:4:11: executing ...
+var helmTemplateErrorPattern = regexp.MustCompile(`template: ([^:\s]+):(.*)`)
+
+// renderHelmChart renders the chart at the given path with `helm template`,
+// using any provided values files, returning the rendered manifests.
+func renderHelmChart(chart string, valuesFiles []string) ([]byte, error) {
+	args := []string{"template", chart}
+	for _, valuesFile := range valuesFiles {
+		args = append(args, "--values", valuesFile)
+	}
+
+	var stdout, stderr bytes.Buffer
+	helm := exec.Command("helm", args...)
+	helm.Stdout = &stdout
+	helm.Stderr = &stderr
+	if err := helm.Run(); err != nil {
+		if stderr.Len() == 0 {
+			return nil, fmt.Errorf("Failed to render Helm chart %s: %s", chart, err)
+		}
+		return nil, helmRenderErrors(chart, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// helmRenderErrors converts the output of a failed `helm template` into
+// errors reported against the template file responsible, where known.
+func helmRenderErrors(chart string, output string) error {
+	var errors *multierror.Error
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(line, "Error:"))
+		if line == "" {
+			continue
+		}
+		if found := helmTemplateErrorPattern.FindStringSubmatch(line); found != nil {
+			errors = multierror.Append(errors, fmt.Errorf("%s: Failed to render template: %s", found[1], strings.TrimSpace(found[2])))
+		} else {
+			errors = multierror.Append(errors, fmt.Errorf("Failed to render Helm chart %s: %s", chart, line))
+		}
+	}
+	return errors.ErrorOrNil()
+}
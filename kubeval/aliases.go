@@ -0,0 +1,53 @@
+package kubeval
+
+import "strings"
+
+// defaultAPIGroupAliases maps the short or informal API group names used by
+// some tools to the official group names. The core group is official only
+// as the empty group, so apiVersions such as core/v1 become v1.
+func defaultAPIGroupAliases() map[string]string {
+	return map[string]string{
+		"":                      "",
+		"core":                  "",
+		"admissionregistration": "admissionregistration.k8s.io",
+		"apiextensions":         "apiextensions.k8s.io",
+		"apiregistration":       "apiregistration.k8s.io",
+		"certificates":          "certificates.k8s.io",
+		"coordination":          "coordination.k8s.io",
+		"discovery":             "discovery.k8s.io",
+		"events":                "events.k8s.io",
+		"flowcontrol":           "flowcontrol.apiserver.k8s.io",
+		"networking":            "networking.k8s.io",
+		"node":                  "node.k8s.io",
+		"rbac":                  "rbac.authorization.k8s.io",
+		"scheduling":            "scheduling.k8s.io",
+		"storage":               "storage.k8s.io",
+	}
+}
+
+// canonicalAPIVersion returns the official form of an apiVersion which may
+// use an alias. Aliases in config take precedence over the built-in ones,
+// and may name either a whole apiVersion or just its group.
+func canonicalAPIVersion(apiVersion string, config *Config) string {
+	if canonical, found := config.APIVersionAliases[apiVersion]; found {
+		return canonical
+	}
+
+	slash := strings.LastIndex(apiVersion, "/")
+	if slash < 0 {
+		return apiVersion
+	}
+	group, version := apiVersion[:slash], apiVersion[slash+1:]
+
+	canonical, found := config.APIVersionAliases[group]
+	if !found {
+		canonical, found = defaultAPIGroupAliases()[group]
+	}
+	if !found {
+		return apiVersion
+	}
+	if canonical == "" {
+		return version
+	}
+	return canonical + "/" + version
+}
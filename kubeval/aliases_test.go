@@ -0,0 +1,51 @@
+package kubeval
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalAPIVersion(t *testing.T) {
+	config := NewDefaultConfig()
+	config.APIVersionAliases = map[string]string{
+		"acme":       "widgets.acme.com",
+		"legacy/v1":  "widgets.acme.com/v2",
+		"networking": "networking.example.com",
+	}
+
+	tests := map[string]string{
+		"v1":                           "v1",
+		"apps/v1":                      "apps/v1",
+		"core/v1":                      "v1",
+		"/v1":                          "v1",
+		"rbac/v1":                      "rbac.authorization.k8s.io/v1",
+		"storage/v1beta1":              "storage.k8s.io/v1beta1",
+		"rbac.authorization.k8s.io/v1": "rbac.authorization.k8s.io/v1",
+		"acme/v1alpha1":                "widgets.acme.com/v1alpha1",
+		"legacy/v1":                    "widgets.acme.com/v2",
+		"networking/v1":                "networking.example.com/v1",
+		"example.com/v1":               "example.com/v1",
+	}
+	for apiVersion, expected := range tests {
+		assert.Equal(t, expected, canonicalAPIVersion(apiVersion, config), apiVersion)
+	}
+}
+
+func TestValidateAPIVersionAlias(t *testing.T) {
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	input := []byte(`apiVersion: core/v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - port: 80
+`)
+	results, err := Validate(input, config)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", results[0].APIVersion)
+	assert.True(t, results[0].ValidatedAgainstSchema)
+	assert.Empty(t, results[0].Errors)
+}
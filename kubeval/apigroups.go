@@ -0,0 +1,47 @@
+package kubeval
+
+import (
+	"fmt"
+	"strings"
+)
+
+// coreGroupNames are the names by which the core API group, whose
+// apiVersions have no group such as v1, may be passed in Config.APIGroups
+var coreGroupNames = []string{"", "core"}
+
+// apiGroup returns the API group of apiVersion, which is empty for the core
+// group
+func apiGroup(apiVersion string) string {
+	slash := strings.LastIndex(apiVersion, "/")
+	if slash < 0 {
+		return ""
+	}
+	return apiVersion[:slash]
+}
+
+// checkAPIGroups returns an error if any of groups is an apiVersion, such
+// as apps/v1, rather than a group
+func checkAPIGroups(groups []string) error {
+	for _, group := range groups {
+		if strings.Contains(group, "/") {
+			return fmt.Errorf("API groups ('--api-groups' flag) must not include a version, such as apps rather than apps/v1, got '%s'", group)
+		}
+	}
+	return nil
+}
+
+// SelectsAPIGroup returns whether resources of apiVersion are validated
+// with the Config, which is when they are in one of APIGroups, or it is
+// empty. The core group is selected by core or an empty string.
+func (c *Config) SelectsAPIGroup(apiVersion string) bool {
+	if len(c.APIGroups) == 0 {
+		return true
+	}
+	group := apiGroup(apiVersion)
+	for _, selected := range c.APIGroups {
+		if selected == group || (group == "" && in(coreGroupNames, selected)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package kubeval
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// AuditRecord is a record of a validation run, written as a single line of
+// JSON to an append-only audit log
+type AuditRecord struct {
+	Timestamp string `json:"timestamp"`
+	User      string `json:"user"`
+	Version   string `json:"version"`
+	Files     int    `json:"files"`
+	Documents int    `json:"documents"`
+	// Statuses counts the documents with each of ValidStatuses()
+	Statuses map[string]int `json:"statuses"`
+	Success  bool           `json:"success"`
+	// ConfigHash identifies the effective configuration of the run
+	ConfigHash string `json:"configHash"`
+}
+
+// NewAuditRecord returns the record of a run which produced results with
+// config. The files counted are those with at least one result.
+func NewAuditRecord(results []ValidationResult, config *Config, timestamp time.Time, user, version string, success bool) AuditRecord {
+	statuses := make(map[string]int)
+	for _, s := range ValidStatuses() {
+		statuses[s] = 0
+	}
+	files := make(map[string]bool)
+	for _, r := range results {
+		statuses[r.Status()]++
+		files[r.FileName] = true
+	}
+	return AuditRecord{
+		Timestamp:  timestamp.UTC().Format(time.RFC3339),
+		User:       user,
+		Version:    version,
+		Files:      len(files),
+		Documents:  len(results),
+		Statuses:   statuses,
+		Success:    success,
+		ConfigHash: ConfigHash(config),
+	}
+}
+
+// ConfigHash returns the sha256 hash of the settings in config which affect
+// the outcome of validation, so that runs with the same effective config
+// can be identified. The FileName, which changes as files are validated,
+// and any FormatCheckers and PostProcess hook, which are code rather than
+// settings, are left out.
+func ConfigHash(config *Config) string {
+	settings := *config
+	settings.FileName = ""
+	settings.FormatCheckers = nil
+	b, err := json.Marshal(settings)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(b))
+}
+
+// WriteAuditRecord writes record to w as a single line of JSON, in a single
+// write, so that records appended to a log by concurrent runs do not
+// interleave.
+func WriteAuditRecord(w io.Writer, record AuditRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,58 @@
+package kubeval
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+func TestNewAuditRecord(t *testing.T) {
+	results := []ValidationResult{
+		{FileName: "web.yaml", Kind: "Deployment", ValidatedAgainstSchema: true},
+		{FileName: "web.yaml", Kind: "Service", ValidatedAgainstSchema: true, Errors: newResultErrors([]string{"port is required"})},
+		{FileName: "empty.yaml"},
+	}
+	config := NewDefaultConfig()
+	timestamp := time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+
+	record := NewAuditRecord(results, config, timestamp, "alex", "0.15.0", false)
+	assert.Equal(t, "2020-01-02T02:04:05Z", record.Timestamp)
+	assert.Equal(t, "alex", record.User)
+	assert.Equal(t, "0.15.0", record.Version)
+	assert.Equal(t, 2, record.Files)
+	assert.Equal(t, 3, record.Documents)
+	assert.Equal(t, map[string]int{"valid": 1, "invalid": 1, "empty": 1, "skipped": 0, "unvalidated": 0, "schema_error": 0}, record.Statuses)
+	assert.False(t, record.Success)
+	assert.Equal(t, ConfigHash(config), record.ConfigHash)
+}
+
+func TestConfigHash(t *testing.T) {
+	config := NewDefaultConfig()
+	hash := ConfigHash(config)
+	assert.Regexp(t, `^sha256:[a-f0-9]{64}$`, hash)
+
+	config.FileName = "other.yaml"
+	config.FormatCheckers = map[string]gojsonschema.FormatChecker{"custom": ValidFormat{}}
+	config.PostProcess = func(results []ValidationResult) []ValidationResult { return results }
+	assert.Equal(t, hash, ConfigHash(config), "the file name, format checkers and post-processor should not affect the hash")
+
+	config.Strict = true
+	assert.NotEqual(t, hash, ConfigHash(config))
+}
+
+func TestWriteAuditRecord(t *testing.T) {
+	var buf bytes.Buffer
+	record := NewAuditRecord(nil, NewDefaultConfig(), time.Now(), "alex", "dev", true)
+	assert.NoError(t, WriteAuditRecord(&buf, record))
+	assert.NoError(t, WriteAuditRecord(&buf, record))
+
+	lines := bytes.Split(bytes.TrimSuffix(buf.Bytes(), []byte("\n")), []byte("\n"))
+	assert.Len(t, lines, 2)
+	var decoded AuditRecord
+	assert.NoError(t, json.Unmarshal(lines[0], &decoded))
+	assert.Equal(t, record, decoded)
+}
@@ -0,0 +1,86 @@
+package kubeval
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// crdCatalogLocation is the location of the schemas for custom resources
+// in the catalogs below, keyed by ref, group, kind and version
+const crdCatalogLocation = "https://raw.githubusercontent.com/datreeio/CRDs-catalog/{ref}/{group}/{kind}_{version}.json"
+
+// crdCatalog is a set of schemas for custom resources which are not part
+// of Kubernetes itself, but are common enough to be built in. A catalog is
+// always used at a revision given as catalog@ref, such as the commit which
+// matches the release of the project in use, as its schemas follow the
+// latest releases, so results would otherwise change from day to day.
+type crdCatalog struct {
+	// groups are the API groups of the custom resources in the catalog
+	groups []string
+
+	// project is the project whose releases the revision should match
+	project string
+}
+
+var crdCatalogs = map[string]crdCatalog{
+	"knative": {
+		groups: []string{
+			"serving.knative.dev",
+			"eventing.knative.dev",
+			"messaging.knative.dev",
+			"sources.knative.dev",
+			"flows.knative.dev",
+			"networking.internal.knative.dev",
+			"autoscaling.internal.knative.dev",
+		},
+		project: "Knative",
+	},
+}
+
+func validCRDCatalogs() []string {
+	names := make([]string, 0, len(crdCatalogs))
+	for name := range crdCatalogs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseCRDCatalog splits a catalog given as name@ref
+func parseCRDCatalog(value string) (crdCatalog, string, error) {
+	parts := strings.SplitN(value, "@", 2)
+	catalog, found := crdCatalogs[parts[0]]
+	if !found {
+		return catalog, "", fmt.Errorf("Unknown CRD catalog '%s' ('--crd-catalogs' flag), must be one of %v", parts[0], validCRDCatalogs())
+	}
+	if len(parts) != 2 || parts[1] == "" {
+		return catalog, "", fmt.Errorf("CRD catalog '%s' ('--crd-catalogs' flag) must be pinned to a revision as %s@ref, such as the commit of the catalog matching the %s release in use", parts[0], parts[0], catalog.project)
+	}
+	return catalog, parts[1], nil
+}
+
+// determineCRDCatalogSchemaURLs returns the schema URLs from any of the
+// enabled catalogs which cover the group of the given apiVersion
+func determineCRDCatalogSchemaURLs(kind, apiVersion string, config *Config) []string {
+	groupParts := strings.Split(apiVersion, "/")
+	if len(groupParts) != 2 {
+		return nil
+	}
+
+	var urls []string
+	for _, value := range config.CRDCatalogs {
+		catalog, ref, err := parseCRDCatalog(value)
+		if err != nil || !in(catalog.groups, groupParts[0]) {
+			continue
+		}
+		url := strings.NewReplacer(
+			"{ref}", ref,
+			"{group}", groupParts[0],
+			"{kind}", strings.ToLower(kind),
+			"{version}", groupParts[1],
+		).Replace(crdCatalogLocation)
+		urls = append(urls, url)
+	}
+	return urls
+}
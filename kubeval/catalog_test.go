@@ -0,0 +1,68 @@
+package kubeval
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetermineCRDCatalogSchemaURLs(t *testing.T) {
+	var tests = []struct {
+		catalogs   []string
+		kind       string
+		apiVersion string
+		expected   []string
+	}{
+		{
+			catalogs:   []string{},
+			kind:       "Service",
+			apiVersion: "serving.knative.dev/v1",
+			expected:   nil,
+		},
+		{
+			catalogs:   []string{"knative@9e1f0d7"},
+			kind:       "Service",
+			apiVersion: "serving.knative.dev/v1",
+			expected:   []string{"https://raw.githubusercontent.com/datreeio/CRDs-catalog/9e1f0d7/serving.knative.dev/service_v1.json"},
+		},
+		{
+			catalogs:   []string{"knative@4a1b2c3"},
+			kind:       "Broker",
+			apiVersion: "eventing.knative.dev/v1",
+			expected:   []string{"https://raw.githubusercontent.com/datreeio/CRDs-catalog/4a1b2c3/eventing.knative.dev/broker_v1.json"},
+		},
+		{
+			catalogs:   []string{"knative@9e1f0d7"},
+			kind:       "Service",
+			apiVersion: "v1",
+			expected:   nil,
+		},
+		{
+			catalogs:   []string{"knative@9e1f0d7"},
+			kind:       "Deployment",
+			apiVersion: "apps/v1",
+			expected:   nil,
+		},
+	}
+	for _, test := range tests {
+		config := NewDefaultConfig()
+		config.CRDCatalogs = test.catalogs
+		assert.Equal(t, test.expected, determineCRDCatalogSchemaURLs(test.kind, test.apiVersion, config))
+	}
+}
+
+func TestValidateUnknownCRDCatalog(t *testing.T) {
+	config := NewDefaultConfig()
+	config.CRDCatalogs = []string{"unknown"}
+	_, err := Validate([]byte("kind: Pod"), config)
+	assert.EqualError(t, err, "Unknown CRD catalog 'unknown' ('--crd-catalogs' flag), must be one of [knative]")
+}
+
+func TestValidateUnpinnedCRDCatalog(t *testing.T) {
+	config := NewDefaultConfig()
+	for _, catalog := range []string{"knative", "knative@"} {
+		config.CRDCatalogs = []string{catalog}
+		_, err := Validate([]byte("kind: Pod"), config)
+		assert.EqualError(t, err, "CRD catalog 'knative' ('--crd-catalogs' flag) must be pinned to a revision as knative@ref, such as the commit of the catalog matching the Knative release in use")
+	}
+}
@@ -0,0 +1,145 @@
+package kubeval
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// HashManifest records the hash of the contents of each file which passed
+// validation, so that a later run can validate only the files which have
+// changed since
+type HashManifest struct {
+	// ConfigHash is the ConfigHash of the run which wrote the manifest.
+	// Files are only skipped by runs with the same config.
+	ConfigHash string `json:"configHash"`
+	// Files maps each file name to the hash of its contents
+	Files map[string]string `json:"files"`
+}
+
+// NewHashManifest returns an empty manifest
+func NewHashManifest() *HashManifest {
+	return &HashManifest{Files: make(map[string]string)}
+}
+
+// ReadHashManifest reads the manifest at path, returning an empty manifest
+// if there is no file there yet
+func ReadHashManifest(path string) (*HashManifest, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return NewHashManifest(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read hash manifest %s: %s", path, err)
+	}
+	manifest := NewHashManifest()
+	if err := json.Unmarshal(b, manifest); err != nil {
+		return nil, fmt.Errorf("Failed to read hash manifest %s: %s", path, err)
+	}
+	if manifest.Files == nil {
+		manifest.Files = make(map[string]string)
+	}
+	return manifest, nil
+}
+
+// WriteHashManifest writes manifest to path. It is written to a temporary
+// file alongside path which is then renamed over it, so that a run which is
+// interrupted never leaves a partly written manifest behind.
+func WriteHashManifest(path string, manifest *HashManifest) error {
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return fmt.Errorf("Failed to write hash manifest %s: %s", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Failed to write hash manifest %s: %s", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Failed to write hash manifest %s: %s", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Failed to write hash manifest %s: %s", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("Failed to write hash manifest %s: %s", path, err)
+	}
+	return nil
+}
+
+// hashContents returns the sha256 hash of the contents of a file
+func hashContents(contents []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(contents))
+}
+
+// SelectChangedFiles reads files and returns those whose contents do not
+// match the hash recorded in manifest, along with the hash of every file
+// which was read. The files returned have their contents in memory, so
+// they are not read twice, apart from those which could not be read, which
+// are always returned so the failure is reported. If manifest was written
+// with a different config every file is returned.
+func SelectChangedFiles(files []File, manifest *HashManifest, conf ...*Config) ([]File, map[string]string) {
+	config := NewDefaultConfig()
+	if len(conf) == 1 {
+		config = conf[0]
+	}
+
+	sameConfig := manifest.ConfigHash == ConfigHash(config)
+	var changed []File
+	hashes := make(map[string]string)
+	for _, file := range files {
+		contents, err := file.Read()
+		if err != nil {
+			changed = append(changed, file)
+			continue
+		}
+		hash := hashContents(contents)
+		hashes[file.Name] = hash
+		if sameConfig && manifest.Files[file.Name] == hash {
+			continue
+		}
+		changed = append(changed, NewFile(file.Name, contents))
+	}
+	return changed, hashes
+}
+
+// UpdateHashManifest returns manifest updated after a run with config which
+// read files with the given hashes, as returned by SelectChangedFiles. The
+// hashes of files which passed are recorded, while files in failed are
+// removed so that they are validated again by the next run, as are files
+// which no longer exist. Files the run did not read which still exist keep
+// their entries, unless the config has changed.
+func UpdateHashManifest(manifest *HashManifest, hashes map[string]string, failed map[string]bool, conf ...*Config) *HashManifest {
+	config := NewDefaultConfig()
+	if len(conf) == 1 {
+		config = conf[0]
+	}
+
+	updated := NewHashManifest()
+	updated.ConfigHash = ConfigHash(config)
+	if manifest.ConfigHash == updated.ConfigHash {
+		for name, hash := range manifest.Files {
+			if _, err := os.Stat(name); os.IsNotExist(err) {
+				continue
+			}
+			updated.Files[name] = hash
+		}
+	}
+	for name, hash := range hashes {
+		if failed[name] {
+			delete(updated.Files, name)
+			continue
+		}
+		updated.Files[name] = hash
+	}
+	return updated
+}
@@ -0,0 +1,108 @@
+package kubeval
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectChangedFiles(t *testing.T) {
+	config := NewDefaultConfig()
+	manifest := NewHashManifest()
+	manifest.ConfigHash = ConfigHash(config)
+	manifest.Files["same.yaml"] = hashContents([]byte("same"))
+	manifest.Files["edited.yaml"] = hashContents([]byte("before"))
+
+	files := []File{
+		NewFile("same.yaml", []byte("same")),
+		NewFile("edited.yaml", []byte("after")),
+		NewFile("new.yaml", []byte("new")),
+		{Name: "unreadable.yaml", Read: func() ([]byte, error) { return nil, os.ErrPermission }},
+	}
+
+	changed, hashes := SelectChangedFiles(files, manifest, config)
+	names := []string{}
+	for _, f := range changed {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"edited.yaml", "new.yaml", "unreadable.yaml"}, names)
+	assert.Len(t, hashes, 3)
+	assert.Equal(t, hashContents([]byte("after")), hashes["edited.yaml"])
+
+	// Every file is selected when the config has changed
+	config.Strict = true
+	changed, _ = SelectChangedFiles(files, manifest, config)
+	assert.Len(t, changed, 4)
+}
+
+func TestUpdateHashManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeval-changed-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	kept := filepath.Join(dir, "kept.yaml")
+	require.NoError(t, ioutil.WriteFile(kept, []byte("kept"), 0644))
+	deleted := filepath.Join(dir, "deleted.yaml")
+
+	config := NewDefaultConfig()
+	manifest := NewHashManifest()
+	manifest.ConfigHash = ConfigHash(config)
+	manifest.Files[kept] = "sha256:kept"
+	manifest.Files[deleted] = "sha256:deleted"
+	manifest.Files["failing.yaml"] = "sha256:old"
+
+	hashes := map[string]string{"passing.yaml": "sha256:new", "failing.yaml": "sha256:failing"}
+	updated := UpdateHashManifest(manifest, hashes, map[string]bool{"failing.yaml": true}, config)
+	assert.Equal(t, map[string]string{kept: "sha256:kept", "passing.yaml": "sha256:new"}, updated.Files)
+
+	// Entries for files the run did not read are dropped when the config
+	// has changed, as they were validated with a different config
+	config.Strict = true
+	updated = UpdateHashManifest(manifest, hashes, nil, config)
+	assert.Equal(t, map[string]string{"passing.yaml": "sha256:new", "failing.yaml": "sha256:failing"}, updated.Files)
+	assert.Equal(t, ConfigHash(config), updated.ConfigHash)
+}
+
+func TestWriteHashManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeval-changed-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "hashes.json")
+
+	manifest, err := ReadHashManifest(path)
+	require.NoError(t, err)
+	assert.Empty(t, manifest.Files)
+
+	manifest.ConfigHash = "sha256:config"
+	manifest.Files["b.yaml"] = "sha256:b"
+	manifest.Files["a.yaml"] = "sha256:a"
+	require.NoError(t, WriteHashManifest(path, manifest))
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  "configHash": "sha256:config",
+  "files": {
+    "a.yaml": "sha256:a",
+    "b.yaml": "sha256:b"
+  }
+}
+`, string(b))
+
+	read, err := ReadHashManifest(path)
+	require.NoError(t, err)
+	assert.Equal(t, manifest, read)
+
+	// No temporary files are left behind
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("not json"), 0644))
+	_, err = ReadHashManifest(path)
+	assert.Error(t, err)
+}
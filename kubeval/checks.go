@@ -0,0 +1,363 @@
+package kubeval
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// newCheckError returns a gojsonschema.ResultError describing a failed
+// check, so that problems found outside of the schema are reported in
+// exactly the same way as schema validation errors.
+func newCheckError(errorType string, path []string, value interface{}, description string) gojsonschema.ResultError {
+	context := gojsonschema.NewJsonContext(gojsonschema.STRING_ROOT_SCHEMA_PROPERTY, nil)
+	for _, p := range path {
+		context = gojsonschema.NewJsonContext(p, context)
+	}
+
+	r := &gojsonschema.ResultErrorFields{}
+	r.SetType(errorType)
+	r.SetContext(context)
+	r.SetValue(value)
+	r.SetDescription(description)
+	r.SetDetails(gojsonschema.ErrorDetails{})
+	return r
+}
+
+var (
+	// coreAPIVersionPattern matches the versions of the core API group,
+	// such as v1 or v2beta1
+	coreAPIVersionPattern = regexp.MustCompile(`^v[0-9]+((alpha|beta)[0-9]+)?$`)
+
+	// groupAPIVersionPattern matches a group, which must be a DNS subdomain,
+	// and a version, which must be a DNS label, such as apps/v1
+	groupAPIVersionPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*/[a-z]([-a-z0-9]*[a-z0-9])?$`)
+)
+
+// checkAPIVersionFormat returns an error if the apiVersion is not of the
+// form version, for the core API group, or group/version.
+func checkAPIVersionFormat(apiVersion string) gojsonschema.ResultError {
+	if coreAPIVersionPattern.MatchString(apiVersion) || groupAPIVersionPattern.MatchString(apiVersion) {
+		return nil
+	}
+	return newCheckError("api_version", []string{"apiVersion"}, apiVersion, "Invalid apiVersion, expected a lowercase group/version such as apps/v1, or a version such as v1 for the core API group")
+}
+
+// runChecks runs the optional checks enabled in config against a
+// resource, returning any problems found.
+func runChecks(body map[string]interface{}, result *ValidationResult, config *Config) []gojsonschema.ResultError {
+	var errors []gojsonschema.ResultError
+	if config.CheckSecretData {
+		errors = append(errors, checkSecretData(body, result)...)
+	}
+	if config.RequireImageDigests {
+		errors = append(errors, checkImageDigests(body)...)
+	}
+	if config.CheckLatestTags {
+		errors = append(errors, checkLatestTags(body)...)
+	}
+	if config.CheckResourceRequests {
+		errors = append(errors, checkResourceRequests(body)...)
+	}
+	if len(config.AllowedRegistries) > 0 || len(config.DeniedRegistries) > 0 {
+		errors = append(errors, checkImageRegistries(body, config)...)
+	}
+	if len(config.RequiredFields) > 0 {
+		errors = append(errors, checkRequiredFields(body, result, config)...)
+	}
+	if config.CheckRecommendedLabels {
+		errors = append(errors, checkRecommendedLabels(body, config)...)
+	}
+	if config.CheckStructuralSchemas && result.Kind == "CustomResourceDefinition" {
+		errors = append(errors, checkStructuralSchemas(body)...)
+	}
+	if config.CheckCRDPaths && result.Kind == "CustomResourceDefinition" {
+		errors = append(errors, checkCRDPaths(body)...)
+	}
+	if config.CheckSchedulingKeys {
+		errors = append(errors, checkSchedulingKeys(body)...)
+	}
+	if config.CheckObjectSize {
+		errors = append(errors, checkObjectSize(body, config)...)
+	}
+	return errors
+}
+
+// imageDigestPattern matches an image reference pinned by a sha256 digest
+var imageDigestPattern = regexp.MustCompile(`@sha256:[a-f0-9]{64}$`)
+
+// containerImage is the image of a container within a resource
+type containerImage struct {
+	// path is the path to the image field
+	path      []string
+	container string
+	image     string
+}
+
+// containerImages returns the image of every container, init container and
+// ephemeral container in the pod templates of a resource
+func containerImages(body map[string]interface{}) []containerImage {
+	var images []containerImage
+	for _, c := range podContainers(body) {
+		image, ok := c.container["image"].(string)
+		if !ok {
+			continue
+		}
+		path := append(append([]string{}, c.path...), "image")
+		images = append(images, containerImage{path: path, container: c.name, image: image})
+	}
+	return images
+}
+
+// checkImageDigests ensures that every container image is pinned by
+// digest, rather than referenced by a mutable tag.
+func checkImageDigests(body map[string]interface{}) []gojsonschema.ResultError {
+	var errors []gojsonschema.ResultError
+	for _, c := range containerImages(body) {
+		if imageDigestPattern.MatchString(c.image) {
+			continue
+		}
+		errors = append(errors, newCheckError("image_digest", c.path, c.image, fmt.Sprintf("Image '%s' of container '%s' is not pinned by digest, use image@sha256:<digest>", c.image, c.container)))
+	}
+	return errors
+}
+
+// checkLatestTags flags every container image which uses the latest tag,
+// or no tag, which means the same. Images pinned by digest are not
+// flagged, whatever their tag. Problems are reported as image_tag, which is
+// a warning unless passed to --error-on-keyword.
+func checkLatestTags(body map[string]interface{}) []gojsonschema.ResultError {
+	var errors []gojsonschema.ResultError
+	for _, c := range containerImages(body) {
+		if strings.Contains(c.image, "@") {
+			continue
+		}
+		switch imageTag(c.image) {
+		case "":
+			errors = append(errors, newCheckError("image_tag", c.path, c.image, fmt.Sprintf("Image '%s' of container '%s' has no tag, so uses latest; pin a specific version", c.image, c.container)))
+		case "latest":
+			errors = append(errors, newCheckError("image_tag", c.path, c.image, fmt.Sprintf("Image '%s' of container '%s' uses the latest tag; pin a specific version", c.image, c.container)))
+		}
+	}
+	return errors
+}
+
+// checkResourceRequests flags every container which sets no resource
+// requests, so is scheduled without regard for what it needs and is among
+// the first to be evicted. Containers with limits are not flagged, as their
+// requests default to them, nor are ephemeral containers, which cannot set
+// resources. Problems are reported as resource_requests, which is a warning
+// unless passed to --error-on-keyword.
+func checkResourceRequests(body map[string]interface{}) []gojsonschema.ResultError {
+	var errors []gojsonschema.ResultError
+	for _, c := range podContainers(body) {
+		if in(c.path, "ephemeralContainers") {
+			continue
+		}
+		resources, _ := c.container["resources"].(map[string]interface{})
+		requests, _ := resources["requests"].(map[string]interface{})
+		limits, _ := resources["limits"].(map[string]interface{})
+		if len(requests) > 0 || len(limits) > 0 {
+			continue
+		}
+		path := append(append([]string{}, c.path...), "resources")
+		errors = append(errors, newCheckError("resource_requests", path, c.container["resources"], fmt.Sprintf("Container '%s' sets no resource requests; add requests for cpu and memory so it is scheduled onto a node with room for it", c.name)))
+	}
+	return errors
+}
+
+// maxObjectSize returns Config.MaxObjectSize in bytes
+func maxObjectSize(config *Config) (int64, error) {
+	size, ok := parseQuantity(config.MaxObjectSize)
+	if !ok || !size.IsInt() || size.Sign() <= 0 {
+		return 0, fmt.Errorf("Maximum object size ('--max-object-size' flag) must be a positive number of bytes such as 1Mi or 800Ki, got '%s'", config.MaxObjectSize)
+	}
+	return size.Num().Int64(), nil
+}
+
+// checkObjectSize estimates the size of a resource as stored in etcd from
+// its size serialized as JSON, and reports it if it is over
+// Config.MaxObjectSize, naming the top level field taking up the most room,
+// which is usually the data of a ConfigMap or Secret
+func checkObjectSize(body map[string]interface{}, config *Config) []gojsonschema.ResultError {
+	limit, err := maxObjectSize(config)
+	if err != nil {
+		return nil
+	}
+	b, err := json.Marshal(body)
+	if err != nil || int64(len(b)) <= limit {
+		return nil
+	}
+
+	var largest string
+	var largestSize int
+	for _, key := range sortedKeys(body) {
+		field, _ := json.Marshal(body[key])
+		if len(field) > largestSize {
+			largest, largestSize = key, len(field)
+		}
+	}
+	return []gojsonschema.ResultError{newCheckError("object_size", []string{largest}, len(b), fmt.Sprintf("Object is %d bytes serialized, more than the maximum of %s (%d bytes) for objects stored in etcd; %d bytes of it are in %s", len(b), config.MaxObjectSize, limit, largestSize, largest))}
+}
+
+// lookupPath returns the value at a path of object keys, or nil if the
+// path does not exist
+func lookupPath(body map[string]interface{}, path []string) interface{} {
+	var value interface{} = body
+	for _, key := range path {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// requiredFieldRule is a field which must be present in every resource
+// of a kind, parsed from a Kind:path rule in Config.RequiredFields
+type requiredFieldRule struct {
+	rule string
+	kind string
+	path []string
+}
+
+// parseRequiredFieldRule parses a Kind:path rule, where the path is a
+// dotted list of keys in which * matches every key of an object or every
+// element of an array. A kind of * applies the rule to all kinds.
+func parseRequiredFieldRule(value string) (requiredFieldRule, error) {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return requiredFieldRule{}, fmt.Errorf("Invalid required field rule '%s' ('--require-fields' flag), expected Kind:path such as Deployment:spec.template.metadata.labels.team", value)
+	}
+	path := strings.Split(parts[1], ".")
+	for _, key := range path {
+		if key == "" {
+			return requiredFieldRule{}, fmt.Errorf("Invalid required field rule '%s' ('--require-fields' flag), the path contains an empty key", value)
+		}
+	}
+	return requiredFieldRule{rule: value, kind: parts[0], path: path}, nil
+}
+
+// checkRequiredFields ensures that the fields required by the rules in
+// Config.RequiredFields for the kind of the resource are present and not null.
+func checkRequiredFields(body map[string]interface{}, result *ValidationResult, config *Config) []gojsonschema.ResultError {
+	var errors []gojsonschema.ResultError
+	for _, value := range config.RequiredFields {
+		rule, err := parseRequiredFieldRule(value)
+		if err != nil || (rule.kind != "*" && rule.kind != result.Kind) {
+			continue
+		}
+		for _, missing := range findMissingFields(body, nil, rule.path) {
+			errors = append(errors, newCheckError("required_field", missing, nil, fmt.Sprintf("Field is required by rule %s", rule.rule)))
+		}
+	}
+	return errors
+}
+
+// findMissingFields returns the paths at which the remaining keys of a
+// required field path are not found under value. Wildcards are expanded,
+// so each missing field is reported with the concrete path leading to it.
+func findMissingFields(value interface{}, parent []string, remaining []string) [][]string {
+	if len(remaining) == 0 {
+		return nil
+	}
+
+	key := remaining[0]
+	here := append(append([]string{}, parent...), key)
+	missing := func() [][]string {
+		return [][]string{append(append([]string{}, parent...), remaining...)}
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if key == "*" {
+			var found [][]string
+			for _, k := range sortedKeys(typed) {
+				found = append(found, findMissingFields(typed[k], append(append([]string{}, parent...), k), remaining[1:])...)
+			}
+			return found
+		}
+		child, ok := typed[key]
+		if !ok || child == nil {
+			return missing()
+		}
+		return findMissingFields(child, here, remaining[1:])
+	case []interface{}:
+		if key == "*" {
+			var found [][]string
+			for i, element := range typed {
+				found = append(found, findMissingFields(element, append(append([]string{}, parent...), strconv.Itoa(i)), remaining[1:])...)
+			}
+			return found
+		}
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(typed) || typed[i] == nil {
+			return missing()
+		}
+		return findMissingFields(typed[i], here, remaining[1:])
+	default:
+		return missing()
+	}
+}
+
+// checkSecretData ensures that each value under a Secret's `data` is valid
+// base64, and that values under `stringData`, which the API server encodes
+// itself, do not look like they have already been base64 encoded.
+func checkSecretData(body map[string]interface{}, result *ValidationResult) []gojsonschema.ResultError {
+	if result.Kind != "Secret" || result.APIVersion != "v1" {
+		return nil
+	}
+
+	var errors []gojsonschema.ResultError
+
+	data, _ := getObject(body, "data")
+	for _, key := range sortedKeys(data) {
+		value, ok := data[key].(string)
+		if !ok {
+			continue
+		}
+		if _, err := base64.StdEncoding.DecodeString(value); err != nil {
+			errors = append(errors, newCheckError("secret_data", []string{"data", key}, value, "Value is not valid base64"))
+		}
+	}
+
+	stringData, _ := getObject(body, "stringData")
+	for _, key := range sortedKeys(stringData) {
+		value, ok := stringData[key].(string)
+		if !ok {
+			continue
+		}
+		if looksBase64Encoded(value) {
+			errors = append(errors, newCheckError("secret_string_data", []string{"stringData", key}, value, "Value appears to be base64 encoded already; stringData is encoded by the API server, use data instead"))
+		}
+	}
+
+	return errors
+}
+
+// looksBase64Encoded returns whether value decodes as base64 into
+// printable text. Short values are ignored as too many plain words
+// happen to be valid base64.
+func looksBase64Encoded(value string) bool {
+	if len(value) < 8 {
+		return false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || !utf8.Valid(decoded) {
+		return false
+	}
+	for _, r := range string(decoded) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
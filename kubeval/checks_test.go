@@ -0,0 +1,336 @@
+package kubeval
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSecretData(t *testing.T) {
+	var tests = []struct {
+		fixture  string
+		expected []string
+	}{
+		{
+			fixture:  "secret_valid_data.yaml",
+			expected: []string{},
+		},
+		{
+			fixture: "secret_invalid_data.yaml",
+			expected: []string{
+				"data.username: Value is not valid base64",
+				"stringData.token: Value appears to be base64 encoded already; stringData is encoded by the API server, use data instead",
+			},
+		},
+	}
+	for _, test := range tests {
+		filePath, _ := filepath.Abs("../fixtures/" + test.fixture)
+		fileContents, _ := ioutil.ReadFile(filePath)
+		config := NewDefaultConfig()
+		config.FileName = test.fixture
+		config.SchemaLocation = localSchemaLocation()
+		config.CheckSecretData = true
+		results, err := Validate(fileContents, config)
+		if err != nil {
+			t.Fatalf("Unexpected error validating %s: %v", test.fixture, err)
+		}
+
+		errors := []string{}
+		for _, e := range results[0].Errors {
+			errors = append(errors, e.String())
+		}
+		assert.Equal(t, test.expected, errors, test.fixture)
+	}
+}
+
+func TestCheckSecretDataDisabled(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/secret_invalid_data.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "secret_invalid_data.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results[0].Errors) != 0 {
+		t.Errorf("Secret data should not be checked unless enabled, got %v", results[0].Errors)
+	}
+}
+
+func TestLooksBase64Encoded(t *testing.T) {
+	var tests = []struct {
+		value    string
+		expected bool
+	}{
+		{"c2VjcmV0LXRva2Vu", true},
+		{"aGVsbG8gd29ybGQ=", true},
+		{"password", false},
+		{"dGVzdA==", true},
+		{"not base64", false},
+		{"abc", false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, looksBase64Encoded(test.value), test.value)
+	}
+}
+
+func TestCheckAPIVersionFormat(t *testing.T) {
+	valid := []string{"v1", "v2beta1", "apps/v1", "rbac.authorization.k8s.io/v1", "bitnami.com/v1alpha1", "example.com/v1test"}
+	for _, apiVersion := range valid {
+		assert.Nil(t, checkAPIVersionFormat(apiVersion), apiVersion)
+	}
+
+	invalid := []string{"", "Apps/v1", "apps/V1", "apps//v1", "apps/v1/", "V1", "apps", " v1", "-apps/v1"}
+	for _, apiVersion := range invalid {
+		assert.NotNil(t, checkAPIVersionFormat(apiVersion), apiVersion)
+	}
+}
+
+func TestValidateMalformedAPIVersion(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/malformed_api_version.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "malformed_api_version.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	config.IgnoreMissingSchemas = true
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assert.Len(t, results, 5)
+	for _, r := range results {
+		assert.Equal(t, "invalid", r.Status(), r.ResourceName)
+		if assert.Len(t, r.Errors, 1, r.ResourceName) {
+			assert.Equal(t, "api_version", r.Errors[0].Type())
+		}
+	}
+}
+
+func TestCheckRequiredFields(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/required_fields.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "required_fields.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	config.RequiredFields = []string{
+		"Deployment:spec.template.metadata.labels.team",
+		"Deployment:spec.template.spec.containers.*.resources.limits",
+		"*:metadata.labels",
+	}
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := [][]string{
+		{
+			"metadata.labels: Field is required by rule *:metadata.labels",
+		},
+		{
+			"spec.template.metadata.labels.team: Field is required by rule Deployment:spec.template.metadata.labels.team",
+			"spec.template.spec.containers.1.resources.limits: Field is required by rule Deployment:spec.template.spec.containers.*.resources.limits",
+			"metadata.labels: Field is required by rule *:metadata.labels",
+		},
+		{
+			"metadata.labels: Field is required by rule *:metadata.labels",
+		},
+	}
+	if !assert.Len(t, results, len(expected)) {
+		return
+	}
+	for i, result := range results {
+		errors := []string{}
+		for _, e := range result.Errors {
+			errors = append(errors, e.String())
+		}
+		assert.Equal(t, expected[i], errors, result.ResourceName)
+	}
+}
+
+func TestInvalidRequiredFieldRules(t *testing.T) {
+	for _, rule := range []string{"Deployment", "Deployment:", ":spec", "Deployment:spec..labels"} {
+		config := NewDefaultConfig()
+		config.RequiredFields = []string{rule}
+		_, err := Validate([]byte("apiVersion: v1\nkind: Service\n"), config)
+		assert.Error(t, err, rule)
+	}
+}
+
+func TestCheckLatestTags(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/latest_tags.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "latest_tags.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	config.CheckLatestTags = true
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"spec.template.spec.initContainers.0.image: Image 'localhost:5000/migrate' of container 'migrate' has no tag, so uses latest; pin a specific version",
+		"spec.template.spec.containers.1.image: Image 'envoyproxy/envoy:latest' of container 'proxy' uses the latest tag; pin a specific version",
+	}
+	warnings := []string{}
+	for _, w := range results[0].Warnings {
+		warnings = append(warnings, w.String())
+	}
+	assert.Empty(t, results[0].Errors)
+	assert.Equal(t, expected, warnings)
+
+	// The warnings become errors with --error-on-keyword
+	config.ErrorOnKeywords = []string{"image_tag"}
+	results, err = Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	errors := []string{}
+	for _, e := range results[0].Errors {
+		errors = append(errors, e.String())
+	}
+	assert.Empty(t, results[0].Warnings)
+	assert.Equal(t, expected, errors)
+}
+
+func TestCheckResourceRequests(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/resource_requests.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "resource_requests.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	config.CheckResourceRequests = true
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"spec.template.spec.initContainers.0.resources: Container 'migrate' sets no resource requests; add requests for cpu and memory so it is scheduled onto a node with room for it",
+		"spec.template.spec.containers.2.resources: Container 'metrics' sets no resource requests; add requests for cpu and memory so it is scheduled onto a node with room for it",
+	}
+	warnings := []string{}
+	for _, w := range results[0].Warnings {
+		warnings = append(warnings, w.String())
+	}
+	assert.Empty(t, results[0].Errors)
+	assert.Equal(t, expected, warnings)
+
+	// The warnings become errors with --error-on-keyword
+	config.ErrorOnKeywords = []string{"resource_requests"}
+	results, err = Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assert.Empty(t, results[0].Warnings)
+	assert.Len(t, results[0].Errors, 2)
+}
+
+func TestCheckObjectSize(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/object_size.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "object_size.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	config.CheckObjectSize = true
+
+	// Neither Secret is near the default maximum
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, result := range results {
+		assert.Empty(t, result.Errors)
+	}
+
+	config.MaxObjectSize = "1Ki"
+	results, err = Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if assert.Len(t, results, 2) && assert.Len(t, results[0].Errors, 1) {
+		assert.Equal(t, "data: Object is 1554 bytes serialized, more than the maximum of 1Ki (1024 bytes) for objects stored in etcd; 1482 bytes of it are in data", results[0].Errors[0].String())
+		assert.Empty(t, results[1].Errors)
+	}
+
+	config.MaxObjectSize = "lots"
+	_, err = Validate(fileContents, config)
+	assert.EqualError(t, err, "Maximum object size ('--max-object-size' flag) must be a positive number of bytes such as 1Mi or 800Ki, got 'lots'")
+}
+
+func TestCheckImageDigests(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/image_digests.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "image_digests.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	config.RequireImageDigests = true
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	errors := []string{}
+	for _, e := range results[0].Errors {
+		errors = append(errors, e.String())
+	}
+	assert.Equal(t, []string{
+		"spec.template.spec.initContainers.0.image: Image 'example.com/migrate:v2' of container 'migrate' is not pinned by digest, use image@sha256:<digest>",
+		"spec.template.spec.containers.1.image: Image 'envoyproxy/envoy:latest' of container 'proxy' is not pinned by digest, use image@sha256:<digest>",
+	}, errors)
+}
+
+func TestCheckYAMLKeys(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/yaml_keys.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "yaml_keys.yaml"
+	config.IgnoreMissingSchemas = true
+
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assert.Empty(t, results[0].Errors)
+
+	config.StrictYAMLKeys = true
+	results, err = Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	errors := []string{}
+	for _, e := range results[0].Errors {
+		assert.Equal(t, "yaml_key", e.Type())
+		errors = append(errors, e.String())
+	}
+	assert.Equal(t, []string{
+		"metadata.labels.on: Key on is a boolean rather than a string, which Kubernetes does not allow; quote it",
+		"data.80: Key 80 is an integer rather than a string, which Kubernetes does not allow; quote it",
+	}, errors)
+
+	// Keys of JSON documents are always strings
+	assert.Empty(t, checkYAMLKeys([]byte(`{"data": {"80": "http"}}`)))
+
+	errors = []string{}
+	for _, e := range checkYAMLKeys([]byte("items:\n- a: 1\n  1.5: b\n- ~: c\n")) {
+		errors = append(errors, e.String())
+	}
+	assert.Equal(t, []string{
+		"items.0.1.5: Key 1.5 is a float rather than a string, which Kubernetes does not allow; quote it",
+		"items.1.null: Key null is null rather than a string, which Kubernetes does not allow; quote it",
+	}, errors)
+
+	// Keys are reported as written, including those nested under them
+	errors = []string{}
+	for _, e := range checkYAMLKeys([]byte("items:\n- yes:\n    0x50: a\n  off: b\n")) {
+		errors = append(errors, e.String())
+	}
+	assert.Equal(t, []string{
+		"items.0.yes: Key yes is a boolean rather than a string, which Kubernetes does not allow; quote it",
+		"items.0.yes.0x50: Key 0x50 is an integer rather than a string, which Kubernetes does not allow; quote it",
+		"items.0.off: Key off is a boolean rather than a string, which Kubernetes does not allow; quote it",
+	}, errors)
+}
@@ -0,0 +1,167 @@
+package kubeval
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+const (
+	// UnlistedSchemasWarn validates against schemas missing from
+	// Config.SchemaChecksums, with a warning
+	UnlistedSchemasWarn = "warn"
+	// UnlistedSchemasAllow validates against schemas missing from
+	// Config.SchemaChecksums silently
+	UnlistedSchemasAllow = "allow"
+	// UnlistedSchemasError treats schemas missing from
+	// Config.SchemaChecksums as failing verification
+	UnlistedSchemasError = "error"
+)
+
+func validUnlistedSchemas() []string {
+	return []string{
+		UnlistedSchemasWarn,
+		UnlistedSchemasAllow,
+		UnlistedSchemasError,
+	}
+}
+
+// SchemaChecksumError is returned when a schema does not match the checksum
+// pinned for it in Config.SchemaChecksums, or is not listed there when
+// Config.UnlistedSchemas is error. Validation stops, as the schema location
+// can no longer be trusted.
+type SchemaChecksumError struct {
+	Ref    string
+	Reason string
+}
+
+func (e *SchemaChecksumError) Error() string {
+	return fmt.Sprintf("Schema %s failed checksum verification: %s", e.Ref, e.Reason)
+}
+
+// checksumLinePattern matches a line of the output of sha256sum: a hash,
+// then whitespace and a file name, which may be marked as binary with *
+var checksumLinePattern = regexp.MustCompile(`^([a-fA-F0-9]{64})\s+\*?(.+)$`)
+
+// readSchemaChecksums reads a file of schema checksums in the format written
+// by sha256sum, mapping the name of each schema to its hash. Blank lines and
+// lines starting with # are ignored.
+func readSchemaChecksums(path string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read schema checksums ('--schema-checksums' flag): %s", err)
+	}
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		found := checksumLinePattern.FindStringSubmatch(line)
+		if found == nil {
+			return nil, fmt.Errorf("Invalid schema checksum on line %d of %s, expected a sha256 hash followed by a schema name", n, path)
+		}
+		checksums[strings.TrimSpace(found[2])] = strings.ToLower(found[1])
+	}
+	return checksums, nil
+}
+
+// loadSchemaChecksums returns the checksums in Config.SchemaChecksums, as
+// parsed for the run by parseConfigFiles if it has been
+func loadSchemaChecksums(config *Config) (map[string]string, error) {
+	if config.parsedSchemaChecksums != nil {
+		return config.parsedSchemaChecksums, nil
+	}
+	return readSchemaChecksums(config.SchemaChecksums)
+}
+
+// expectedChecksum returns the checksum pinned for the schema at ref. A
+// schema is listed under its full URL, or under its path relative to the
+// schema location, such as master-standalone/deployment-apps-v1.json. The
+// longest matching path is used.
+func expectedChecksum(ref string, checksums map[string]string) (string, bool) {
+	if sum, found := checksums[ref]; found {
+		return sum, true
+	}
+	match := ""
+	for name := range checksums {
+		if strings.HasSuffix(ref, "/"+strings.TrimPrefix(name, "/")) && len(name) > len(match) {
+			match = name
+		}
+	}
+	sum, found := checksums[match]
+	return sum, found
+}
+
+// fetchSchema returns the raw contents of the schema at ref, which is a
+// file:// or http(s):// URL
+func fetchSchema(ref string) ([]byte, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "file" {
+		return ioutil.ReadFile(u.Path)
+	}
+	resp, err := http.Get(ref)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Could not read schema from HTTP, response status is %s", resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// verifiedSchemaLoader fetches the schema at ref and verifies it against
+// Config.SchemaChecksums, returning a loader for the verified contents so
+// that exactly what was verified is compiled, and whether the schema is
+// listed. A schema which is not listed fails verification if
+// Config.UnlistedSchemas is error.
+func verifiedSchemaLoader(ref string, config *Config) (gojsonschema.JSONLoader, bool, error) {
+	checksums, err := loadSchemaChecksums(config)
+	if err != nil {
+		return nil, false, &SchemaChecksumError{Ref: ref, Reason: err.Error()}
+	}
+	release := fetchSlot(ref, config)
+	contents, err := fetchSchema(ref)
+	release()
+	if err != nil {
+		return nil, false, err
+	}
+
+	expected, listed := expectedChecksum(ref, checksums)
+	switch {
+	case listed:
+		if actual := fmt.Sprintf("%x", sha256.Sum256(contents)); actual != expected {
+			return nil, false, &SchemaChecksumError{Ref: ref, Reason: fmt.Sprintf("expected sha256 %s but got %s", expected, actual)}
+		}
+	case config.UnlistedSchemas == UnlistedSchemasError:
+		return nil, false, &SchemaChecksumError{Ref: ref, Reason: fmt.Sprintf("it is not listed in %s", config.SchemaChecksums)}
+	}
+	return gojsonschema.NewBytesLoader(contents), listed, nil
+}
+
+// warnUnlistedSchema warns on resource if its schema, cached in
+// schemaCache, was compiled from schemas not listed in
+// Config.SchemaChecksums, unless Config.UnlistedSchemas says otherwise. The
+// refs of these are kept with the cached schema, so that every resource
+// validated against it is warned, not only the one which compiled it.
+func warnUnlistedSchema(resource *ValidationResult, schemaCache schemaStore, config *Config) {
+	if config.SchemaChecksums == "" || config.UnlistedSchemas == UnlistedSchemasAllow {
+		return
+	}
+	for _, ref := range schemaCache.unlisted(schemaCacheKey(resource, config)) {
+		resource.Warnings = append(resource.Warnings, newCheckError("schema_checksum", nil, ref, fmt.Sprintf("Schema %s is not listed in %s, so was not verified", ref, config.SchemaChecksums)))
+	}
+}
@@ -0,0 +1,138 @@
+package kubeval
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func checksumConfig() *Config {
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	config.SchemaChecksums = "../fixtures/schema_checksums.txt"
+	return config
+}
+
+func TestSchemaChecksumsVerified(t *testing.T) {
+	deployment := []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n")
+	results, err := Validate(deployment, checksumConfig())
+	assert.NoError(t, err)
+	assert.True(t, results[0].ValidatedAgainstSchema)
+	assert.Empty(t, results[0].Warnings)
+}
+
+func TestSchemaChecksumsMismatch(t *testing.T) {
+	input := []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n---\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n")
+	config := checksumConfig()
+	config.IgnoreMissingSchemas = true
+	schemaCache := NewSchemaCache()
+
+	results, err := ValidateWithCache(input, schemaCache, config)
+	checksumErr, ok := err.(*SchemaChecksumError)
+	if assert.True(t, ok, "expected a SchemaChecksumError, got %v", err) {
+		assert.Contains(t, checksumErr.Ref, "master-standalone/service-v1.json")
+		assert.Contains(t, checksumErr.Error(), "expected sha256 0000000000000000000000000000000000000000000000000000000000000000 but got 7b27816")
+	}
+	// Validation stops at the untrusted schema, which is not cached
+	assert.Len(t, results, 1)
+	assert.NotContains(t, schemaCache, "v1/Service")
+}
+
+func TestSchemaChecksumsUnlisted(t *testing.T) {
+	secret := []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: credentials\n")
+
+	results, err := Validate(secret, checksumConfig())
+	assert.NoError(t, err)
+	if assert.Len(t, results[0].Warnings, 1) {
+		assert.Equal(t, "schema_checksum", results[0].Warnings[0].Type())
+		assert.Contains(t, results[0].Warnings[0].Description(), "is not listed in ../fixtures/schema_checksums.txt")
+	}
+	assert.Equal(t, "valid", results[0].Status())
+
+	// Every resource validated against the schema is warned, not only the
+	// one for which it was fetched
+	results, err = Validate([]byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: credentials\n---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: token\n"), checksumConfig())
+	assert.NoError(t, err)
+	if assert.Len(t, results, 2) {
+		assert.Len(t, results[0].Warnings, 1)
+		assert.Len(t, results[1].Warnings, 1)
+	}
+
+	// including those validated later against the cached schema
+	schemaCache := NewSchemaCache()
+	sharedCache := NewSharedSchemaCache()
+	for i := 0; i < 2; i++ {
+		results, err = ValidateWithCache(secret, schemaCache, checksumConfig())
+		assert.NoError(t, err)
+		assert.Len(t, results[0].Warnings, 1)
+		results, err = ValidateWithSharedCache(secret, sharedCache, checksumConfig())
+		assert.NoError(t, err)
+		assert.Len(t, results[0].Warnings, 1)
+	}
+
+	config := checksumConfig()
+	config.UnlistedSchemas = UnlistedSchemasAllow
+	results, err = Validate(secret, config)
+	assert.NoError(t, err)
+	assert.Empty(t, results[0].Warnings)
+
+	config.UnlistedSchemas = UnlistedSchemasError
+	_, err = Validate(secret, config)
+	assert.IsType(t, &SchemaChecksumError{}, err)
+
+	config.UnlistedSchemas = "maybe"
+	_, err = Validate(secret, config)
+	assert.EqualError(t, err, "Unlisted schemas ('--unlisted-schemas' flag) must be one of [warn allow error]")
+}
+
+func TestReadSchemaChecksums(t *testing.T) {
+	checksums, err := readSchemaChecksums("../fixtures/schema_checksums.txt")
+	require.NoError(t, err)
+	assert.Len(t, checksums, 2)
+
+	sum, found := expectedChecksum("https://example.com/schemas/master-standalone/deployment-apps-v1.json", checksums)
+	assert.True(t, found)
+	assert.Equal(t, "354d1bdb64c716aa17c88a302c61cdec32c667dc1a1d4580a2fb98f05f04a880", sum)
+	_, found = expectedChecksum("https://example.com/schemas/v1.25-standalone/deployment-apps-v1.json", checksums)
+	assert.False(t, found)
+
+	dir, err := ioutil.TempDir("", "kubeval-checksums-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sums.txt")
+	require.NoError(t, ioutil.WriteFile(path, []byte("not a checksum\n"), 0644))
+	_, err = readSchemaChecksums(path)
+	assert.EqualError(t, err, "Invalid schema checksum on line 1 of "+path+", expected a sha256 hash followed by a schema name")
+}
+
+func TestLoadSchemaChecksumsOncePerRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeval-checksums-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sums.txt")
+	sum := "354d1bdb64c716aa17c88a302c61cdec32c667dc1a1d4580a2fb98f05f04a880"
+	require.NoError(t, ioutil.WriteFile(path, []byte(sum+"  a.json\n"), 0644))
+
+	config := checksumConfig()
+	config.SchemaChecksums = path
+	run := *config
+	require.NoError(t, parseConfigFiles(&run))
+
+	// The file is not read again during the run
+	require.NoError(t, ioutil.WriteFile(path, []byte(sum+"  b.json\n"), 0644))
+	checksums, err := loadSchemaChecksums(&run)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a.json": sum}, checksums)
+
+	// but is by the next one, as nothing is kept in the Config passed in
+	_, err = Validate([]byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: web\n"), config)
+	require.NoError(t, err)
+	assert.Nil(t, config.parsedSchemaChecksums)
+	checksums, err = loadSchemaChecksums(config)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"b.json": sum}, checksums)
+}
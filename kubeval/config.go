@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+	"github.com/xeipuuv/gojsonschema"
 )
 
 // DefaultSchemaLocation is the default location to search for schemas
@@ -12,6 +13,90 @@ const DefaultSchemaLocation = "https://kubernetesjsonschema.dev"
 // OpenShiftSchemaLocation is the alternative location for OpenShift specific schemas
 const OpenShiftSchemaLocation = "https://raw.githubusercontent.com/garethr/openshift-json-schema/master"
 
+// DefaultSchemaRepository is the repository serving the default schemas, used
+// when pinning the schemas to a specific git tag or commit
+const DefaultSchemaRepository = "https://raw.githubusercontent.com/instrumenta/kubernetes-json-schema"
+
+// OpenShiftSchemaRepository is the repository serving the OpenShift schemas, used
+// when pinning the schemas to a specific git tag or commit
+const OpenShiftSchemaRepository = "https://raw.githubusercontent.com/garethr/openshift-json-schema"
+
+// SchemaRefPlaceholder is replaced with the SchemaRef in a custom schema location
+const SchemaRefPlaceholder = "{ref}"
+
+// KubernetesVersionAnnotation may be set on a resource to validate it
+// against the schemas for a different version of Kubernetes to the rest
+const KubernetesVersionAnnotation = "kubeval.io/kubernetes-version"
+
+// DefaultPrefetchWorkers is the default number of schemas loaded at once
+// when prefetching schemas
+const DefaultPrefetchWorkers = 4
+
+// DefaultMaxObjectSize is the default largest size of a serialized
+// resource. etcd rejects requests over 1.5Mi, and the API server adds
+// metadata, such as managed fields, to the object as applied.
+const DefaultMaxObjectSize = "1Mi"
+
+// ReportFormatVersion1 is the original format of structured output, where the
+// json output is a bare array of results
+const ReportFormatVersion1 = 1
+
+// ReportFormatVersion2 is the format of structured output where the json
+// output is an object holding the reportVersion alongside the results
+const ReportFormatVersion2 = 2
+
+func validReportFormatVersions() []int {
+	return []int{
+		ReportFormatVersion1,
+		ReportFormatVersion2,
+	}
+}
+
+// isValidReportFormatVersion returns whether version is one of the
+// validReportFormatVersions, or zero, which is left from a Config that does
+// not set it and means ReportFormatVersion1
+func isValidReportFormatVersion(version int) bool {
+	if version == 0 {
+		return true
+	}
+	for _, valid := range validReportFormatVersions() {
+		if version == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// JSONShapeFlat is the shape of the json output where results are a single
+// array of documents
+const JSONShapeFlat = "flat"
+
+// JSONShapeGrouped is the shape of the json output where results are an
+// object keyed by filename, holding the documents found in each file
+const JSONShapeGrouped = "grouped"
+
+func validJSONShapes() []string {
+	return []string{
+		JSONShapeFlat,
+		JSONShapeGrouped,
+	}
+}
+
+// CoreGroupSchemaFormatShort names core group schemas after the kind and
+// version, for example `pod-v1`
+const CoreGroupSchemaFormatShort = "short"
+
+// CoreGroupSchemaFormatQualified names core group schemas after the fully
+// qualified OpenAPI definition, for example `io.k8s.api.core.v1.pod`
+const CoreGroupSchemaFormatQualified = "qualified"
+
+func validCoreGroupSchemaFormats() []string {
+	return []string{
+		CoreGroupSchemaFormatShort,
+		CoreGroupSchemaFormatQualified,
+	}
+}
+
 // A Config object contains various configuration data for kubeval
 type Config struct {
 	// DefaultNamespace is the namespace to assume in resources
@@ -28,11 +113,58 @@ type Config struct {
 	// It can be either a remote location or a local directory
 	SchemaLocation string
 
+	// SchemaRef pins the schemas to a git tag or commit of the schema
+	// repository, so that upstream changes cannot alter the results. For
+	// a custom SchemaLocation, any SchemaRefPlaceholder is replaced with it
+	SchemaRef string
+
+	// SchemaMap is the path of a JSON file mapping the apiVersion and kind
+	// of resources, such as apps/v1/Deployment, to the path or URL of the
+	// schema to validate them against. When set, it is the only place
+	// schemas are looked for, and kinds it does not list are left
+	// unvalidated
+	SchemaMap string
+
+	// StrictSchemaMap makes resources whose kind is not in SchemaMap fail,
+	// rather than being left unvalidated
+	StrictSchemaMap bool
+
 	// AdditionalSchemaLocations is a list of alternative base URLs from
 	// which to search for schemas, given that the desired schema was not
 	// found at SchemaLocation
 	AdditionalSchemaLocations []string
 
+	// CoreGroupSchemaFormat controls how resources in the core API group,
+	// which have no group in their apiVersion, map to a schema filename.
+	// Either CoreGroupSchemaFormatShort (the default) or
+	// CoreGroupSchemaFormatQualified
+	CoreGroupSchemaFormat string
+
+	// CRDCatalogs is a list of built-in catalogs of schemas for common custom
+	// resources to search, given that the desired schema was not found in
+	// the other locations. Each is pinned to a revision as name@ref
+	CRDCatalogs []string
+
+	// OfflineFallback tells kubeval to validate common core kinds against
+	// fallback schemas bundled with kubeval when the schema locations
+	// cannot be reached, rather than failing
+	OfflineFallback bool
+
+	// Prefetch tells ValidateFiles to first collect the kinds of every
+	// resource in the files, and load their schemas concurrently using
+	// PrefetchWorkers, before validating anything
+	Prefetch bool
+
+	// PrefetchWorkers is the number of schemas loaded at once by Prefetch
+	// and PrefetchSchemas
+	PrefetchWorkers int
+
+	// FetchConcurrency, when more than zero, is the most schemas fetched
+	// from remote schema locations at once, however many validations
+	// sharing a schema cache or PrefetchWorkers need them, so as not to
+	// overwhelm a shared mirror
+	FetchConcurrency int
+
 	// OpenShift represents whether to test against
 	// upstream Kubernetes or the OpenShift schemas
 	OpenShift bool
@@ -41,6 +173,15 @@ type Config struct {
 	// the schema. The API allows them, but kubectl does not
 	Strict bool
 
+	// StrictYAMLKeys tells kubeval to reject YAML mapping keys which are
+	// not strings, such as 80 or true, which the API server does not allow
+	StrictYAMLKeys bool
+
+	// DocumentSeparators are lines which separate documents in addition to
+	// the standard YAML separator, ---, for tools which join manifests with
+	// a marker of their own
+	DocumentSeparators []string
+
 	// IgnoreMissingSchemas tells kubeval whether to skip validation
 	// for resource definitions without an available schema
 	IgnoreMissingSchemas bool
@@ -49,36 +190,252 @@ type Config struct {
 	// first error encountered or to continue, aggregating all errors
 	ExitOnError bool
 
+	// ExitOn is the list of result statuses which fail the run, causing a
+	// non-zero exit code and counting as failures in reports and metrics
+	ExitOn []string
+
+	// CheckSecretData tells kubeval to check that Secret `data` values are
+	// valid base64 and that `stringData` values are not already encoded
+	CheckSecretData bool
+
+	// CheckFormats tells kubeval to check the JSON schema formats used by
+	// the Kubernetes schemas which gojsonschema does not know about, such
+	// as int32 and byte, see kubernetesFormatCheckers
+	CheckFormats bool
+
+	// EvaluatePolicies tells kubeval to evaluate the `validate.pattern`
+	// rules of any Kyverno policies against the other resources validated
+	EvaluatePolicies bool
+
+	// CheckReferences tells kubeval to check that each Ingress routes to a
+	// Service and port defined in the same run, and that each Service
+	// selects at least one workload
+	CheckReferences bool
+
+	// CheckConfigReferences tells kubeval to check that every ConfigMap and
+	// Secret referenced by a workload is defined in the same run
+	CheckConfigReferences bool
+
+	// CheckResourceQuotas tells kubeval to compare the resources requested
+	// by the workloads in each namespace with any ResourceQuota for it
+	CheckResourceQuotas bool
+
+	// CheckImmutableFields tells kubeval to compare each resource with an
+	// earlier version of it, in the same run or in ImmutableBaseline, and to
+	// flag changes to fields which cannot be changed once it is created
+	CheckImmutableFields bool
+
+	// ImmutableBaseline is the path of a file or directory of manifests of
+	// the resources as they are currently applied, such as the output of
+	// kubectl get -o yaml, which CheckImmutableFields compares them with
+	ImmutableBaseline string
+
+	// CheckRecommendedLabels tells kubeval to check that every resource has
+	// the RecommendedLabels, and that the values of its app.kubernetes.io/
+	// labels are valid
+	CheckRecommendedLabels bool
+
+	// RecommendedLabels are the labels required by CheckRecommendedLabels,
+	// which defaults to app.kubernetes.io/name, instance and managed-by
+	RecommendedLabels []string
+
+	// CheckStructuralSchemas tells kubeval to check that the schemas in
+	// CustomResourceDefinitions are structural, as the API server requires
+	CheckStructuralSchemas bool
+
+	// CheckCRDPaths tells kubeval to check that the JSONPaths of the
+	// additional printer columns and the scale subresource of
+	// CustomResourceDefinitions are well formed and refer to fields in their
+	// schemas, and that a status subresource has a status field to serve
+	CheckCRDPaths bool
+
+	// SchemaChecksums is the path of a file of sha256 checksums, in the
+	// format written by sha256sum, which schemas must match once fetched
+	SchemaChecksums string
+
+	// UnlistedSchemas is how schemas not listed in SchemaChecksums are
+	// treated, one of warn, allow or error
+	UnlistedSchemas string
+
+	// CheckSchedulingKeys tells kubeval to check that the keys of the node
+	// selectors, affinities and tolerations of pod specs are valid label
+	// and taint keys
+	CheckSchedulingKeys bool
+
+	// RequireImageDigests tells kubeval to check that every container image
+	// is pinned by a sha256 digest rather than referenced by a mutable tag
+	RequireImageDigests bool
+
+	// CheckLatestTags tells kubeval to flag container images which use the
+	// latest tag or no tag. These are warnings unless image_tag is in
+	// ErrorOnKeywords.
+	CheckLatestTags bool
+
+	// CheckResourceRequests tells kubeval to flag containers which set no
+	// resource requests. These are warnings unless resource_requests is in
+	// ErrorOnKeywords.
+	CheckResourceRequests bool
+
+	// CheckObjectSize tells kubeval to check that resources, serialized as
+	// JSON, are no larger than MaxObjectSize, as etcd rejects large objects
+	CheckObjectSize bool
+
+	// MaxObjectSize is the largest size of a resource allowed by
+	// CheckObjectSize, as a quantity of bytes such as 1Mi or 800Ki
+	MaxObjectSize string
+
+	// AllowedRegistries, when set, are the only registries container images
+	// may be pulled from. Each is a registry such as gcr.io, optionally
+	// followed by a path within it such as docker.io/myorg.
+	AllowedRegistries []string
+
+	// DeniedRegistries are registries, in the same form as
+	// AllowedRegistries, which container images must not be pulled from
+	DeniedRegistries []string
+
+	// RequiredFields is a list of Kind:path rules naming fields which must
+	// be present in every resource of a kind, beyond those required by the
+	// schema. Paths are dotted, with * matching every key or array element
+	RequiredFields []string
+
+	// WarnOnKeywords is a list of JSON schema keywords, such as format, or
+	// the types of kubeval's own checks, such as image_digest, whose
+	// failures are reported as warnings rather than errors
+	WarnOnKeywords []string
+
+	// ErrorOnKeywords is a list of keywords whose failures are always
+	// reported as errors, taking precedence over WarnOnKeywords
+	ErrorOnKeywords []string
+
+	// FormatCheckers are additional JSON schema format checkers, keyed by
+	// format name, used alongside those enabled by CheckFormats. A checker
+	// here replaces any built-in one of the same name. Checkers must be
+	// comparable, as each is registered with gojsonschema once
+	FormatCheckers map[string]gojsonschema.FormatChecker
+
+	// PostProcess, when set, is given the results of a validation once all
+	// of them are in, and returns the results to use in their place. It may
+	// transform them, for example to map the severity of errors to that of
+	// an organisation, or filter them. ValidateFiles calls it once with the
+	// results of every file, and the other validation functions with the
+	// results of their input, so it runs before any output is rendered and
+	// before the exit code is decided. Being code, it is not part of the
+	// Config when serialized.
+	PostProcess func([]ValidationResult) []ValidationResult `json:"-"`
+
+	// APIVersionAliases maps aliases used in the apiVersion of resources to
+	// their official form, in addition to the built-in aliases of the
+	// Kubernetes API groups. Keys may be a whole apiVersion, such as
+	// acme/v1, or just a group, such as acme
+	APIVersionAliases map[string]string
+
 	// KindsToSkip is a list of kubernetes resources types with which to skip
 	// schema validation
 	KindsToSkip []string
 
+	// Selector is a label selector, such as `team=payments,tier in (web)`.
+	// Resources whose labels do not match it are skipped
+	Selector string
+
+	// KindsToValidate is a list of case-sensitive kinds to validate. When
+	// set, resources of any other kind are skipped
+	KindsToValidate []string
+
+	// APIGroups is a list of API groups, such as apps and batch, to
+	// validate. When set, resources in any other group are skipped. The
+	// core group, of apiVersions such as v1, is core or an empty string
+	APIGroups []string
+
+	// ClusterScopedKinds is a list of kinds, such as those of custom
+	// resources, whose resources are cluster-scoped, in addition to the
+	// built-in cluster-scoped kinds. Their names must be unique across the
+	// cluster rather than within a namespace
+	ClusterScopedKinds []string
+
 	// KindsToReject is a list of case-sensitive prohibited kubernetes resources types
 	KindsToReject []string
 
 	// FileName is the name to be displayed when testing manifests read from stdin
 	FileName string
 
+	// Environment is the environment, such as prod or staging, the
+	// manifests were rendered for, which is recorded in each result
+	Environment string
+
 	// OutputFormat is the name of the output formatter which will be used when
 	// reporting results to the user.
 	OutputFormat string
 
+	// DedupeErrors tells the stdout output to collapse identical errors for
+	// the same kind into a single entry listing the affected files. This
+	// only affects presentation
+	DedupeErrors bool
+
+	// ReportFormatVersion is the version of the structured output format
+	// to use. The version is only increased for breaking changes to the
+	// format, so consumers can rely on its structure. Zero is the same as
+	// ReportFormatVersion1
+	ReportFormatVersion int
+
+	// JSONShape is the shape of the json output, either JSONShapeFlat (the
+	// default) or JSONShapeGrouped. The fields of each document are the same
+	JSONShape string
+
 	// Quiet indicates whether non-results output should be emitted to the applications
 	// log.
 	Quiet bool
 
+	// ForceColor colors the output even if stdout is not a terminal, which
+	// is otherwise only colored when it is
+	ForceColor bool
+
 	// InsecureSkipTLSVerify controls whether to skip TLS certificate validation
 	// when retrieving schema content over HTTPS
 	InsecureSkipTLSVerify bool
+
+	// parsedSchemaChecksums and parsedSchemaMap are the contents of
+	// SchemaChecksums and SchemaMap, as parsed for the run by
+	// parseConfigFiles
+	parsedSchemaChecksums map[string]string
+	parsedSchemaMap       map[string]string
+
+	// fetchSlots are the places among the schemas fetched at once during
+	// the run, see useFetchSlots
+	fetchSlots chan struct{}
 }
 
 // NewDefaultConfig creates a Config with default values
 func NewDefaultConfig() *Config {
 	return &Config{
-		DefaultNamespace:  "default",
-		FileName:          "stdin",
-		KubernetesVersion: "master",
+		DefaultNamespace:      "default",
+		FileName:              "stdin",
+		KubernetesVersion:     "master",
+		CoreGroupSchemaFormat: CoreGroupSchemaFormatShort,
+		ReportFormatVersion:   ReportFormatVersion1,
+		JSONShape:             JSONShapeFlat,
+		PrefetchWorkers:       DefaultPrefetchWorkers,
+		MaxObjectSize:         DefaultMaxObjectSize,
+		ExitOn:                DefaultExitOn(),
+	}
+}
+
+// CheckKindFilters returns an error if KindsToSkip and KindsToValidate are
+// combined in a way which makes no sense, such as a kind in both lists,
+// which would otherwise silently skip resources the user asked to validate.
+func (c *Config) CheckKindFilters() error {
+	var conflicting []string
+	for _, kind := range c.KindsToValidate {
+		if in(c.KindsToSkip, kind) && !in(conflicting, kind) {
+			conflicting = append(conflicting, kind)
+		}
+	}
+	if len(conflicting) == 0 {
+		return nil
+	}
+	if len(conflicting) == len(c.KindsToValidate) {
+		return fmt.Errorf("Every kind passed to '--only-kinds' is also passed to '--skip-kinds', so nothing would be validated: %v", conflicting)
 	}
+	return fmt.Errorf("Kinds cannot be passed to both '--only-kinds' and '--skip-kinds': %v", conflicting)
 }
 
 // AddKubevalFlags adds the default flags for kubeval to cmd
@@ -88,13 +445,57 @@ func AddKubevalFlags(cmd *cobra.Command, config *Config) *cobra.Command {
 	cmd.Flags().BoolVar(&config.IgnoreMissingSchemas, "ignore-missing-schemas", false, "Skip validation for resource definitions without a schema")
 	cmd.Flags().BoolVar(&config.OpenShift, "openshift", false, "Use OpenShift schemas instead of upstream Kubernetes")
 	cmd.Flags().BoolVar(&config.Strict, "strict", false, "Disallow additional properties not in schema")
+	cmd.Flags().BoolVar(&config.StrictYAMLKeys, "strict-yaml-keys", false, "Disallow YAML mapping keys which are not strings, such as integers or booleans, rather than converting them to strings")
+	cmd.Flags().StringSliceVar(&config.DocumentSeparators, "document-separator", []string{}, "A line which separates documents in addition to ---, such as '# ---'. Can be specified once or more")
 	cmd.Flags().StringVarP(&config.FileName, "filename", "f", "stdin", "filename to be displayed when testing manifests read from stdin")
+	cmd.Flags().BoolVar(&config.CheckSecretData, "check-secret-data", false, "Check that Secret data values are valid base64 and that stringData values are not already base64 encoded")
+	cmd.Flags().BoolVar(&config.CheckFormats, "check-formats", false, "Check the formats used by the Kubernetes schemas, such as int32 and byte, as well as the type of each value")
+	cmd.Flags().BoolVar(&config.EvaluatePolicies, "evaluate-policies", false, "Evaluate the validate.pattern rules of Kyverno policies against the other resources validated")
+	cmd.Flags().BoolVar(&config.CheckReferences, "check-references", false, "Check that Ingresses reference Services and ports defined in the resources validated, and that Services select at least one workload")
+	cmd.Flags().BoolVar(&config.CheckConfigReferences, "check-config-references", false, "Check that the ConfigMaps and Secrets referenced by workloads through envFrom, valueFrom and volumes are defined in the resources validated")
+	cmd.Flags().BoolVar(&config.CheckResourceQuotas, "check-resource-quotas", false, "Warn when the resources requested by the workloads in a namespace, times their replicas, exceed a ResourceQuota for it")
+	cmd.Flags().BoolVar(&config.CheckImmutableFields, "check-immutable-fields", false, "Warn when a resource changes a field which cannot be changed once it is created, such as the selector of a Deployment, from an earlier version of it in the resources validated or in --immutable-baseline")
+	cmd.Flags().StringVar(&config.ImmutableBaseline, "immutable-baseline", "", "Path of a file or directory of manifests of the resources as currently applied, such as the output of kubectl get -o yaml, which --check-immutable-fields compares them with")
+	cmd.Flags().BoolVar(&config.CheckRecommendedLabels, "check-recommended-labels", false, "Check that every resource has the labels passed to --recommended-labels, and that its app.kubernetes.io/ labels have valid values")
+	cmd.Flags().StringSliceVar(&config.RecommendedLabels, "recommended-labels", defaultRecommendedLabels(), "Comma-separated list of labels required by --check-recommended-labels")
+	cmd.Flags().BoolVar(&config.CheckStructuralSchemas, "check-structural-schemas", false, "Check that the schemas in CustomResourceDefinitions are structural, reporting the rules the API server would reject them for")
+	cmd.Flags().BoolVar(&config.CheckCRDPaths, "check-crd-paths", false, "Check that the JSONPaths of the printer columns and scale subresource of CustomResourceDefinitions are well formed and refer to fields in their schemas")
+	cmd.Flags().StringVar(&config.SchemaChecksums, "schema-checksums", "", "Path of a file of sha256 checksums, as written by sha256sum, which each schema must match once fetched. A mismatch stops validation")
+	cmd.Flags().StringVar(&config.UnlistedSchemas, "unlisted-schemas", UnlistedSchemasWarn, fmt.Sprintf("How to treat schemas not listed in --schema-checksums. Options are: %v", validUnlistedSchemas()))
+	cmd.Flags().BoolVar(&config.CheckSchedulingKeys, "check-scheduling-keys", false, "Check that the keys of nodeSelector, node and pod affinity match expressions and tolerations are valid label and taint keys")
+	cmd.Flags().BoolVar(&config.RequireImageDigests, "require-image-digests", false, "Check that every container image is pinned by sha256 digest rather than referenced by tag")
+	cmd.Flags().BoolVar(&config.CheckObjectSize, "check-object-size", false, "Check that resources are no larger than --max-object-size when serialized, as etcd rejects large objects such as ConfigMaps packed with data")
+	cmd.Flags().StringVar(&config.MaxObjectSize, "max-object-size", DefaultMaxObjectSize, "The largest size of a resource allowed by --check-object-size, such as 1Mi or 800Ki")
+	cmd.Flags().BoolVar(&config.CheckResourceRequests, "check-resource-requests", false, "Warn about containers which set no resource requests, nor limits for them to default to. Pass resource_requests to --error-on-keyword to fail instead")
+	cmd.Flags().BoolVar(&config.CheckLatestTags, "check-latest-tags", false, "Warn about container images which use the latest tag or no tag. Pass image_tag to --error-on-keyword to fail instead")
+	cmd.Flags().StringSliceVar(&config.AllowedRegistries, "allowed-registries", []string{}, "A comma-separated list of the only registries container images may be pulled from, each optionally followed by a path such as docker.io/myorg. Images without a registry are from docker.io")
+	cmd.Flags().StringSliceVar(&config.DeniedRegistries, "denied-registries", []string{}, "A comma-separated list of registries container images must not be pulled from, each optionally followed by a path such as docker.io/myorg")
+	cmd.Flags().StringSliceVar(&config.RequiredFields, "require-fields", []string{}, "Comma-separated list of Kind:path rules naming fields which must be present, such as Deployment:spec.template.metadata.labels.team. Paths may use * to match every key or array element, and a kind of * matches all kinds")
+	cmd.Flags().StringToStringVar(&config.APIVersionAliases, "api-version-aliases", map[string]string{}, "Comma-separated list of alias=official pairs of apiVersions or API groups, such as acme=widgets.acme.com, used alongside the built-in aliases of Kubernetes API groups")
+	cmd.Flags().StringSliceVar(&config.WarnOnKeywords, "warn-on-keyword", []string{}, "Comma-separated list of JSON schema keywords, such as format,pattern, or check types, such as image_digest, whose failures are reported as warnings rather than errors")
+	cmd.Flags().StringSliceVar(&config.ErrorOnKeywords, "error-on-keyword", []string{}, "Comma-separated list of JSON schema keywords or check types whose failures are always errors, even if also passed to --warn-on-keyword")
 	cmd.Flags().StringSliceVar(&config.KindsToSkip, "skip-kinds", []string{}, "Comma-separated list of case-sensitive kinds to skip when validating against schemas")
+	cmd.Flags().StringVarP(&config.Selector, "selector", "l", "", "Label selector, supporting =, ==, !=, in, notin and existence requirements, such as team=payments,tier in (web). Resources which do not match are skipped")
+	cmd.Flags().StringSliceVar(&config.APIGroups, "api-groups", []string{}, "Comma-separated list of API groups, such as apps,batch, to validate, skipping resources in all others. The core group is core")
+	cmd.Flags().StringSliceVar(&config.KindsToValidate, "only-kinds", []string{}, "Comma-separated list of case-sensitive kinds to validate, skipping all others")
+	cmd.Flags().StringSliceVar(&config.ClusterScopedKinds, "cluster-scoped-kinds", []string{}, "Comma-separated list of kinds, such as those of custom resources, which are cluster-scoped, in addition to the built-in cluster-scoped kinds such as Namespace")
 	cmd.Flags().StringSliceVar(&config.KindsToReject, "reject-kinds", []string{}, "Comma-separated list of case-sensitive kinds to prohibit validating against schemas")
 	cmd.Flags().StringVarP(&config.SchemaLocation, "schema-location", "s", "", "Base URL used to download schemas. Can also be specified with the environment variable KUBEVAL_SCHEMA_LOCATION.")
+	cmd.Flags().StringVar(&config.SchemaRef, "schema-ref", "", fmt.Sprintf("Git tag or commit of the schema repository to validate against. Replaces %s in a custom schema location, which must contain it", SchemaRefPlaceholder))
+	cmd.Flags().StringVar(&config.SchemaMap, "schema-map", "", "Path of a JSON file mapping apiVersion/kind, such as apps/v1/Deployment, to the path or URL of its schema. Only the schemas it lists are used, and other kinds are not validated")
+	cmd.Flags().BoolVar(&config.StrictSchemaMap, "strict-schema-map", false, "Fail resources whose kind is not in --schema-map, rather than leaving them unvalidated")
 	cmd.Flags().StringSliceVar(&config.AdditionalSchemaLocations, "additional-schema-locations", []string{}, "Comma-seperated list of secondary base URLs used to download schemas")
+	cmd.Flags().StringVar(&config.CoreGroupSchemaFormat, "core-group-schema-format", CoreGroupSchemaFormatShort, fmt.Sprintf("How core API group resources map to a schema filename. Options are: %v", validCoreGroupSchemaFormats()))
+	cmd.Flags().StringSliceVar(&config.CRDCatalogs, "crd-catalogs", []string{}, fmt.Sprintf("Comma-separated list of built-in catalogs of custom resource schemas to search, each pinned to a revision of the catalog as name@ref. Options are: %v", validCRDCatalogs()))
+	cmd.Flags().BoolVar(&config.OfflineFallback, "offline-fallback", false, "Validate common core kinds against less thorough bundled schemas when the schema locations cannot be reached")
+	cmd.Flags().BoolVar(&config.Prefetch, "prefetch", false, "Collect the kinds of every resource first, and load their schemas concurrently before validating")
+	cmd.Flags().IntVar(&config.PrefetchWorkers, "prefetch-workers", DefaultPrefetchWorkers, "Number of schemas to load at once with --prefetch")
+	cmd.Flags().IntVar(&config.FetchConcurrency, "fetch-concurrency", 0, "The most schemas to fetch over HTTP at once, however many are validated or prefetched in parallel, to protect a shared schema mirror. 0 means no limit")
 	cmd.Flags().StringVarP(&config.KubernetesVersion, "kubernetes-version", "v", "master", "Version of Kubernetes to validate against")
 	cmd.Flags().StringVarP(&config.OutputFormat, "output", "o", "", fmt.Sprintf("The format of the output of this script. Options are: %v", validOutputs()))
+	cmd.Flags().BoolVar(&config.DedupeErrors, "dedupe-errors", false, "Collapse identical errors for the same kind into a single entry listing the affected files")
+	cmd.Flags().IntVar(&config.ReportFormatVersion, "report-format-version", ReportFormatVersion1, fmt.Sprintf("The version of the structured output format to use. Options are: %v", validReportFormatVersions()))
+	cmd.Flags().StringVar(&config.JSONShape, "json-shape", JSONShapeFlat, fmt.Sprintf("The shape of the json output, a single array of documents or documents grouped by file. Options are: %v", validJSONShapes()))
 	cmd.Flags().BoolVar(&config.Quiet, "quiet", false, "Silences any output aside from the direct results")
 	cmd.Flags().BoolVar(&config.InsecureSkipTLSVerify, "insecure-skip-tls-verify", false, "If true, the server's certificate will not be checked for validity. This will make your HTTPS connections insecure")
 
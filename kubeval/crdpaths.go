@@ -0,0 +1,293 @@
+package kubeval
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// printerColumnTypes are the types an additional printer column may have
+var printerColumnTypes = []string{"integer", "number", "string", "boolean", "date"}
+
+// objectMetaFields are the fields every resource has, which the schema of
+// a CustomResourceDefinition need not describe
+var objectMetaFields = []string{"apiVersion", "kind", "metadata"}
+
+// jsonPathStep is a step of a JSONPath: a field, or the elements of an
+// array picked by an index, wildcard, slice or filter
+type jsonPathStep struct {
+	field   string
+	element bool
+}
+
+// parseJSONPath parses the JSONPath of a printer column or subresource,
+// such as .spec.replicas or .status.conditions[?(@.type=="Ready")].status,
+// into its steps. A path which descends recursively with .. is parsed up to
+// that point, as the fields it reaches are not known. With simple, only
+// fields are allowed, as the API server requires of subresource paths.
+func parseJSONPath(path string, simple bool) ([]jsonPathStep, error) {
+	if !strings.HasPrefix(path, ".") {
+		return nil, fmt.Errorf("it must start with '.'")
+	}
+	var steps []jsonPathStep
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			if strings.HasPrefix(path[i:], "..") {
+				if simple {
+					return nil, fmt.Errorf("recursive descent with '..' is not allowed")
+				}
+				return steps, nil
+			}
+			end := i + 1
+			for end < len(path) && path[end] != '.' && path[end] != '[' {
+				end++
+			}
+			if end == i+1 {
+				return nil, fmt.Errorf("a field name is missing after the '.' at offset %d", i)
+			}
+			steps = append(steps, jsonPathStep{field: path[i+1 : end]})
+			i = end
+		case '[':
+			end := closingBracket(path, i)
+			if end < 0 {
+				return nil, fmt.Errorf("the '[' at offset %d is not closed", i)
+			}
+			inner := strings.TrimSpace(path[i+1 : end])
+			if field, quoted := unquoteField(inner); quoted {
+				steps = append(steps, jsonPathStep{field: field})
+			} else if simple {
+				return nil, fmt.Errorf("only fields are allowed, not array elements such as [%s]", inner)
+			} else if inner == "" {
+				return nil, fmt.Errorf("the '[]' at offset %d is empty", i)
+			} else {
+				steps = append(steps, jsonPathStep{element: true})
+			}
+			i = end + 1
+		default:
+			return nil, fmt.Errorf("unexpected '%c' at offset %d", path[i], i)
+		}
+	}
+	return steps, nil
+}
+
+// closingBracket returns the offset of the ] closing the [ at offset open
+// of path, skipping over quoted strings and nested brackets and
+// parentheses, or -1 if it is not closed
+func closingBracket(path string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+			if depth == 0 {
+				if c == ']' {
+					return i
+				}
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
+// unquoteField returns the field named by the contents of brackets, such
+// as 'app.kubernetes.io/name', and whether they were a quoted field name
+func unquoteField(inner string) (string, bool) {
+	if len(inner) < 2 || (inner[0] != '\'' && inner[0] != '"') || inner[len(inner)-1] != inner[0] {
+		return "", false
+	}
+	return inner[1 : len(inner)-1], true
+}
+
+// lookupSchemaPath returns the node of schema the steps of a JSONPath lead
+// to, and whether they lead anywhere. The node is nil when the path enters
+// fields the schema keeps without describing them.
+func lookupSchemaPath(schema map[string]interface{}, steps []jsonPathStep) (map[string]interface{}, bool) {
+	node := schema
+	for _, step := range steps {
+		var next map[string]interface{}
+		var found bool
+		if step.element {
+			next, found = node["items"].(map[string]interface{})
+		} else {
+			next, found = lookupPath(node, []string{"properties", step.field}).(map[string]interface{})
+			if !found {
+				next, found = node["additionalProperties"].(map[string]interface{})
+			}
+		}
+		if !found {
+			if node["x-kubernetes-preserve-unknown-fields"] == true || node["additionalProperties"] == true {
+				return nil, true
+			}
+			return nil, false
+		}
+		node = next
+	}
+	return node, true
+}
+
+// crdPathChecker collects the problems found by checkCRDPaths
+type crdPathChecker struct {
+	errors []gojsonschema.ResultError
+}
+
+func (c *crdPathChecker) problem(errorType string, path []string, value interface{}, description string) {
+	c.errors = append(c.errors, newCheckError(errorType, path, value, description))
+}
+
+// checkCRDPaths checks the additional printer columns and the subresources
+// of a CustomResourceDefinition: that their JSONPaths are well formed and
+// refer to fields in the schema of the version they belong to, as columns
+// which do not are silently empty and a broken scale subresource fails at
+// runtime. Both the apiextensions.k8s.io/v1 form, where each version has
+// its own, and the v1beta1 one, where they may be shared, are checked.
+func checkCRDPaths(body map[string]interface{}) []gojsonschema.ResultError {
+	c := &crdPathChecker{}
+	jsonPathKey := "jsonPath"
+	if apiVersion, _ := body["apiVersion"].(string); apiVersion == "apiextensions.k8s.io/v1beta1" {
+		jsonPathKey = "JSONPath"
+	}
+
+	spec, _ := body["spec"].(map[string]interface{})
+	sharedSchema, _ := lookupPath(spec, []string{"validation", "openAPIV3Schema"}).(map[string]interface{})
+	c.checkColumns(spec, []string{"spec"}, jsonPathKey, sharedSchema, "")
+	c.checkSubresources(spec, []string{"spec"}, sharedSchema, "")
+
+	versions, _ := spec["versions"].([]interface{})
+	for i, item := range versions {
+		version, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := version["name"].(string)
+		schema, found := lookupPath(version, []string{"schema", "openAPIV3Schema"}).(map[string]interface{})
+		if !found {
+			schema = sharedSchema
+		}
+		path := []string{"spec", "versions", strconv.Itoa(i)}
+		c.checkColumns(version, path, jsonPathKey, schema, name)
+		c.checkSubresources(version, path, schema, name)
+	}
+	return c.errors
+}
+
+// checkColumns checks the additionalPrinterColumns of owner, which is
+// either a version or the spec of a CustomResourceDefinition
+func (c *crdPathChecker) checkColumns(owner map[string]interface{}, path []string, jsonPathKey string, schema map[string]interface{}, version string) {
+	columns, _ := owner["additionalPrinterColumns"].([]interface{})
+	for i, item := range columns {
+		column, _ := item.(map[string]interface{})
+		columnPath := append(append([]string{}, path...), "additionalPrinterColumns", strconv.Itoa(i))
+		name, _ := column["name"].(string)
+		subject := fmt.Sprintf("Printer column '%s'", name)
+
+		if columnType, ok := column["type"].(string); ok && !in(printerColumnTypes, columnType) {
+			c.problem("printer_column", append(columnPath, "type"), columnType, fmt.Sprintf("%s has type '%s', which must be one of %s", subject, columnType, strings.Join(printerColumnTypes, ", ")))
+		}
+
+		jsonPath, _ := column[jsonPathKey].(string)
+		if jsonPath == "" {
+			continue
+		}
+		steps, err := parseJSONPath(jsonPath, false)
+		if err != nil {
+			c.problem("printer_column", append(columnPath, jsonPathKey), jsonPath, fmt.Sprintf("%s has an invalid JSONPath %s: %s", subject, jsonPath, err))
+			continue
+		}
+		c.checkReference("printer_column", append(columnPath, jsonPathKey), jsonPath, steps, schema, version, subject)
+	}
+}
+
+// scalePaths are the paths of the scale subresource, the fields they must
+// be under and the type of the field they must refer to
+var scalePaths = []struct {
+	key       string
+	prefixes  []string
+	fieldType string
+}{
+	{key: "specReplicasPath", prefixes: []string{".spec"}, fieldType: "integer"},
+	{key: "statusReplicasPath", prefixes: []string{".status"}, fieldType: "integer"},
+	{key: "labelSelectorPath", prefixes: []string{".spec", ".status"}, fieldType: "string"},
+}
+
+// checkSubresources checks the status and scale subresources of owner,
+// which is either a version or the spec of a CustomResourceDefinition
+func (c *crdPathChecker) checkSubresources(owner map[string]interface{}, path []string, schema map[string]interface{}, version string) {
+	subresources, ok := owner["subresources"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	subresourcesPath := append(append([]string{}, path...), "subresources")
+
+	if _, enabled := subresources["status"]; enabled && schema != nil {
+		if _, found := lookupSchemaPath(schema, []jsonPathStep{{field: "status"}}); !found {
+			c.problem("subresource", append(subresourcesPath, "status"), nil, fmt.Sprintf("The status subresource is enabled, but %s has no status field", schemaDescription(version)))
+		}
+	}
+
+	scale, ok := subresources["scale"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, scalePath := range scalePaths {
+		value, _ := scale[scalePath.key].(string)
+		if value == "" {
+			continue
+		}
+		fieldPath := append(append([]string{}, subresourcesPath...), "scale", scalePath.key)
+		subject := "Scale subresource " + scalePath.key
+		steps, err := parseJSONPath(value, true)
+		if err != nil {
+			c.problem("subresource", fieldPath, value, fmt.Sprintf("%s %s is not a valid JSONPath: %s", subject, value, err))
+			continue
+		}
+		under := false
+		for _, prefix := range scalePath.prefixes {
+			under = under || strings.HasPrefix(value, prefix+".")
+		}
+		if !under {
+			c.problem("subresource", fieldPath, value, fmt.Sprintf("%s %s must be a path under %s", subject, value, strings.Join(scalePath.prefixes, " or ")))
+			continue
+		}
+		node, found := c.checkReference("subresource", fieldPath, value, steps, schema, version, subject)
+		if fieldType, _ := node["type"].(string); found && fieldType != "" && fieldType != scalePath.fieldType {
+			c.problem("subresource", fieldPath, value, fmt.Sprintf("%s %s refers to a field of type %s rather than %s", subject, value, fieldType, scalePath.fieldType))
+		}
+	}
+}
+
+// checkReference reports a JSONPath which refers to a field not in schema,
+// returning the node of the schema it refers to, if known, and whether it
+// was found. Fields every resource has, such as metadata, are not checked.
+func (c *crdPathChecker) checkReference(errorType string, path []string, jsonPath string, steps []jsonPathStep, schema map[string]interface{}, version string, subject string) (map[string]interface{}, bool) {
+	if schema == nil || len(steps) == 0 || (!steps[0].element && in(objectMetaFields, steps[0].field)) {
+		return nil, false
+	}
+	node, found := lookupSchemaPath(schema, steps)
+	if !found {
+		c.problem(errorType, path, jsonPath, fmt.Sprintf("%s refers to %s, which is not a field in %s", subject, jsonPath, schemaDescription(version)))
+	}
+	return node, found
+}
+
+// schemaDescription describes the schema of a version of a
+// CustomResourceDefinition, or the schema shared by all of them
+func schemaDescription(version string) string {
+	if version == "" {
+		return "the schema"
+	}
+	return fmt.Sprintf("the schema of version %s", version)
+}
@@ -0,0 +1,106 @@
+package kubeval
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckCRDPaths(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/crd_paths.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "crd_paths.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	config.IgnoreMissingSchemas = true
+	config.CheckCRDPaths = true
+
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	errors := []string{}
+	for _, e := range results[0].Errors {
+		errors = append(errors, e.String())
+	}
+	assert.Equal(t, []string{
+		"spec.versions.0.additionalPrinterColumns.4.jsonPath: Printer column 'Colour' refers to .spec.colour, which is not a field in the schema of version v1",
+		"spec.versions.0.additionalPrinterColumns.5.type: Printer column 'Size' has type 'text', which must be one of integer, number, string, boolean, date",
+		"spec.versions.0.additionalPrinterColumns.5.jsonPath: Printer column 'Size' has an invalid JSONPath .spec.sizes[0: the '[' at offset 11 is not closed",
+		"spec.versions.0.subresources.scale.specReplicasPath: Scale subresource specReplicasPath refers to .spec.replica, which is not a field in the schema of version v1",
+		"spec.versions.0.subresources.scale.labelSelectorPath: Scale subresource labelSelectorPath .status.selector refers to a field of type object rather than string",
+		"spec.versions.1.subresources.status: The status subresource is enabled, but the schema of version v1beta1 has no status field",
+		"spec.versions.1.subresources.scale.specReplicasPath: Scale subresource specReplicasPath .replicas must be a path under .spec",
+		"spec.versions.1.subresources.scale.statusReplicasPath: Scale subresource statusReplicasPath .status.conditions[0].count is not a valid JSONPath: only fields are allowed, not array elements such as [0]",
+	}, errors)
+
+	config.CheckCRDPaths = false
+	results, _ = Validate(fileContents, config)
+	assert.Empty(t, results[0].Errors)
+}
+
+func TestCheckCRDPathsSharedColumns(t *testing.T) {
+	body := map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1beta1",
+		"spec": map[string]interface{}{
+			"validation": map[string]interface{}{
+				"openAPIV3Schema": map[string]interface{}{
+					"properties": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"properties": map[string]interface{}{
+								"size": map[string]interface{}{"type": "integer"},
+							},
+						},
+					},
+				},
+			},
+			"additionalPrinterColumns": []interface{}{
+				map[string]interface{}{"name": "Size", "type": "integer", "JSONPath": ".spec.size"},
+				map[string]interface{}{"name": "Shape", "type": "string", "JSONPath": ".spec['shape']"},
+			},
+			"versions": []interface{}{
+				map[string]interface{}{"name": "v1beta1"},
+				map[string]interface{}{"name": "v1alpha1"},
+			},
+		},
+	}
+	errors := []string{}
+	for _, e := range checkCRDPaths(body) {
+		errors = append(errors, e.String())
+	}
+	assert.Equal(t, []string{
+		"spec.additionalPrinterColumns.1.JSONPath: Printer column 'Shape' refers to .spec['shape'], which is not a field in the schema",
+	}, errors)
+}
+
+func TestParseJSONPath(t *testing.T) {
+	var tests = []struct {
+		Path   string
+		Simple bool
+		Steps  []jsonPathStep
+		Error  string
+	}{
+		{Path: ".spec.replicas", Simple: true, Steps: []jsonPathStep{{field: "spec"}, {field: "replicas"}}},
+		{Path: ".metadata.labels['app.kubernetes.io/name']", Steps: []jsonPathStep{{field: "metadata"}, {field: "labels"}, {field: "app.kubernetes.io/name"}}},
+		{Path: `.status.conditions[?(@.type=="Ready")].status`, Steps: []jsonPathStep{{field: "status"}, {field: "conditions"}, {element: true}, {field: "status"}}},
+		{Path: ".spec.containers[*].image", Steps: []jsonPathStep{{field: "spec"}, {field: "containers"}, {element: true}, {field: "image"}}},
+		{Path: ".status..ready", Steps: []jsonPathStep{{field: "status"}}},
+		{Path: ".status..ready", Simple: true, Error: "recursive descent with '..' is not allowed"},
+		{Path: "spec.replicas", Error: "it must start with '.'"},
+		{Path: ".spec.", Error: "a field name is missing after the '.' at offset 5"},
+		{Path: ".spec[]", Error: "the '[]' at offset 5 is empty"},
+		{Path: ".spec.items[0]", Simple: true, Error: "only fields are allowed, not array elements such as [0]"},
+	}
+	for _, test := range tests {
+		steps, err := parseJSONPath(test.Path, test.Simple)
+		if test.Error != "" {
+			assert.EqualError(t, err, test.Error, test.Path)
+			continue
+		}
+		if assert.NoError(t, err, test.Path) {
+			assert.Equal(t, test.Steps, steps, test.Path)
+		}
+	}
+}
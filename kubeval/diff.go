@@ -0,0 +1,188 @@
+package kubeval
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hunkHeaderPattern matches the header of a hunk in a unified diff, such as
+// @@ -1,5 +1,6 @@, capturing the length of the old side and the start and
+// length of the new side
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -\d+(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// defaultDiffContext is the number of unchanged lines diff shows around
+// each change unless told otherwise, as kubectl diff does by default
+const defaultDiffContext = 3
+
+// diffFile is the new side of a single file in a unified diff
+type diffFile struct {
+	name string
+	// nextLine is the line number of the new side expected next
+	nextLine int
+	lines    []string
+
+	// changed is set once a line has been added or removed
+	changed bool
+	// leading and trailing are the unchanged lines before the first change
+	// and after the last one
+	leading, trailing int
+}
+
+// context records an unchanged line of the new side
+func (f *diffFile) context(line string) {
+	f.lines = append(f.lines, line)
+	f.nextLine++
+	if f.changed {
+		f.trailing++
+	} else {
+		f.leading++
+	}
+}
+
+// change records a line added to or removed from the new side
+func (f *diffFile) change() {
+	f.changed = true
+	f.trailing = 0
+}
+
+// mayBeTruncated returns whether lines may be missing from the end of the
+// file. A diff does not say how long a file is, but when lines follow the
+// last change diff shows only its context of unchanged lines after it. Such
+// a file was diffed with the default context if at most that many unchanged
+// lines precede its first change, as no more would be shown.
+func (f *diffFile) mayBeTruncated() bool {
+	return f.trailing == defaultDiffContext && f.leading <= defaultDiffContext
+}
+
+// ParseDiff reconstructs the new side of each file in a unified diff, such as
+// the output of `kubectl diff`, so the proposed manifests can be validated.
+// Each file is named after the base name of its path on the new side. Files
+// deleted by the diff are ignored.
+//
+// Only lines present in the diff can be reconstructed, so the diff must
+// include the whole of each file, as it does for new objects or when
+// generated with a large amount of context, for example using
+// KUBECTL_EXTERNAL_DIFF="diff -u -N -U 100000". Lines missing from the start
+// or middle of a file are an error. Lines missing from the end cannot be told
+// apart for certain, so files which appear to have been diffed with the
+// default context, and so may be cut off, are left out and a warning is
+// returned for each.
+func ParseDiff(input []byte) ([]File, []string, error) {
+	var files []File
+	var warnings []string
+	var current *diffFile
+	remainingOld, remainingNew := 0, 0
+	sawFileHeader := false
+
+	finish := func() {
+		if current != nil && current.name != "" {
+			if current.mayBeTruncated() {
+				warnings = append(warnings, fmt.Sprintf("Diff for %s may not include the whole file, as it shows only %d unchanged lines after the last change, so it was not validated. Generate the diff with more context, for example using KUBECTL_EXTERNAL_DIFF=\"diff -u -N -U 100000\"", current.name, defaultDiffContext))
+			} else {
+				files = append(files, NewFile(current.name, []byte(strings.Join(current.lines, "\n")+"\n")))
+			}
+		}
+		current = nil
+	}
+
+	lines := strings.Split(string(bytes.Replace(input, []byte("\r\n"), []byte("\n"), -1)), "\n")
+	for i, line := range lines {
+		lineNumber := i + 1
+
+		if remainingOld > 0 || remainingNew > 0 {
+			switch {
+			case strings.HasPrefix(line, "+") && remainingNew > 0:
+				current.lines = append(current.lines, line[1:])
+				current.nextLine++
+				current.change()
+				remainingNew--
+			case strings.HasPrefix(line, "-") && remainingOld > 0:
+				// removed lines are not part of the new side
+				current.change()
+				remainingOld--
+			case (strings.HasPrefix(line, " ") || line == "") && remainingOld > 0 && remainingNew > 0:
+				// some tools strip the trailing space from empty context lines
+				if line != "" {
+					line = line[1:]
+				}
+				current.context(line)
+				remainingOld--
+				remainingNew--
+			case strings.HasPrefix(line, `\`):
+				// "\ No newline at end of file"
+			default:
+				return nil, nil, fmt.Errorf("Malformed diff at line %d: unexpected line in hunk: %q", lineNumber, line)
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff "):
+			finish()
+		case strings.HasPrefix(line, "--- "):
+			finish()
+		case strings.HasPrefix(line, "+++ "):
+			if current != nil {
+				return nil, nil, fmt.Errorf("Malformed diff at line %d: unexpected file header", lineNumber)
+			}
+			current = &diffFile{name: diffFileName(line[4:]), nextLine: 1}
+			sawFileHeader = true
+		case strings.HasPrefix(line, "@@"):
+			if current == nil {
+				return nil, nil, fmt.Errorf("Malformed diff at line %d: hunk found before a file header", lineNumber)
+			}
+			found := hunkHeaderPattern.FindStringSubmatch(line)
+			if found == nil {
+				return nil, nil, fmt.Errorf("Malformed diff at line %d: invalid hunk header: %q", lineNumber, line)
+			}
+			remainingOld = hunkLength(found[1])
+			start, _ := strconv.Atoi(found[2])
+			remainingNew = hunkLength(found[3])
+			if remainingNew > 0 && start != current.nextLine {
+				return nil, nil, fmt.Errorf("Diff for %s does not include the whole file, lines %d to %d are missing. Generate the diff with more context, for example using KUBECTL_EXTERNAL_DIFF=\"diff -u -N -U 100000\"", current.name, current.nextLine, start-1)
+			}
+		case strings.HasPrefix(line, `\`), strings.TrimSpace(line) == "":
+			// "\ No newline at end of file", or trailing blank lines
+		default:
+			if current != nil {
+				return nil, nil, fmt.Errorf("Malformed diff at line %d: unexpected line: %q", lineNumber, line)
+			}
+			// anything before the first file header, such as a commit message, is ignored
+		}
+	}
+
+	if remainingOld > 0 || remainingNew > 0 {
+		return nil, nil, fmt.Errorf("Malformed diff: the last hunk for %s is truncated", current.name)
+	}
+	finish()
+
+	if !sawFileHeader && len(bytes.TrimSpace(input)) > 0 {
+		return nil, nil, fmt.Errorf("Malformed diff: no files found")
+	}
+	return files, warnings, nil
+}
+
+// hunkLength returns the length of one side of a hunk, which is 1 when
+// omitted from the header
+func hunkLength(value string) int {
+	if value == "" {
+		return 1
+	}
+	length, _ := strconv.Atoi(value)
+	return length
+}
+
+// diffFileName returns the base name of a path from a diff file header,
+// without any timestamp, or an empty name for deleted files
+func diffFileName(header string) string {
+	name := strings.SplitN(header, "\t", 2)[0]
+	name = strings.TrimSpace(name)
+	if name == "/dev/null" {
+		return ""
+	}
+	return path.Base(name)
+}
@@ -0,0 +1,92 @@
+package kubeval
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDiff(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/kubectl_diff.diff")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	files, warnings, err := ParseDiff(fileContents)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assert.Empty(t, warnings)
+	if !assert.Len(t, files, 2) {
+		return
+	}
+	assert.Equal(t, "apps.v1.Deployment.default.nginx", files[0].Name)
+	assert.Equal(t, "v1.Service.default.nginx", files[1].Name)
+
+	deployment, _ := files[0].Read()
+	assert.Contains(t, string(deployment), "  replicas: three\n")
+	assert.Contains(t, string(deployment), "      - image: nginx:1.17\n")
+	assert.NotContains(t, string(deployment), "replicas: 2")
+
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	results, err := ValidateFiles(StaticDiscoverer(files), NewSchemaCache(), config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, "invalid", results[0].Status())
+		assert.Equal(t, "valid", results[1].Status())
+	}
+}
+
+func TestParseMalformedDiff(t *testing.T) {
+	var tests = []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{
+			name:  "not a diff",
+			input: "apiVersion: v1\nkind: Service\n",
+			err:   "Malformed diff: no files found",
+		},
+		{
+			name:  "hunk without a file",
+			input: "@@ -1 +1 @@\n-a\n+b\n",
+			err:   "Malformed diff at line 1: hunk found before a file header",
+		},
+		{
+			name:  "invalid hunk header",
+			input: "--- a\n+++ b\n@@ one @@\n",
+			err:   `Malformed diff at line 3: invalid hunk header: "@@ one @@"`,
+		},
+		{
+			name:  "truncated hunk",
+			input: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n",
+			err:   "Malformed diff: the last hunk for b is truncated",
+		},
+		{
+			name:  "partial context",
+			input: "--- a\n+++ b\n@@ -5,1 +5,1 @@\n-a\n+b\n",
+			err:   `Diff for b does not include the whole file, lines 1 to 4 are missing. Generate the diff with more context, for example using KUBECTL_EXTERNAL_DIFF="diff -u -N -U 100000"`,
+		},
+	}
+	for _, test := range tests {
+		_, _, err := ParseDiff([]byte(test.input))
+		assert.EqualError(t, err, test.err, test.name)
+	}
+}
+
+func TestParseDiffDeletedFile(t *testing.T) {
+	files, _, err := ParseDiff([]byte("--- a/service.yaml\n+++ /dev/null\n@@ -1,2 +0,0 @@\n-apiVersion: v1\n-kind: Service\n"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 0)
+}
+
+func TestParseDiffWithDefaultContext(t *testing.T) {
+	input := "--- a/service.yaml\n+++ b/service.yaml\n@@ -1,7 +1,7 @@\n apiVersion: v1\n kind: Service\n metadata:\n-  name: web\n+  name: api\n spec:\n   ports:\n   - port: 80\n"
+	files, warnings, err := ParseDiff([]byte(input))
+	assert.NoError(t, err)
+	assert.Len(t, files, 0)
+	assert.Equal(t, []string{`Diff for service.yaml may not include the whole file, as it shows only 3 unchanged lines after the last change, so it was not validated. Generate the diff with more context, for example using KUBECTL_EXTERNAL_DIFF="diff -u -N -U 100000"`}, warnings)
+}
@@ -0,0 +1,235 @@
+package kubeval
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// File is a named blob of manifests to be validated. It may be read from
+// the filesystem or from anywhere else, such as a git object store.
+type File struct {
+	Name string
+
+	// Read returns the contents of the file
+	Read func() ([]byte, error)
+
+	// Stdin is set for a file which reads from stdin rather than from
+	// disk, whatever its Name
+	Stdin bool
+}
+
+// NewFile returns a File with the given contents already in memory
+func NewFile(name string, contents []byte) File {
+	return File{
+		Name: name,
+		Read: func() ([]byte, error) {
+			return contents, nil
+		},
+	}
+}
+
+// FileDiscoverer finds the files to be validated by ValidateFiles. The
+// default implementation, returned by NewFilesystemDiscoverer, walks
+// the local filesystem.
+type FileDiscoverer interface {
+	Discover() ([]File, error)
+}
+
+// StaticDiscoverer is a FileDiscoverer for a fixed set of files, such as
+// files already held in memory
+type StaticDiscoverer []File
+
+// Discover returns the files
+func (s StaticDiscoverer) Discover() ([]File, error) {
+	return s, nil
+}
+
+// filesystemDiscoverer finds files on the local filesystem
+type filesystemDiscoverer struct {
+	files               []string
+	directories         []string
+	ignoredPathPatterns []string
+}
+
+// NewFilesystemDiscoverer returns a FileDiscoverer for the given files,
+// along with any YAML files found by recursively searching directories.
+// Paths matching any of the ignoredPathPatterns regular expressions are
+// not searched.
+func NewFilesystemDiscoverer(files, directories, ignoredPathPatterns []string) FileDiscoverer {
+	return &filesystemDiscoverer{
+		files:               files,
+		directories:         directories,
+		ignoredPathPatterns: ignoredPathPatterns,
+	}
+}
+
+// Discover returns the files, then the files found in each directory
+func (d *filesystemDiscoverer) Discover() ([]File, error) {
+	files := make([]File, 0, len(d.files))
+	for _, fileName := range d.files {
+		files = append(files, newFilesystemFile(fileName))
+	}
+
+	var allErrors *multierror.Error
+	for _, directory := range d.directories {
+		// A path which is neither a directory nor a regular file, such as a
+		// named pipe or the /dev/fd path of a process substitution, is
+		// validated as a file whatever its name, rather than silently ignored
+		if info, err := os.Stat(directory); err == nil && !info.IsDir() && !info.Mode().IsRegular() {
+			ignored, err := d.isIgnored(directory)
+			if err != nil {
+				allErrors = multierror.Append(allErrors, err)
+			} else if !ignored {
+				files = append(files, newFilesystemFile(directory))
+			}
+			continue
+		}
+		err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			ignored, err := d.isIgnored(path)
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && (strings.HasSuffix(info.Name(), ".yaml") || strings.HasSuffix(info.Name(), ".yml")) && !ignored {
+				files = append(files, newFilesystemFile(path))
+			}
+			return nil
+		})
+		if err != nil {
+			allErrors = multierror.Append(allErrors, err)
+		}
+	}
+
+	return files, allErrors.ErrorOrNil()
+}
+
+// isIgnored returns whether the specified filename should be ignored.
+func (d *filesystemDiscoverer) isIgnored(path string) (bool, error) {
+	for _, p := range d.ignoredPathPatterns {
+		m, err := regexp.MatchString(p, path)
+		if err != nil {
+			return false, err
+		}
+		if m {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func newFilesystemFile(fileName string) File {
+	return File{
+		Name: fileName,
+		Read: func() ([]byte, error) {
+			filePath, _ := filepath.Abs(fileName)
+			return ioutil.ReadFile(filePath)
+		},
+	}
+}
+
+// ValidateFiles validates each of the files found by discoverer, sharing
+// schemaCache between them. Config.PostProcess is given the results of
+// every file at once. Files which cannot be read or validated are
+// reported in the returned error, and the remaining files are validated
+// unless ExitOnError is set.
+func ValidateFiles(discoverer FileDiscoverer, schemaCache map[string]*gojsonschema.Schema, conf ...*Config) ([]ValidationResult, error) {
+	return validateFiles(discoverer, schemaMap(schemaCache), conf...)
+}
+
+// validateFiles validates each of the files found by discoverer, caching
+// the compiled schemas in schemaCache, see ValidateFiles
+func validateFiles(discoverer FileDiscoverer, schemaCache schemaStore, conf ...*Config) ([]ValidationResult, error) {
+	config := NewDefaultConfig()
+	if len(conf) == 1 {
+		// The file name is set for each file, so work on a copy, leaving
+		// the caller's Config untouched
+		copied := *conf[0]
+		config = &copied
+	}
+
+	// Prefetching and validating the files fetch schemas in the same run
+	useFetchSlots(config, schemaCache)
+
+	var results []ValidationResult
+	var errors *multierror.Error
+
+	files, err := discoverer.Discover()
+	if err != nil {
+		errors = multierror.Append(errors, err)
+		if config.ExitOnError {
+			return postProcess(results, config), errors
+		}
+	}
+
+	if config.Prefetch {
+		files = prefetchFiles(files, schemaCache, config)
+	}
+
+	for _, file := range files {
+		contents, err := file.Read()
+		if err != nil {
+			errors = multierror.Append(errors, fmt.Errorf("Could not open file %v", file.Name))
+			if config.ExitOnError {
+				break
+			}
+			continue
+		}
+		config.FileName = file.Name
+		fileResults, err := validateWithStore(contents, schemaCache, config)
+		results = append(results, fileResults...)
+		// Validation stops altogether if a schema cannot be trusted
+		if _, untrusted := err.(*SchemaChecksumError); untrusted {
+			return postProcess(results, config), err
+		}
+		if err != nil {
+			errors = multierror.Append(errors, err)
+			if config.ExitOnError {
+				break
+			}
+		}
+	}
+
+	if errors != nil {
+		errors.ErrorFormat = singleLineErrorFormat
+	}
+	return postProcess(results, config), errors.ErrorOrNil()
+}
+
+// PrefetchFiles reads files and prefetches the schemas they need with
+// PrefetchSchemas. The files are returned with their contents in memory,
+// so they are not read twice, apart from those which could not be read,
+// which are left to be reported when they are validated.
+func PrefetchFiles(files []File, schemaCache map[string]*gojsonschema.Schema, conf ...*Config) []File {
+	config := NewDefaultConfig()
+	if len(conf) == 1 {
+		config = conf[0]
+	}
+	return prefetchFiles(files, schemaMap(schemaCache), config)
+}
+
+// prefetchFiles reads files and prefetches the schemas they need into
+// schemaCache, see PrefetchFiles
+func prefetchFiles(files []File, schemaCache schemaStore, config *Config) []File {
+	read := make([]File, len(files))
+	var inputs [][]byte
+	for i, file := range files {
+		read[i] = file
+		contents, err := file.Read()
+		if err != nil {
+			continue
+		}
+		read[i] = NewFile(file.Name, contents)
+		inputs = append(inputs, contents)
+	}
+	prefetchSchemas(inputs, schemaCache, config)
+	return read
+}
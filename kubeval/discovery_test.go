@@ -0,0 +1,106 @@
+package kubeval
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilesystemDiscoverer(t *testing.T) {
+	discoverer := NewFilesystemDiscoverer([]string{"../fixtures/valid.yaml"}, []string{"../fixtures"}, []string{"duplicates", `\.json$`})
+	files, err := discoverer.Discover()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	names := []string{}
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, "../fixtures/valid.yaml", names[0])
+	assert.Contains(t, names, "../fixtures/invalid.yaml")
+	for _, name := range names {
+		assert.NotContains(t, name, "duplicates")
+		assert.NotContains(t, name, ".json")
+	}
+
+	contents, err := files[0].Read()
+	assert.NoError(t, err)
+	assert.Contains(t, string(contents), "ReplicationController")
+}
+
+func TestFilesystemDiscovererMissingDirectory(t *testing.T) {
+	_, err := NewFilesystemDiscoverer([]string{}, []string{"../fixtures/not-here"}, []string{}).Discover()
+	assert.Error(t, err)
+}
+
+func TestValidateFilesWithCustomDiscoverer(t *testing.T) {
+	discoverer := StaticDiscoverer{
+		NewFile("service.yaml", []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: frontend\n")),
+		{
+			Name: "unreadable.yaml",
+			Read: func() ([]byte, error) {
+				return nil, errors.New("object not found")
+			},
+		},
+		NewFile("deployment.yaml", []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: frontend\nspec:\n  replicas: many\n")),
+	}
+
+	config := NewDefaultConfig()
+	config.FileName = "stdin"
+	config.SchemaLocation = localSchemaLocation()
+	results, err := ValidateFiles(discoverer, NewSchemaCache(), config)
+	assert.EqualError(t, err, "Could not open file unreadable.yaml")
+	assert.Equal(t, "stdin", config.FileName)
+
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, "service.yaml", results[0].FileName)
+		assert.Equal(t, "valid", results[0].Status())
+		assert.Equal(t, "deployment.yaml", results[1].FileName)
+		assert.Equal(t, "invalid", results[1].Status())
+	}
+}
+
+func TestValidateFilesPostProcess(t *testing.T) {
+	discoverer := StaticDiscoverer{
+		NewFile("service.yaml", []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: frontend\n")),
+		NewFile("deployment.yaml", []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: frontend\nspec:\n  replicas: many\n")),
+	}
+
+	// Errors in Deployments are only warnings in this organisation, and
+	// valid resources are not reported
+	calls := 0
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	config.PostProcess = func(results []ValidationResult) []ValidationResult {
+		calls++
+		var processed []ValidationResult
+		for _, r := range results {
+			if r.Kind == "Deployment" {
+				r.Warnings = append(r.Warnings, r.Errors...)
+				r.Errors = nil
+			}
+			if len(r.Errors) > 0 || len(r.Warnings) > 0 {
+				processed = append(processed, r)
+			}
+		}
+		return processed
+	}
+
+	results, err := ValidateFiles(discoverer, NewSchemaCache(), config)
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls, "the results of every file should be post-processed at once")
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "deployment.yaml", results[0].FileName)
+		assert.Empty(t, results[0].Errors)
+		assert.Len(t, results[0].Warnings, 3)
+	}
+
+	// The results of a single input are post-processed too
+	results, err = Validate([]byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: frontend\n"), config)
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Empty(t, results)
+}
@@ -0,0 +1,75 @@
+//go:build !windows
+// +build !windows
+
+package kubeval
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeFIFO creates a named pipe and writes contents to it in the
+// background, as a shell does for process substitution
+func writeFIFO(t *testing.T, path, contents string) string {
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	go func() {
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return
+		}
+		f.WriteString(contents)
+		f.Close()
+	}()
+	return path
+}
+
+func TestValidateFilesFromNamedPipe(t *testing.T) {
+	manifest := "apiVersion: v1\nkind: Service\nmetadata:\n  name: frontend\n"
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	dir, err := ioutil.TempDir("", "kubeval")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, discoverer := range []FileDiscoverer{
+		NewFilesystemDiscoverer([]string{writeFIFO(t, filepath.Join(dir, "62"), manifest)}, nil, nil),
+		// a named pipe passed as a directory is validated as a file, even
+		// though it has no .yaml extension
+		NewFilesystemDiscoverer(nil, []string{writeFIFO(t, filepath.Join(dir, "63"), manifest)}, nil),
+	} {
+		results, err := ValidateFiles(discoverer, NewSchemaCache(), config)
+		assert.NoError(t, err)
+		if assert.Len(t, results, 1) {
+			assert.Equal(t, "Service", results[0].Kind)
+			assert.Equal(t, "valid", results[0].Status())
+		}
+	}
+
+	// ignored paths are not read, even when passed as a directory
+	ignored := filepath.Join(dir, "64")
+	if err := syscall.Mkfifo(ignored, 0600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	files, err := NewFilesystemDiscoverer(nil, []string{ignored}, []string{"64$"}).Discover()
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+
+	// a regular file passed as a directory is still only validated if it
+	// has a YAML extension
+	path := filepath.Join(dir, "manifest.txt")
+	if err := ioutil.WriteFile(path, []byte(manifest), 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	files, err = NewFilesystemDiscoverer(nil, []string{path}, nil).Discover()
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
@@ -0,0 +1,59 @@
+package kubeval
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// documentStartPattern matches a line starting a YAML document, which may
+// be followed by spaces or a comment
+var documentStartPattern = regexp.MustCompile(`^---[ \t]*(?:#.*)?$`)
+
+// documentEndPattern matches a line ending a YAML document
+var documentEndPattern = regexp.MustCompile(`^\.\.\.[ \t]*(?:#.*)?$`)
+
+// splitDocuments splits a stream of YAML documents on the lines which
+// separate them: ---, or any of separators, ignoring trailing whitespace.
+// A ... line ends a document, and anything after it other than comments
+// is taken to start the next one.
+func splitDocuments(input []byte, separators []string) [][]byte {
+	var documents [][]byte
+	var lines [][]byte
+	// ended is set by a ... line, after which lines up to the next
+	// separator are only a document if they are more than comments
+	ended := false
+
+	flush := func() {
+		document := bytes.Join(lines, []byte("\n"))
+		if !ended || !isCommentOnly(document) {
+			documents = append(documents, document)
+		}
+		lines = nil
+		ended = false
+	}
+
+	for _, line := range bytes.Split(input, []byte("\n")) {
+		trimmed := strings.TrimRight(string(line), " \t\r")
+		switch {
+		case documentStartPattern.MatchString(trimmed) || in(separators, trimmed):
+			flush()
+		case documentEndPattern.MatchString(trimmed):
+			if !ended {
+				flush()
+				ended = true
+			}
+		default:
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > 0 || !ended {
+		flush()
+	}
+
+	// Ignore a license header or other comments above the first separator
+	if len(documents) > 1 && isCommentOnly(documents[0]) {
+		documents = documents[1:]
+	}
+	return documents
+}
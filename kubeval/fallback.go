@@ -0,0 +1,209 @@
+package kubeval
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// The fallback schemas below are used with Config.OfflineFallback when the
+// schema locations cannot be reached. They are much less thorough than the
+// generated schemas, covering only the overall structure of common core
+// kinds, so results validated against them are reported as such.
+
+func fallbackObject(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func fallbackArray(items map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": "array", "items": items}
+}
+
+func fallbackType(types ...string) map[string]interface{} {
+	return map[string]interface{}{"type": append(types, "null")}
+}
+
+func fallbackStringMap() map[string]interface{} {
+	return map[string]interface{}{
+		"type":                 []string{"object", "null"},
+		"additionalProperties": fallbackType("string"),
+	}
+}
+
+func fallbackObjectMeta() map[string]interface{} {
+	return fallbackObject(map[string]interface{}{
+		"name":         fallbackType("string"),
+		"generateName": fallbackType("string"),
+		"namespace":    fallbackType("string"),
+		"labels":       fallbackStringMap(),
+		"annotations":  fallbackStringMap(),
+	})
+}
+
+func fallbackLabelSelector() map[string]interface{} {
+	return fallbackObject(map[string]interface{}{
+		"matchLabels":      fallbackStringMap(),
+		"matchExpressions": fallbackArray(fallbackObject(map[string]interface{}{"key": fallbackType("string"), "operator": fallbackType("string")}, "key", "operator")),
+	})
+}
+
+func fallbackContainer() map[string]interface{} {
+	return fallbackObject(map[string]interface{}{
+		"name":  fallbackType("string"),
+		"image": fallbackType("string"),
+		"ports": fallbackArray(fallbackObject(map[string]interface{}{
+			"containerPort": fallbackType("integer"),
+			"protocol":      fallbackType("string"),
+		}, "containerPort")),
+		"env":       fallbackArray(fallbackObject(map[string]interface{}{"name": fallbackType("string")}, "name")),
+		"resources": fallbackObject(map[string]interface{}{"limits": fallbackType("object"), "requests": fallbackType("object")}),
+	}, "name")
+}
+
+func fallbackPodSpec() map[string]interface{} {
+	return fallbackObject(map[string]interface{}{
+		"containers":         fallbackArray(fallbackContainer()),
+		"initContainers":     fallbackArray(fallbackContainer()),
+		"serviceAccountName": fallbackType("string"),
+		"nodeSelector":       fallbackStringMap(),
+		"volumes":            fallbackArray(fallbackObject(map[string]interface{}{"name": fallbackType("string")}, "name")),
+	}, "containers")
+}
+
+func fallbackPodTemplate() map[string]interface{} {
+	return fallbackObject(map[string]interface{}{
+		"metadata": fallbackObjectMeta(),
+		"spec":     fallbackPodSpec(),
+	})
+}
+
+func fallbackResource(spec map[string]interface{}, required ...string) map[string]interface{} {
+	properties := map[string]interface{}{
+		"apiVersion": fallbackType("string"),
+		"kind":       fallbackType("string"),
+		"metadata":   fallbackObjectMeta(),
+	}
+	if spec != nil {
+		properties["spec"] = spec
+	}
+	return fallbackObject(properties, required...)
+}
+
+func fallbackWorkload(extra map[string]interface{}) map[string]interface{} {
+	properties := map[string]interface{}{
+		"replicas": fallbackType("integer"),
+		"selector": fallbackLabelSelector(),
+		"template": fallbackPodTemplate(),
+	}
+	for key, value := range extra {
+		properties[key] = value
+	}
+	return fallbackResource(fallbackObject(properties, "selector", "template"))
+}
+
+func fallbackJobSpec() map[string]interface{} {
+	return fallbackObject(map[string]interface{}{
+		"backoffLimit": fallbackType("integer"),
+		"completions":  fallbackType("integer"),
+		"parallelism":  fallbackType("integer"),
+		"template":     fallbackPodTemplate(),
+	}, "template")
+}
+
+// fallbackSchemas returns the fallback schemas, keyed by VersionKind
+func fallbackSchemas() map[string]map[string]interface{} {
+	return map[string]map[string]interface{}{
+		"v1/Pod": fallbackResource(fallbackPodSpec()),
+		"v1/Service": fallbackResource(fallbackObject(map[string]interface{}{
+			"type":     fallbackType("string"),
+			"selector": fallbackStringMap(),
+			"ports": fallbackArray(fallbackObject(map[string]interface{}{
+				"name":       fallbackType("string"),
+				"port":       fallbackType("integer"),
+				"targetPort": fallbackType("integer", "string"),
+				"protocol":   fallbackType("string"),
+			}, "port")),
+		})),
+		"v1/ConfigMap":             fallbackResource(nil),
+		"v1/Secret":                fallbackResource(nil),
+		"v1/Namespace":             fallbackResource(nil),
+		"v1/ServiceAccount":        fallbackResource(nil),
+		"v1/PersistentVolumeClaim": fallbackResource(fallbackObject(map[string]interface{}{"accessModes": fallbackArray(fallbackType("string"))})),
+		"apps/v1/Deployment":       fallbackWorkload(map[string]interface{}{"strategy": fallbackType("object")}),
+		"apps/v1/StatefulSet":      fallbackWorkload(map[string]interface{}{"serviceName": fallbackType("string")}),
+		"apps/v1/DaemonSet":        fallbackWorkload(nil),
+		"apps/v1/ReplicaSet":       fallbackWorkload(nil),
+		"batch/v1/Job":             fallbackResource(fallbackJobSpec()),
+		"batch/v1/CronJob": fallbackResource(fallbackObject(map[string]interface{}{
+			"schedule": fallbackType("string"),
+			"jobTemplate": fallbackObject(map[string]interface{}{
+				"metadata": fallbackObjectMeta(),
+				"spec":     fallbackJobSpec(),
+			}),
+		}, "schedule", "jobTemplate")),
+		"networking.k8s.io/v1/Ingress": fallbackResource(fallbackObject(map[string]interface{}{
+			"ingressClassName": fallbackType("string"),
+			"rules":            fallbackArray(fallbackObject(map[string]interface{}{"host": fallbackType("string"), "http": fallbackType("object")})),
+			"tls":              fallbackArray(fallbackType("object")),
+		})),
+	}
+}
+
+// fallbackCacheKey returns the key under which the fallback schema for a
+// resource is cached, alongside the schemas from the schema locations
+func fallbackCacheKey(resource *ValidationResult) string {
+	return "fallback@" + resource.VersionKind()
+}
+
+// httpStatusPattern matches the error returned when a schema location
+// responds with a status other than 200 OK, capturing the status code
+var httpStatusPattern = regexp.MustCompile(`^Could not read schema from HTTP, response status is (\d{3})`)
+
+// isNetworkFailure returns whether any of the errors from loading a schema
+// are because a schema location could not be reached, or responded with a
+// server error or by rate limiting, rather than because the schema does not
+// exist there
+func isNetworkFailure(errors []error) bool {
+	for _, err := range errors {
+		if _, ok := err.(*url.Error); ok {
+			return true
+		}
+		if found := httpStatusPattern.FindStringSubmatch(err.Error()); found != nil {
+			code, _ := strconv.Atoi(found[1])
+			if code >= http.StatusInternalServerError || code == http.StatusTooManyRequests {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// loadFallbackSchema returns the fallback schema for the resource, caching
+// it, or nil if there is none
+func loadFallbackSchema(resource *ValidationResult, schemaCache schemaStore) (*gojsonschema.Schema, error) {
+	key := fallbackCacheKey(resource)
+	if schema, ok := schemaCache.load(key); ok {
+		return schema, nil
+	}
+	var schema *gojsonschema.Schema
+	if definition, found := fallbackSchemas()[resource.VersionKind()]; found {
+		var err error
+		schema, err = gojsonschema.NewSchema(gojsonschema.NewGoLoader(definition))
+		if err != nil {
+			return nil, multierror.Prefix(err, "Failed initializing fallback schema:")
+		}
+	}
+	schemaCache.store(key, schema)
+	return schema, nil
+}
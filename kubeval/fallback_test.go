@@ -0,0 +1,105 @@
+package kubeval
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// unreachableSchemaLocation refuses connections, like a schema location
+// during an outage
+const unreachableSchemaLocation = "http://127.0.0.1:1"
+
+func TestOfflineFallback(t *testing.T) {
+	input := []byte(`apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - port: http
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+spec:
+  ports:
+  - port: 80
+`)
+	config := NewDefaultConfig()
+	config.SchemaLocation = unreachableSchemaLocation
+	config.OfflineFallback = true
+	results, err := Validate(input, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !assert.Len(t, results, 2) {
+		return
+	}
+	for _, r := range results {
+		assert.True(t, r.ValidatedAgainstSchema, r.ResourceName)
+		assert.True(t, r.ValidatedAgainstFallback, r.ResourceName)
+	}
+	if assert.Len(t, results[0].Errors, 1) {
+		assert.Equal(t, "spec.ports.0.port: Invalid type. Expected: [integer,null], given: string", results[0].Errors[0].String())
+	}
+	assert.Empty(t, results[1].Errors)
+
+	config.OfflineFallback = false
+	_, err = Validate(input, config)
+	assert.Error(t, err)
+}
+
+func TestOfflineFallbackOnlyOnNetworkFailure(t *testing.T) {
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	config.OfflineFallback = true
+	// there is no local schema for a Pod, but the location was reachable
+	_, err := Validate([]byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: web\n"), config)
+	assert.Error(t, err)
+
+	// there is no fallback schema for custom resources
+	config.SchemaLocation = unreachableSchemaLocation
+	_, err = Validate([]byte("apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: web\n"), config)
+	assert.Error(t, err)
+}
+
+func TestOfflineFallbackOnUnavailableStatus(t *testing.T) {
+	input := []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n")
+	for _, code := range []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusTooManyRequests} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+		}))
+		config := NewDefaultConfig()
+		config.SchemaLocation = server.URL
+		config.OfflineFallback = true
+		results, err := Validate(input, config)
+		server.Close()
+		if assert.NoError(t, err, code) && assert.Len(t, results, 1) {
+			assert.True(t, results[0].ValidatedAgainstFallback, code)
+		}
+	}
+
+	// a location which responds that it does not have the schema is reachable
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	config := NewDefaultConfig()
+	config.SchemaLocation = server.URL
+	config.OfflineFallback = true
+	_, err := Validate(input, config)
+	assert.Error(t, err)
+}
+
+func TestFallbackSchemasLoad(t *testing.T) {
+	for versionKind := range fallbackSchemas() {
+		i := strings.LastIndex(versionKind, "/")
+		resource := &ValidationResult{APIVersion: versionKind[:i], Kind: versionKind[i+1:]}
+		schema, err := loadFallbackSchema(resource, schemaMap(NewSchemaCache()))
+		assert.NoError(t, err, versionKind)
+		assert.NotNil(t, schema, versionKind)
+	}
+}
@@ -0,0 +1,35 @@
+package kubeval
+
+import (
+	"net/url"
+)
+
+// useFetchSlots sets the places among the schemas fetched at once for the
+// run of config, which is a copy of the caller's, to those of schemaCache,
+// so that all the validations sharing the cache share the limit too. A run
+// which already has them keeps them.
+func useFetchSlots(config *Config, schemaCache schemaStore) {
+	if config.FetchConcurrency > 0 && config.fetchSlots == nil {
+		config.fetchSlots = schemaCache.fetchSlots(config.FetchConcurrency)
+	}
+}
+
+// fetchSlot waits until fewer than Config.FetchConcurrency schemas are being
+// fetched from remote schema locations during the run, and holds a place
+// among them until the returned function is called. The ref is the one
+// actually fetched, as schemas read from files are not limited.
+func fetchSlot(ref string, config *Config) func() {
+	slots := config.fetchSlots
+	if slots == nil || !isRemoteRef(ref) {
+		return func() {}
+	}
+
+	slots <- struct{}{}
+	return func() { <-slots }
+}
+
+// isRemoteRef returns whether the schema at ref is fetched over HTTP
+func isRemoteRef(ref string) bool {
+	u, err := url.Parse(ref)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
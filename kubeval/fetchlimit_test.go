@@ -0,0 +1,128 @@
+package kubeval
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fetchCountingServer serves an empty schema for every request, slowly,
+// recording the most requests it served at once
+type fetchCountingServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	inFlight int
+	most     int
+	served   int
+}
+
+func newFetchCountingServer() *fetchCountingServer {
+	s := &fetchCountingServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.inFlight++
+		s.served++
+		if s.inFlight > s.most {
+			s.most = s.inFlight
+		}
+		s.mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprint(w, `{"type": "object"}`)
+
+		s.mu.Lock()
+		s.inFlight--
+		s.mu.Unlock()
+	}))
+	return s
+}
+
+// widgetManifests returns one resource of each of count kinds, each with a
+// schema of its own to fetch
+func widgetManifests(count int) [][]byte {
+	var inputs [][]byte
+	for i := 0; i < count; i++ {
+		inputs = append(inputs, []byte(fmt.Sprintf("apiVersion: example.com/v1\nkind: Widget%d\nmetadata:\n  name: widget\n", i)))
+	}
+	return inputs
+}
+
+func TestFetchConcurrency(t *testing.T) {
+	var tests = []struct {
+		name     string
+		validate func(inputs [][]byte, config *Config)
+	}{
+		{
+			name: "prefetch",
+			validate: func(inputs [][]byte, config *Config) {
+				config.PrefetchWorkers = 8
+				PrefetchSchemas(inputs, NewSchemaCache(), config)
+			},
+		},
+		{
+			name: "concurrent validations",
+			validate: func(inputs [][]byte, config *Config) {
+				cache := NewSharedSchemaCache()
+				var wg sync.WaitGroup
+				for _, input := range inputs {
+					wg.Add(1)
+					go func(input []byte) {
+						defer wg.Done()
+						_, err := ValidateWithSharedCache(input, cache, config)
+						assert.NoError(t, err)
+					}(input)
+				}
+				wg.Wait()
+			},
+		},
+	}
+	for _, test := range tests {
+		server := newFetchCountingServer()
+		config := NewDefaultConfig()
+		config.SchemaLocation = server.URL
+		config.FetchConcurrency = 2
+
+		test.validate(widgetManifests(8), config)
+		server.Close()
+
+		assert.Equal(t, 8, server.served, test.name)
+		// The limit is reached, but not exceeded
+		assert.Equal(t, 2, server.most, "%s fetched %d schemas at once", test.name, server.most)
+	}
+}
+
+func TestFetchSlotsPerRun(t *testing.T) {
+	config := NewDefaultConfig()
+	config.FetchConcurrency = 2
+
+	// Validations sharing a cache share the limit, but no others do
+	cache := NewSharedSchemaCache()
+	first, second, other := *config, *config, *config
+	useFetchSlots(&first, cache)
+	useFetchSlots(&second, cache)
+	useFetchSlots(&other, NewSharedSchemaCache())
+	assert.Equal(t, first.fetchSlots, second.fetchSlots)
+	assert.NotEqual(t, first.fetchSlots, other.fetchSlots)
+	assert.Nil(t, config.fetchSlots)
+}
+
+func TestFetchConcurrencyNegative(t *testing.T) {
+	config := NewDefaultConfig()
+	config.FetchConcurrency = -1
+	_, err := Validate(widgetManifests(1)[0], config)
+	require.EqualError(t, err, "Fetch concurrency ('--fetch-concurrency' flag) must not be negative")
+}
+
+func TestIsRemoteRef(t *testing.T) {
+	assert.True(t, isRemoteRef("https://kubernetesjsonschema.dev/master-standalone/service-v1.json"))
+	assert.True(t, isRemoteRef("http://mirror.internal/service-v1.json"))
+	assert.False(t, isRemoteRef("file:///schemas/service-v1.json"))
+	assert.False(t, isRemoteRef("/schemas/service-v1.json"))
+}
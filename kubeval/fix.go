@@ -0,0 +1,220 @@
+package kubeval
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Fix is a change made by FixManifests
+type Fix struct {
+	// Line is the line of the input the change was made at, from 1
+	Line int
+	// Description describes the change in the imperative, such as Remove
+	// the status of Deployment 'web'
+	Description string
+}
+
+func (f Fix) String() string {
+	return fmt.Sprintf("line %d: %s", f.Line, f.Description)
+}
+
+var (
+	// topLevelKeyPattern matches a line holding a key of the top level
+	// mapping of a document, along with anything following it
+	topLevelKeyPattern = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9_-]*):(.*)$`)
+
+	// apiVersionValuePattern matches the value of an apiVersion, which may
+	// be quoted and followed by a comment
+	apiVersionValuePattern = regexp.MustCompile(`^(\s*["']?)([^"'\s#]+)(["']?.*)$`)
+)
+
+// FixManifests makes safe, mechanical fixes to the YAML documents in
+// input, returning the fixed manifests and the fixes made. Only these are
+// fixed, leaving the rest of each document, including its comments and
+// formatting, as it was:
+//
+//   - apiVersions with upper case letters, such as Apps/V1, are lower cased,
+//     as API groups and versions always are
+//
+//   - the status of resources of built-in kinds is removed, as the API
+//     server ignores it when they are applied
+//
+//   - namespaced resources without a namespace are given
+//     Config.DefaultNamespace, which they would be put in anyway
+//
+// JSON documents, Lists and documents which cannot be parsed are left as
+// they are.
+func FixManifests(input []byte, config *Config) ([]byte, []Fix) {
+	lines := strings.Split(string(input), "\n")
+	var output []string
+	var fixes []Fix
+
+	start := 0
+	for i := 0; i <= len(lines); i++ {
+		if i < len(lines) && !isDocumentBoundary(lines[i], config.DocumentSeparators) {
+			continue
+		}
+		fixed, documentFixes := fixDocument(lines[start:i], start, config)
+		output = append(output, fixed...)
+		fixes = append(fixes, documentFixes...)
+		if i < len(lines) {
+			output = append(output, lines[i])
+		}
+		start = i + 1
+	}
+	return []byte(strings.Join(output, "\n")), fixes
+}
+
+// isDocumentBoundary returns whether line separates or ends a document, in
+// the same way as splitDocuments
+func isDocumentBoundary(line string, separators []string) bool {
+	trimmed := strings.TrimRight(line, " \t\r")
+	return documentStartPattern.MatchString(trimmed) || documentEndPattern.MatchString(trimmed) || in(separators, trimmed)
+}
+
+// fixDocument fixes the lines of a single document, which start at line
+// offset of the input
+func fixDocument(lines []string, offset int, config *Config) ([]string, []Fix) {
+	document := []byte(strings.Join(lines, "\n"))
+	if trimmed := bytes.TrimSpace(document); len(trimmed) == 0 || trimmed[0] == '{' || trimmed[0] == '[' {
+		return lines, nil
+	}
+	var body map[string]interface{}
+	if err := unmarshalDocument(document, &body); err != nil || body == nil {
+		return lines, nil
+	}
+	kind, _ := body["kind"].(string)
+	apiVersion, _ := body["apiVersion"].(string)
+	if kind == "" || kind == "List" {
+		return lines, nil
+	}
+	name, _ := lookupPath(body, []string{"metadata", "name"}).(string)
+	resource := fmt.Sprintf("%s '%s'", kind, name)
+
+	var fixes []Fix
+	replaced := make(map[int]string)
+	inserted := make(map[int]string)
+	removed := make(map[int]bool)
+
+	for i, line := range lines {
+		found := topLevelKeyPattern.FindStringSubmatch(line)
+		if found == nil {
+			continue
+		}
+		switch found[1] {
+		case "apiVersion":
+			value := apiVersionValuePattern.FindStringSubmatch(found[2])
+			if value == nil || strings.ToLower(value[2]) == value[2] {
+				continue
+			}
+			replaced[i] = "apiVersion:" + value[1] + strings.ToLower(value[2]) + value[3]
+			fixes = append(fixes, Fix{Line: offset + i + 1, Description: fmt.Sprintf("Lower case the apiVersion %s of %s to %s", value[2], resource, strings.ToLower(value[2]))})
+		case "status":
+			if !isBuiltInAPIVersion(apiVersion) {
+				continue
+			}
+			end := blockEnd(lines, i)
+			for j := i; j <= end; j++ {
+				removed[j] = true
+			}
+			fixes = append(fixes, Fix{Line: offset + i + 1, Description: fmt.Sprintf("Remove the status of %s, which the API server ignores", resource)})
+		case "metadata":
+			_, hasNamespace := lookupPath(body, []string{"metadata", "namespace"}).(string)
+			if hasNamespace || isClusterScoped(kind, config) || strings.TrimSpace(stripComment(found[2])) != "" {
+				continue
+			}
+			after, indent, ok := namespacePosition(lines, i)
+			if !ok {
+				continue
+			}
+			inserted[after] = indent + "namespace: " + config.DefaultNamespace
+			fixes = append(fixes, Fix{Line: offset + after + 1, Description: fmt.Sprintf("Add the namespace %s to %s, which it would be created in", config.DefaultNamespace, resource)})
+		}
+	}
+
+	if len(fixes) == 0 {
+		return lines, nil
+	}
+	var fixed []string
+	for i, line := range lines {
+		if replacement, ok := replaced[i]; ok {
+			line = replacement
+		}
+		if !removed[i] {
+			fixed = append(fixed, line)
+		}
+		if addition, ok := inserted[i]; ok {
+			fixed = append(fixed, addition)
+		}
+	}
+	return fixed, fixes
+}
+
+// isBuiltInAPIVersion returns whether apiVersion belongs to one of the
+// API groups built into Kubernetes, which are the core group and groups
+// without a domain or under k8s.io
+func isBuiltInAPIVersion(apiVersion string) bool {
+	parts := strings.Split(apiVersion, "/")
+	if len(parts) == 1 {
+		return true
+	}
+	group := strings.ToLower(parts[0])
+	return !strings.Contains(group, ".") || strings.HasSuffix(group, ".k8s.io")
+}
+
+// blockEnd returns the last line of the value of the top level key at
+// line start: the last line before the next top level key or comment
+// which is indented or an item of a sequence
+func blockEnd(lines []string, start int) int {
+	end := start
+	for i := start + 1; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' && line[0] != '-' {
+			break
+		}
+		end = i
+	}
+	return end
+}
+
+// namespacePosition returns the line of the block holding the metadata at
+// line start after which to add a namespace, which is after its name if it
+// has one, and the indentation of its keys
+func namespacePosition(lines []string, start int) (int, string, bool) {
+	after := start
+	indent := ""
+	for i := start + 1; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		lineIndent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+		if lineIndent == "" {
+			break
+		}
+		if indent == "" {
+			indent = lineIndent
+		}
+		if lineIndent == indent && strings.HasPrefix(trimmed, "name:") && strings.TrimSpace(stripComment(strings.TrimPrefix(trimmed, "name:"))) != "" {
+			after = i
+		}
+	}
+	return after, indent, indent != ""
+}
+
+// stripComment removes a trailing comment from the value of a key
+func stripComment(value string) string {
+	if i := strings.Index(value, " #"); i >= 0 {
+		return value[:i]
+	}
+	if strings.HasPrefix(strings.TrimSpace(value), "#") {
+		return ""
+	}
+	return value
+}
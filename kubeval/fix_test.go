@@ -0,0 +1,73 @@
+package kubeval
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixManifests(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/fixable.yaml")
+	fileContents, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	config := NewDefaultConfig()
+
+	fixed, fixes := FixManifests(fileContents, config)
+	assert.Equal(t, []Fix{
+		{Line: 2, Description: "Lower case the apiVersion Apps/V1 of Deployment 'web' to apps/v1"},
+		{Line: 5, Description: "Add the namespace default to Deployment 'web', which it would be created in"},
+		{Line: 21, Description: "Remove the status of Deployment 'web', which the API server ignores"},
+	}, fixes)
+
+	// Comments and the documents which need no fixes are kept as they were
+	assert.Contains(t, string(fixed), "# A Deployment with mistakes --fix can correct\napiVersion: apps/v1\n")
+	assert.Contains(t, string(fixed), "  name: web # the frontend\n  namespace: default\n  labels:\n")
+	assert.Contains(t, string(fixed), "          image: nginx:1.17\n---\napiVersion: example.com/v1\n")
+	// Custom resources may rely on their status, so it is left alone
+	assert.Contains(t, string(fixed), "status:\n  ready: true\n")
+
+	config.SchemaLocation = localSchemaLocation()
+	config.IgnoreMissingSchemas = true
+	results, err := Validate(fixed, config)
+	require.NoError(t, err)
+	for _, r := range results {
+		assert.Empty(t, r.Errors)
+	}
+
+	refixed, fixes := FixManifests(fixed, config)
+	assert.Empty(t, fixes)
+	assert.Equal(t, string(fixed), string(refixed))
+}
+
+func TestFixManifestsLeavesDocumentsAlone(t *testing.T) {
+	config := NewDefaultConfig()
+	config.DefaultNamespace = "team-a"
+	var tests = []struct {
+		Name  string
+		Input string
+	}{
+		{"empty", ""},
+		{"json", `{"apiVersion": "V1", "kind": "Service", "metadata": {"name": "web"}}`},
+		{"flow metadata", "apiVersion: v1\nkind: Service\nmetadata: {name: web, namespace: team-a}\n"},
+		{"cluster scoped", "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: team-a\n"},
+		{"list", "apiVersion: V1\nkind: List\nitems: []\n"},
+		{"unparseable", "apiVersion: V1\nkind: [Service\n"},
+	}
+	for _, test := range tests {
+		fixed, fixes := FixManifests([]byte(test.Input), config)
+		assert.Empty(t, fixes, test.Name)
+		assert.Equal(t, test.Input, string(fixed), test.Name)
+	}
+}
+
+func TestFixManifestsQuotedAPIVersion(t *testing.T) {
+	config := NewDefaultConfig()
+	config.DefaultNamespace = "team-a"
+	input := "---\napiVersion: \"V1\" # core\nkind: ConfigMap\nmetadata:\n    labels:\n        app: web\n"
+	fixed, fixes := FixManifests([]byte(input), config)
+	assert.Len(t, fixes, 2)
+	assert.Equal(t, "---\napiVersion: \"v1\" # core\nkind: ConfigMap\nmetadata:\n    namespace: team-a\n    labels:\n        app: web\n", string(fixed))
+}
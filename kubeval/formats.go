@@ -0,0 +1,251 @@
+package kubeval
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// The format checkers below enforce the formats used by the Kubernetes
+// schemas which gojsonschema does not know about. Formats are only checked
+// for values of the right type, as the type itself is checked separately,
+// so a checker is never the cause of a false positive for a value the
+// schema otherwise allows.
+
+// Int32Format checks that numbers are whole and fit in 32 bits
+type Int32Format struct{}
+
+// IsFormat meets the gojsonschema.FormatChecker interface
+func (f Int32Format) IsFormat(input interface{}) bool {
+	value, ok := toFloat64(input)
+	if !ok {
+		return true
+	}
+	return value == math.Trunc(value) && value >= math.MinInt32 && value <= math.MaxInt32
+}
+
+// Int64Format checks that numbers are whole
+type Int64Format struct{}
+
+// IsFormat meets the gojsonschema.FormatChecker interface
+func (f Int64Format) IsFormat(input interface{}) bool {
+	value, ok := toFloat64(input)
+	if !ok {
+		return true
+	}
+	return value == math.Trunc(value) && value >= math.MinInt64 && value <= math.MaxInt64
+}
+
+// ByteFormat checks that strings are valid base64, as used for binary
+// data such as the values of a Secret
+type ByteFormat struct{}
+
+// IsFormat meets the gojsonschema.FormatChecker interface
+func (f ByteFormat) IsFormat(input interface{}) bool {
+	value, ok := input.(string)
+	if !ok {
+		return true
+	}
+	_, err := base64.StdEncoding.DecodeString(value)
+	return err == nil
+}
+
+// IntOrStringFormat checks that numbers are whole, as the integer half of
+// an int-or-string, such as a port which may be given by number or name
+type IntOrStringFormat struct{}
+
+// IsFormat meets the gojsonschema.FormatChecker interface
+func (f IntOrStringFormat) IsFormat(input interface{}) bool {
+	if _, ok := input.(string); ok {
+		return true
+	}
+	return Int64Format{}.IsFormat(input)
+}
+
+// kubernetesFormatCheckers returns the format checkers for the formats used
+// by the Kubernetes schemas, which are checked when Config.CheckFormats is
+// set. The date-time format is checked by gojsonschema itself.
+func kubernetesFormatCheckers() map[string]gojsonschema.FormatChecker {
+	return map[string]gojsonschema.FormatChecker{
+		"int32":         Int32Format{},
+		"int64":         Int64Format{},
+		"byte":          ByteFormat{},
+		"int-or-string": IntOrStringFormat{},
+		// floating point numbers need no further checks
+		"double": ValidFormat{},
+		"float":  ValidFormat{},
+	}
+}
+
+// formatCheckers returns the format checkers to validate with, keyed by
+// format name: those for the Kubernetes formats if Config.CheckFormats is
+// set, and any in Config.FormatCheckers which can be registered, see
+// checkFormatCheckers
+func formatCheckers(config *Config) map[string]gojsonschema.FormatChecker {
+	checkers := make(map[string]gojsonschema.FormatChecker)
+	if config.CheckFormats {
+		checkers = kubernetesFormatCheckers()
+	}
+	for name, checker := range config.FormatCheckers {
+		if isComparable(checker) {
+			checkers[name] = checker
+		}
+	}
+	return checkers
+}
+
+// isComparable returns whether checker can be told apart from others, and
+// so registered once, see useFormatCheckers
+func isComparable(checker gojsonschema.FormatChecker) bool {
+	return checker != nil && reflect.TypeOf(checker).Comparable()
+}
+
+// checkFormatCheckers returns an error if any of Config.FormatCheckers
+// cannot be registered, see useFormatCheckers
+func checkFormatCheckers(config *Config) error {
+	for name, checker := range config.FormatCheckers {
+		if !isComparable(checker) {
+			return fmt.Errorf("The format checker for %s in Config.FormatCheckers must be comparable, such as a struct or a pointer", name)
+		}
+	}
+	return nil
+}
+
+// formatCheckerKey identifies a checker registered for a format
+type formatCheckerKey struct {
+	format  string
+	checker gojsonschema.FormatChecker
+}
+
+var (
+	// formatCheckersLock guards gojsonschema.FormatCheckers, which is
+	// global to the process and consulted both when a schema is loaded and
+	// during validation. Runs hold it for reading, while a checker not
+	// seen before is registered holding it exclusively.
+	formatCheckersLock sync.RWMutex
+
+	// registeredFormats are the names each checker is registered with
+	// gojsonschema under, and the format each of those names stands for.
+	// Rather than a checker replacing another of the same format, which
+	// would apply it to every run, each is registered once under a name of
+	// its own, which the schemas of the runs using it are rewritten to
+	// use, see renameFormats. It is guarded by formatCheckersLock.
+	registeredFormats = struct {
+		names   map[formatCheckerKey]string
+		formats map[string]string
+	}{names: make(map[formatCheckerKey]string), formats: make(map[string]string)}
+)
+
+// useFormatCheckers registers the format checkers for config with
+// gojsonschema, if they have not been already, and holds them until the
+// returned function is called. Formats are looked up both when a schema is
+// loaded and during validation, so the schemas of a run are both compiled
+// and used within it.
+func useFormatCheckers(config *Config) func() {
+	checkers := formatCheckers(config)
+	formatCheckersLock.RLock()
+	registered := len(formatNames(checkers)) == len(checkers)
+	formatCheckersLock.RUnlock()
+
+	if !registered {
+		formatCheckersLock.Lock()
+		for format, checker := range checkers {
+			key := formatCheckerKey{format: format, checker: checker}
+			if _, found := registeredFormats.names[key]; found {
+				continue
+			}
+			name := fmt.Sprintf("kubeval-%d:%s", len(registeredFormats.names), format)
+			gojsonschema.FormatCheckers.Add(name, checker)
+			registeredFormats.names[key] = name
+			registeredFormats.formats[name] = format
+		}
+		formatCheckersLock.Unlock()
+	}
+
+	formatCheckersLock.RLock()
+	return formatCheckersLock.RUnlock
+}
+
+// formatNames returns the names checkers are registered under, keyed by
+// format, leaving out any not yet registered. It must be called holding
+// formatCheckersLock, as it is during a run, see useFormatCheckers.
+func formatNames(checkers map[string]gojsonschema.FormatChecker) map[string]string {
+	names := make(map[string]string)
+	for format, checker := range checkers {
+		if name, found := registeredFormats.names[formatCheckerKey{format: format, checker: checker}]; found {
+			names[format] = name
+		}
+	}
+	return names
+}
+
+// renameFormats points the formats used in document to the names their
+// checkers are registered under, see formatNames
+func renameFormats(document interface{}, names map[string]string) {
+	if len(names) == 0 {
+		return
+	}
+	switch typed := document.(type) {
+	case map[string]interface{}:
+		if format, ok := typed["format"].(string); ok {
+			if name, found := names[format]; found {
+				typed["format"] = name
+			}
+		}
+		for _, child := range typed {
+			renameFormats(child, names)
+		}
+	case []interface{}:
+		for _, child := range typed {
+			renameFormats(child, names)
+		}
+	}
+}
+
+// restoreFormatNames reports the failures of the formats renamed by
+// renameFormats under the name used in the schema. Like formatNames, it
+// must be called holding formatCheckersLock.
+func restoreFormatNames(errors []gojsonschema.ResultError) {
+	for _, e := range errors {
+		if e.Type() != "format" {
+			continue
+		}
+		name, _ := e.Details()["format"].(string)
+		if format, found := registeredFormats.formats[name]; found {
+			e.Details()["format"] = format
+			e.SetDescription(strings.Replace(e.Description(), "'"+name+"'", "'"+format+"'", 1))
+		}
+	}
+}
+
+// toFloat64 returns the value of a number, however it was decoded
+func toFloat64(input interface{}) (float64, bool) {
+	switch value := input.(type) {
+	case float64:
+		return value, true
+	case float32:
+		return float64(value), true
+	case int:
+		return float64(value), true
+	case int32:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case *big.Float:
+		f, _ := value.Float64()
+		return f, true
+	case json.Number:
+		f, err := strconv.ParseFloat(string(value), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
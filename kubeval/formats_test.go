@@ -0,0 +1,157 @@
+package kubeval
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+func TestIntOrStringFormat(t *testing.T) {
+	var tests = []struct {
+		input    interface{}
+		expected bool
+	}{
+		{"http", true},
+		{"50%", true},
+		{float64(8080), true},
+		{json.Number("8080"), true},
+		{float64(80.5), false},
+		{json.Number("80.5"), false},
+		{true, true},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, IntOrStringFormat{}.IsFormat(test.input), "%v", test.input)
+	}
+}
+
+func TestByteFormat(t *testing.T) {
+	var tests = []struct {
+		input    interface{}
+		expected bool
+	}{
+		{"c2VjcmV0", true},
+		{"", true},
+		{"not base64!", false},
+		{"c2VjcmV0=", false},
+		{float64(1), true},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, ByteFormat{}.IsFormat(test.input), "%v", test.input)
+	}
+}
+
+func TestIntegerFormats(t *testing.T) {
+	assert.True(t, Int32Format{}.IsFormat(float64(2147483647)))
+	assert.False(t, Int32Format{}.IsFormat(float64(2147483648)))
+	assert.False(t, Int32Format{}.IsFormat(float64(1.5)))
+	assert.True(t, Int32Format{}.IsFormat("not a number"))
+	assert.True(t, Int64Format{}.IsFormat(float64(2147483648)))
+	assert.False(t, Int64Format{}.IsFormat(json.Number("1.5")))
+	assert.False(t, Int32Format{}.IsFormat(big.NewFloat(3000000000)))
+}
+
+func TestFormatsEnforced(t *testing.T) {
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	input := []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\nspec:\n  replicas: 3000000000\n  selector: {}\n  template: {}\n")
+
+	// Formats are only checked when enabled
+	results, err := Validate(input, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assert.Empty(t, results[0].Errors)
+
+	config.CheckFormats = true
+	results, err = Validate(input, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if assert.Len(t, results[0].Errors, 1) {
+		assert.Equal(t, "spec.replicas: Does not match format 'int32'", results[0].Errors[0].String())
+	}
+
+	results, err = Validate([]byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\nspec:\n  ports:\n  - port: 80\n    targetPort: http\n"), config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assert.Empty(t, results[0].Errors)
+}
+
+type upperCaseFormat struct{}
+
+func (f upperCaseFormat) IsFormat(input interface{}) bool {
+	value, ok := input.(string)
+	return !ok || value == "UPPER"
+}
+
+func TestCustomFormatCheckers(t *testing.T) {
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	config.FormatCheckers = map[string]gojsonschema.FormatChecker{"byte": upperCaseFormat{}}
+	input := []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: web\ndata:\n  key: c2VjcmV0\n")
+
+	results, err := ValidateWithCache(input, NewSchemaCache(), config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assert.Len(t, results[0].Errors, 1)
+
+	// The custom checker only applies while validating with its Config
+	config.FormatCheckers = nil
+	results, err = ValidateWithCache(input, NewSchemaCache(), config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assert.Empty(t, results[0].Errors)
+}
+
+type funcFormat func(input interface{}) bool
+
+func (f funcFormat) IsFormat(input interface{}) bool {
+	return f(input)
+}
+
+func TestCustomFormatCheckersMustBeComparable(t *testing.T) {
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	config.FormatCheckers = map[string]gojsonschema.FormatChecker{"upper": funcFormat(upperCaseFormat{}.IsFormat)}
+	_, err := Validate([]byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: web\n"), config)
+	assert.EqualError(t, err, "The format checker for upper in Config.FormatCheckers must be comparable, such as a struct or a pointer")
+}
+
+func TestCustomFormatCheckersWithSharedCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeval-formats-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "master-standalone"), 0755))
+	schema := `{"properties": {"data": {"additionalProperties": {"type": "string", "format": "upper"}}}}`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "master-standalone", "configmap-v1.json"), []byte(schema), 0644))
+
+	withChecker := NewDefaultConfig()
+	withChecker.SchemaLocation = "file://" + dir
+	withChecker.FormatCheckers = map[string]gojsonschema.FormatChecker{"upper": upperCaseFormat{}}
+	withoutChecker := NewDefaultConfig()
+	withoutChecker.SchemaLocation = withChecker.SchemaLocation
+	input := []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: web\ndata:\n  key: lower\n")
+
+	// Runs sharing a cache each validate with their own format checkers,
+	// whichever compiled the schema first
+	for _, configs := range [][]*Config{{withChecker, withoutChecker}, {withoutChecker, withChecker}} {
+		cache := NewSchemaCache()
+		for _, config := range configs {
+			results, err := ValidateWithCache(input, cache, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			assert.Len(t, results[0].Errors, len(config.FormatCheckers))
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package kubeval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// immutableFields are the fields of built-in kinds which cannot be changed
+// once an object is created, so that applying a change to them fails
+var immutableFields = map[string][]string{
+	"ClusterRoleBinding":    {"roleRef"},
+	"DaemonSet":             {"spec.selector"},
+	"Deployment":            {"spec.selector"},
+	"Job":                   {"spec.selector", "spec.template", "spec.completionMode"},
+	"PersistentVolumeClaim": {"spec.storageClassName", "spec.accessModes", "spec.volumeMode", "spec.volumeName", "spec.selector", "spec.dataSource"},
+	"ReplicaSet":            {"spec.selector"},
+	"RoleBinding":           {"roleRef"},
+	"Secret":                {"type"},
+	"Service":               {"spec.clusterIP"},
+	"StatefulSet":           {"spec.selector", "spec.serviceName", "spec.volumeClaimTemplates", "spec.podManagementPolicy"},
+	"StorageClass":          {"provisioner", "parameters", "reclaimPolicy", "volumeBindingMode"},
+}
+
+// defaultedImmutableFields are the immutable fields of immutableFields
+// which hold whole objects, to which the API server adds defaults, such as
+// the imagePullPolicy of each container of a pod template. Only what the
+// later version sets is compared for these, so that comparing with the
+// output of kubectl get does not report the defaults as changes.
+var defaultedImmutableFields = map[string]bool{
+	"spec.template":             true,
+	"spec.volumeClaimTemplates": true,
+}
+
+// immutableDataFields are the fields of kinds which become immutable once
+// an object sets immutable: true
+var immutableDataFields = map[string][]string{
+	"ConfigMap": {"immutable", "data", "binaryData"},
+	"Secret":    {"immutable", "data", "stringData"},
+}
+
+// objectVersion is a version of an object, and where it was found
+type objectVersion struct {
+	object map[string]interface{}
+	source string
+}
+
+// objectKey identifies an object across versions, whatever the version of
+// its API group
+func objectKey(kind string, apiVersion string, namespace string, name string, config *Config) string {
+	if isClusterScoped(kind, config) {
+		namespace = ""
+	} else {
+		namespace = resolveNamespace(namespace, config)
+	}
+	return strings.Join([]string{apiGroup(apiVersion), kind, namespace, name}, "/")
+}
+
+// checkImmutableFields compares each object in results with its previous
+// version, which is an earlier one of the same object in results, or else
+// the one in Config.ImmutableBaseline, and reports changes to the fields
+// which cannot be changed once an object is created, as applying them fails.
+// Only fields set in both versions are compared, as an unset field is left
+// as it is.
+func checkImmutableFields(results []ValidationResult, config *Config) error {
+	var errors *multierror.Error
+
+	previous := make(map[string]objectVersion)
+	if config.ImmutableBaseline != "" {
+		baseline, err := readImmutableBaseline(config)
+		if err != nil {
+			return err
+		}
+		previous = baseline
+	}
+
+	for _, r := range results {
+		if r.Object == nil || !isBuiltInAPIVersion(r.APIVersion) || r.ResourceName == "" {
+			continue
+		}
+		key := objectKey(r.Kind, r.APIVersion, r.ResourceNamespace, r.ResourceName, config)
+		if before, found := previous[key]; found {
+			for _, field := range changedImmutableFields(r.Kind, before.object, r.Object) {
+				path := strings.Split(field, ".")
+				errors = multierror.Append(errors, fmt.Errorf("%s: %s '%s' changes the immutable field %s from %s, in %s, to %s, which the API server rejects; delete and recreate it to change the field", r.FileName, r.Kind, r.QualifiedName(), field, formatFieldValue(lookupPath(before.object, path)), before.source, formatFieldValue(lookupPath(r.Object, path))))
+			}
+		}
+		previous[key] = objectVersion{object: r.Object, source: r.FileName}
+	}
+	return errors.ErrorOrNil()
+}
+
+// changedImmutableFields returns the immutable fields of an object of kind
+// which differ between its versions before and after
+func changedImmutableFields(kind string, before map[string]interface{}, after map[string]interface{}) []string {
+	fields := immutableFields[kind]
+	if before["immutable"] == true {
+		fields = append(append([]string{}, fields...), immutableDataFields[kind]...)
+	}
+
+	var changed []string
+	for _, field := range fields {
+		path := strings.Split(field, ".")
+		old, new := lookupPath(before, path), lookupPath(after, path)
+		if old == nil || new == nil {
+			continue
+		}
+		same := reflect.DeepEqual(old, new)
+		if defaultedImmutableFields[field] {
+			same = isSubsetOf(new, old)
+		}
+		if !same {
+			changed = append(changed, field)
+		}
+	}
+	return changed
+}
+
+// isSubsetOf returns whether every field set in subset is set to the same
+// value in value, and lists have the same length, with each item a subset of
+// the one in value
+func isSubsetOf(subset interface{}, value interface{}) bool {
+	switch subset := subset.(type) {
+	case map[string]interface{}:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, field := range subset {
+			if !isSubsetOf(field, object[key]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		items, ok := value.([]interface{})
+		if !ok || len(items) != len(subset) {
+			return false
+		}
+		for i, item := range subset {
+			if !isSubsetOf(item, items[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(subset, value)
+}
+
+// formatFieldValue formats the value of a field for a message, as JSON
+func formatFieldValue(value interface{}) string {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(b)
+}
+
+// readImmutableBaseline reads the objects in the file or directory of
+// Config.ImmutableBaseline, such as the manifests of the previous release
+// or the output of kubectl get -o yaml, keyed by objectKey
+func readImmutableBaseline(config *Config) (map[string]objectVersion, error) {
+	info, err := os.Stat(config.ImmutableBaseline)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the baseline of immutable fields ('--immutable-baseline' flag): %s", err)
+	}
+	discoverer := NewFilesystemDiscoverer([]string{config.ImmutableBaseline}, nil, nil)
+	if info.IsDir() {
+		discoverer = NewFilesystemDiscoverer(nil, []string{config.ImmutableBaseline}, nil)
+	}
+	files, err := discoverer.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	baseline := make(map[string]objectVersion)
+	add := func(object map[string]interface{}, source string) {
+		kind, _ := object["kind"].(string)
+		apiVersion, _ := object["apiVersion"].(string)
+		name, _ := lookupPath(object, []string{"metadata", "name"}).(string)
+		namespace, _ := lookupPath(object, []string{"metadata", "namespace"}).(string)
+		if kind != "" && name != "" {
+			baseline[objectKey(kind, apiVersion, namespace, name, config)] = objectVersion{object: object, source: source}
+		}
+	}
+	for _, file := range files {
+		contents, err := file.Read()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read the baseline of immutable fields %s: %s", file.Name, err)
+		}
+		documents, _ := splitInput(contents, config)
+		for _, document := range documents {
+			var object map[string]interface{}
+			if err := unmarshalDocument(document, &object); err != nil || object == nil {
+				continue
+			}
+			// kubectl get returns a List of the objects it finds
+			if items, isList := object["items"].([]interface{}); isList && strings.HasSuffix(fmt.Sprint(object["kind"]), "List") {
+				for _, item := range items {
+					if itemObject, ok := item.(map[string]interface{}); ok {
+						add(itemObject, file.Name)
+					}
+				}
+				continue
+			}
+			add(object, file.Name)
+		}
+	}
+	return baseline, nil
+}
@@ -0,0 +1,117 @@
+package kubeval
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validateImmutableFixtures validates each of the fixtures of the immutable
+// fields check in turn, as a run over several files does
+func validateImmutableFixtures(t *testing.T, config *Config, names ...string) []ValidationResult {
+	var results []ValidationResult
+	for _, name := range names {
+		fileContents, err := ioutil.ReadFile(filepath.Join("../fixtures/immutable", name))
+		require.NoError(t, err)
+		fileConfig := *config
+		fileConfig.FileName = name
+		fileResults, err := Validate(fileContents, &fileConfig)
+		require.NoError(t, err)
+		results = append(results, fileResults...)
+	}
+	return results
+}
+
+func TestCheckImmutableFields(t *testing.T) {
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	config.CheckImmutableFields = true
+
+	results := validateImmutableFixtures(t, config, "before.yaml", "after.yaml")
+	assert.NoError(t, CheckResourceSet(results, config))
+	assert.EqualError(t, CheckResourceSetWarnings(results, config),
+		`after.yaml: Deployment 'web' changes the immutable field spec.selector from {"matchLabels":{"app":"web"}}, in before.yaml, to {"matchLabels":{"app":"web","track":"stable"}}, which the API server rejects; delete and recreate it to change the field`+"\n"+
+			`after.yaml: Service 'web' changes the immutable field spec.clusterIP from "10.96.0.10", in before.yaml, to "None", which the API server rejects; delete and recreate it to change the field`+"\n"+
+			`after.yaml: ConfigMap 'settings' changes the immutable field data from {"log-level":"info"}, in before.yaml, to {"log-level":"debug"}, which the API server rejects; delete and recreate it to change the field`)
+
+	config.CheckImmutableFields = false
+	assert.NoError(t, CheckResourceSetWarnings(results, config))
+}
+
+func TestCheckImmutableFieldsBaseline(t *testing.T) {
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	config.CheckImmutableFields = true
+	config.ImmutableBaseline = "../fixtures/immutable_baseline.yaml"
+
+	results := validateImmutableFixtures(t, config, "after.yaml")
+	assert.EqualError(t, CheckResourceSetWarnings(results, config),
+		`after.yaml: Deployment 'web' changes the immutable field spec.selector from {"matchLabels":{"app":"web"}}, in ../fixtures/immutable_baseline.yaml, to {"matchLabels":{"app":"web","track":"stable"}}, which the API server rejects; delete and recreate it to change the field`+"\n"+
+			`after.yaml: Service 'web' changes the immutable field spec.clusterIP from "10.96.0.10", in ../fixtures/immutable_baseline.yaml, to "None", which the API server rejects; delete and recreate it to change the field`)
+
+	config.ImmutableBaseline = "../fixtures/missing_baseline.yaml"
+	assert.Contains(t, CheckResourceSetWarnings(results, config).Error(), "Failed to read the baseline of immutable fields ('--immutable-baseline' flag)")
+}
+
+func TestChangedImmutableFields(t *testing.T) {
+	var tests = []struct {
+		kind     string
+		before   map[string]interface{}
+		after    map[string]interface{}
+		expected []string
+	}{
+		// Fields left unset are not changed by applying
+		{
+			kind:   "Service",
+			before: map[string]interface{}{"spec": map[string]interface{}{"clusterIP": "10.96.0.10"}},
+			after:  map[string]interface{}{"spec": map[string]interface{}{}},
+		},
+		{
+			kind:     "RoleBinding",
+			before:   map[string]interface{}{"roleRef": map[string]interface{}{"kind": "Role", "name": "view"}},
+			after:    map[string]interface{}{"roleRef": map[string]interface{}{"kind": "Role", "name": "edit"}},
+			expected: []string{"roleRef"},
+		},
+		// The data of a ConfigMap is only immutable once it says so
+		{
+			kind:   "ConfigMap",
+			before: map[string]interface{}{"data": map[string]interface{}{"a": "1"}},
+			after:  map[string]interface{}{"immutable": true, "data": map[string]interface{}{"a": "2"}},
+		},
+		{
+			kind:     "Secret",
+			before:   map[string]interface{}{"immutable": true, "type": "Opaque", "data": map[string]interface{}{"a": "MQ=="}},
+			after:    map[string]interface{}{"immutable": false, "type": "kubernetes.io/tls", "data": map[string]interface{}{"a": "MQ=="}},
+			expected: []string{"type", "immutable"},
+		},
+		// Defaults the API server adds to a pod template are not changes
+		{
+			kind:   "Job",
+			before: map[string]interface{}{"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{"restartPolicy": "Never", "containers": []interface{}{map[string]interface{}{"name": "run", "image": "busybox", "imagePullPolicy": "Always"}}}}}},
+			after:  map[string]interface{}{"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{map[string]interface{}{"name": "run", "image": "busybox"}}}}}},
+		},
+		{
+			kind:     "Job",
+			before:   map[string]interface{}{"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{map[string]interface{}{"name": "run", "image": "busybox", "imagePullPolicy": "Always"}}}}}},
+			after:    map[string]interface{}{"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{map[string]interface{}{"name": "run", "image": "busybox:1.31"}}}}}},
+			expected: []string{"spec.template"},
+		},
+		{
+			kind:     "StatefulSet",
+			before:   map[string]interface{}{"spec": map[string]interface{}{"volumeClaimTemplates": []interface{}{map[string]interface{}{"metadata": map[string]interface{}{"name": "data"}}}}},
+			after:    map[string]interface{}{"spec": map[string]interface{}{"volumeClaimTemplates": []interface{}{map[string]interface{}{"metadata": map[string]interface{}{"name": "data"}}, map[string]interface{}{"metadata": map[string]interface{}{"name": "logs"}}}}},
+			expected: []string{"spec.volumeClaimTemplates"},
+		},
+		{
+			kind:   "Widget",
+			before: map[string]interface{}{"spec": map[string]interface{}{"selector": "a"}},
+			after:  map[string]interface{}{"spec": map[string]interface{}{"selector": "b"}},
+		},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, changedImmutableFields(test.kind, test.before, test.after), test.kind)
+	}
+}
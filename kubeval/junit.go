@@ -0,0 +1,140 @@
+package kubeval
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// junitTestSuites is the document written by the junit output, which CI
+// systems such as Jenkins and GitLab read as test reports
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite holds the documents of a single file
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is a single document. Invalid documents fail, documents
+// whose schema is malformed are errors, and documents which were not
+// validated against a schema are skipped.
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message  string `xml:"message,attr"`
+	Contents string `xml:",chardata"`
+}
+
+// junitSkippedMessages explain why the documents of each status are skipped
+var junitSkippedMessages = map[status]string{
+	statusSkipped:     "Skipped",
+	statusEmpty:       "Empty document",
+	statusUnvalidated: "Not validated against a schema",
+}
+
+// junitOutputManager reports results as a JUnit XML report on stdout
+type junitOutputManager struct {
+	logger *log.Logger
+
+	data []EvalResult
+}
+
+func newDefaultJUnitOutputManager() *junitOutputManager {
+	return newJUnitOutputManager(log.New(os.Stdout, "", 0))
+}
+
+func newJUnitOutputManager(l *log.Logger) *junitOutputManager {
+	return &junitOutputManager{
+		logger: l,
+	}
+}
+
+func (j *junitOutputManager) Put(r ValidationResult) error {
+	j.data = append(j.data, newEvalResult(r))
+	return nil
+}
+
+func (j *junitOutputManager) Flush() error {
+	report := junitTestSuites{Name: "kubeval"}
+	suites := make(map[string]int)
+	for _, r := range j.data {
+		i, found := suites[r.Filename]
+		if !found {
+			i = len(report.Suites)
+			suites[r.Filename] = i
+			report.Suites = append(report.Suites, junitTestSuite{Name: r.Filename})
+		}
+		suite := &report.Suites[i]
+
+		testCase := junitTestCase{Name: junitTestCaseName(r), ClassName: r.Filename}
+		switch r.Status {
+		case statusValid:
+		case statusInvalid:
+			testCase.Failure = &junitMessage{
+				Message:  fmt.Sprintf("%s is invalid", testCase.Name),
+				Contents: strings.Join(r.Errors, "\n"),
+			}
+			suite.Failures++
+		case statusSchemaError:
+			testCase.Error = &junitMessage{Message: "The schema could not be compiled", Contents: r.SchemaError}
+			suite.Errors++
+		default:
+			testCase.Skipped = &junitMessage{Message: junitSkippedMessages[status(r.Status)]}
+			suite.Skipped++
+		}
+		testCase.SystemOut = strings.Join(r.Warnings, "\n")
+
+		suite.Tests++
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	for _, suite := range report.Suites {
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+	}
+
+	b, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	j.logger.Print(xml.Header + string(b))
+	return nil
+}
+
+// junitTestCaseName names the test case of a document after its kind and
+// name, as the stdout output does
+func junitTestCaseName(r EvalResult) string {
+	if r.Kind == "" {
+		return "empty document"
+	}
+	if r.Name == "" {
+		return r.Kind
+	}
+	if r.Namespace != "" {
+		return fmt.Sprintf("%s (%s.%s)", r.Kind, r.Namespace, r.Name)
+	}
+	return fmt.Sprintf("%s (%s)", r.Kind, r.Name)
+}
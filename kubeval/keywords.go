@@ -0,0 +1,118 @@
+package kubeval
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// keywordErrorTypes maps JSON schema keywords to the types of the errors
+// gojsonschema reports when a value does not meet them
+var keywordErrorTypes = map[string][]string{
+	"additionalItems":      {"array_no_additional_items"},
+	"additionalProperties": {"additional_property_not_allowed"},
+	"allOf":                {"number_all_of"},
+	"anyOf":                {"number_any_of"},
+	"const":                {"const"},
+	"contains":             {"contains"},
+	"dependencies":         {"missing_dependency"},
+	"else":                 {"condition_else"},
+	"enum":                 {"enum"},
+	"exclusiveMaximum":     {"number_lt"},
+	"exclusiveMinimum":     {"number_gt"},
+	"format":               {"format"},
+	"maxItems":             {"array_max_items"},
+	"maxLength":            {"string_lte"},
+	"maxProperties":        {"array_max_properties"},
+	"maximum":              {"number_lte", "number_lt"},
+	"minItems":             {"array_min_items"},
+	"minLength":            {"string_gte"},
+	"minProperties":        {"array_min_properties"},
+	"minimum":              {"number_gte", "number_gt"},
+	"multipleOf":           {"multiple_of"},
+	"not":                  {"number_not"},
+	"oneOf":                {"number_one_of"},
+	"pattern":              {"pattern"},
+	"patternProperties":    {"invalid_property_pattern"},
+	"propertyNames":        {"invalid_property_name"},
+	"required":             {"required"},
+	"then":                 {"condition_then"},
+	"type":                 {"invalid_type"},
+	"uniqueItems":          {"unique"},
+}
+
+// checkErrorTypes are the types of the errors reported by kubeval's own
+// checks, which may be used as keywords alongside the JSON schema ones
+var checkErrorTypes = []string{
+	"api_version",
+	"image_digest",
+	"image_registry",
+	"image_tag",
+	"kubernetes_version",
+	"object_size",
+	"printer_column",
+	"recommended_label",
+	"required_field",
+	"resource_requests",
+	"scheduling_key",
+	"secret_data",
+	"secret_string_data",
+	"structural_schema",
+	"subresource",
+	"yaml_key",
+}
+
+// defaultWarningTypes are the types of the errors reported by kubeval's own
+// checks which are warnings unless passed to --error-on-keyword
+var defaultWarningTypes = []string{
+	"image_tag",
+	"resource_requests",
+}
+
+func validKeywords() []string {
+	keywords := append([]string{}, checkErrorTypes...)
+	for keyword := range keywordErrorTypes {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	return keywords
+}
+
+// checkKeywords returns an error if any of keywords is not known, naming
+// the flag it was passed to
+func checkKeywords(keywords []string, flag string) error {
+	for _, keyword := range keywords {
+		if !in(validKeywords(), keyword) {
+			return fmt.Errorf("Unknown keyword '%s' ('--%s' flag). Options are: %v", keyword, flag, validKeywords())
+		}
+	}
+	return nil
+}
+
+// matchesKeyword returns whether err was reported for one of keywords
+func matchesKeyword(err gojsonschema.ResultError, keywords []string) bool {
+	for _, keyword := range keywords {
+		if err.Type() == keyword || in(keywordErrorTypes[keyword], err.Type()) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyKeywordSeverity separates the errors for keywords in
+// Config.WarnOnKeywords, and those of defaultWarningTypes, which are
+// returned as warnings, from the rest. Keywords in Config.ErrorOnKeywords
+// are always errors, even when they are also in Config.WarnOnKeywords.
+func applyKeywordSeverity(errs []gojsonschema.ResultError, config *Config) ([]gojsonschema.ResultError, []gojsonschema.ResultError) {
+	var errors, warnings []gojsonschema.ResultError
+	for _, err := range errs {
+		warn := matchesKeyword(err, config.WarnOnKeywords) || in(defaultWarningTypes, err.Type())
+		if warn && !matchesKeyword(err, config.ErrorOnKeywords) {
+			warnings = append(warnings, err)
+		} else {
+			errors = append(errors, err)
+		}
+	}
+	return errors, warnings
+}
@@ -0,0 +1,75 @@
+package kubeval
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarnOnKeyword(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/secret_invalid_data.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "secret_invalid_data.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	config.CheckFormats = true
+	config.WarnOnKeywords = []string{"format"}
+
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assert.Empty(t, results[0].Errors)
+	assert.Len(t, results[0].Warnings, 1)
+	assert.Equal(t, "data: Does not match format 'byte'", results[0].Warnings[0].String())
+	assert.Equal(t, "valid", results[0].Status())
+
+	config.ErrorOnKeywords = []string{"format"}
+	results, err = Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assert.Len(t, results[0].Errors, 1)
+	assert.Empty(t, results[0].Warnings)
+	assert.Equal(t, "invalid", results[0].Status())
+}
+
+func TestWarnOnCheckKeyword(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/secret_invalid_data.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "secret_invalid_data.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	config.CheckSecretData = true
+	config.CheckFormats = true
+	config.WarnOnKeywords = []string{"secret_data", "secret_string_data", "format"}
+	config.ErrorOnKeywords = []string{"format"}
+
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assert.Len(t, results[0].Errors, 1)
+	assert.Equal(t, "format", results[0].Errors[0].Type())
+	assert.NotEmpty(t, results[0].Warnings)
+	for _, w := range results[0].Warnings {
+		assert.Contains(t, []string{"secret_data", "secret_string_data"}, w.Type())
+	}
+}
+
+func TestUnknownKeyword(t *testing.T) {
+	config := NewDefaultConfig()
+	config.ErrorOnKeywords = []string{"formats"}
+	_, err := Validate([]byte("kind: Service"), config)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Unknown keyword 'formats' ('--error-on-keyword' flag)")
+}
+
+func TestMatchesKeyword(t *testing.T) {
+	err := newCheckError("number_gte", []string{"spec", "replicas"}, -1, "Must be greater than or equal to 0")
+	assert.True(t, matchesKeyword(err, []string{"minimum"}))
+	assert.True(t, matchesKeyword(err, []string{"number_gte"}))
+	assert.False(t, matchesKeyword(err, []string{"maximum", "format"}))
+}
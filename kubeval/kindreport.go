@@ -0,0 +1,161 @@
+package kubeval
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// KindPassRate is how many of the documents of a kind passed validation
+type KindPassRate struct {
+	Kind  string `json:"kind,omitempty"`
+	Total int    `json:"total"`
+	// Passed documents were valid against their schema
+	Passed int `json:"passed"`
+	// Failed documents were invalid, or had a malformed schema
+	Failed int `json:"failed"`
+	// NotValidated documents were skipped, or had no schema
+	NotValidated int     `json:"notValidated"`
+	PassRate     float64 `json:"passRate"`
+}
+
+// kindReport is the structured form of the kind-report output
+type kindReport struct {
+	Kinds []KindPassRate `json:"kinds"`
+	Total KindPassRate   `json:"total"`
+}
+
+// KindReport returns the pass rate of each kind in results, lowest first so
+// that the most error-prone kinds lead, along with the totals across all
+// of them. Empty documents, which have no kind, are left out.
+func KindReport(results []ValidationResult) ([]KindPassRate, KindPassRate) {
+	total := KindPassRate{}
+	index := make(map[string]*KindPassRate)
+	var kinds []*KindPassRate
+	for _, r := range results {
+		if r.Kind == "" {
+			continue
+		}
+		rate, found := index[r.Kind]
+		if !found {
+			rate = &KindPassRate{Kind: r.Kind}
+			index[r.Kind] = rate
+			kinds = append(kinds, rate)
+		}
+		for _, counts := range []*KindPassRate{rate, &total} {
+			counts.Total++
+			switch getStatus(r) {
+			case statusValid:
+				counts.Passed++
+			case statusInvalid, statusSchemaError:
+				counts.Failed++
+			default:
+				counts.NotValidated++
+			}
+		}
+	}
+
+	report := make([]KindPassRate, 0, len(kinds))
+	for _, rate := range kinds {
+		rate.PassRate = passRate(*rate)
+		report = append(report, *rate)
+	}
+	total.PassRate = passRate(total)
+	sort.SliceStable(report, func(i, j int) bool {
+		if report[i].PassRate != report[j].PassRate {
+			return report[i].PassRate < report[j].PassRate
+		}
+		return report[i].Kind < report[j].Kind
+	})
+	return report, total
+}
+
+// passRate returns the fraction of the documents counted which passed
+func passRate(rate KindPassRate) float64 {
+	if rate.Total == 0 {
+		return 0
+	}
+	return float64(rate.Passed) / float64(rate.Total)
+}
+
+// String describes the pass rate, such as 45/48 passed (93.8%), 3 failed
+func (k KindPassRate) String() string {
+	s := fmt.Sprintf("%d/%d passed (%.1f%%)", k.Passed, k.Total, k.PassRate*100)
+	if k.Failed > 0 {
+		s += fmt.Sprintf(", %d failed", k.Failed)
+	}
+	if k.NotValidated > 0 {
+		s += fmt.Sprintf(", %d not validated", k.NotValidated)
+	}
+	return s
+}
+
+// kindReportOutputManager reports the pass rate of each kind once all the
+// results are in, as text or as JSON
+type kindReportOutputManager struct {
+	logger *log.Logger
+	json   bool
+
+	results []ValidationResult
+}
+
+// newDefaultKindReportOutputManager instantiates a new instance of
+// kindReportOutputManager using the default logger.
+func newDefaultKindReportOutputManager(asJSON bool) *kindReportOutputManager {
+	return newKindReportOutputManager(log.New(os.Stdout, "", 0), asJSON)
+}
+
+// newKindReportOutputManager constructs an instance of
+// kindReportOutputManager given a logger instance.
+func newKindReportOutputManager(l *log.Logger, asJSON bool) *kindReportOutputManager {
+	return &kindReportOutputManager{
+		logger: l,
+		json:   asJSON,
+	}
+}
+
+func (k *kindReportOutputManager) Put(r ValidationResult) error {
+	k.results = append(k.results, r)
+	return nil
+}
+
+func (k *kindReportOutputManager) Flush() error {
+	kinds, total := KindReport(k.results)
+	if k.json {
+		b, err := json.Marshal(kindReport{Kinds: kinds, Total: total})
+		if err != nil {
+			return err
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, b, "", "\t"); err != nil {
+			return err
+		}
+		k.logger.Print(out.String())
+		return nil
+	}
+
+	if len(kinds) == 0 {
+		k.logger.Print("No resources were found to validate")
+		return nil
+	}
+	width := len("Total")
+	for _, rate := range kinds {
+		if len(rate.Kind) > width {
+			width = len(rate.Kind)
+		}
+	}
+	var lines []string
+	for _, rate := range append(kinds, total) {
+		kind := rate.Kind
+		if kind == "" {
+			kind = "Total"
+		}
+		lines = append(lines, fmt.Sprintf("%-*s %s", width+1, kind+":", rate))
+	}
+	k.logger.Print(strings.Join(lines, "\n"))
+	return nil
+}
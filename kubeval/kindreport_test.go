@@ -0,0 +1,72 @@
+package kubeval
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+func kindReportResults() []ValidationResult {
+	invalid := []gojsonschema.ResultError{newCheckError("required", []string{"spec"}, nil, "selector is required")}
+	return []ValidationResult{
+		{FileName: "web.yaml", Kind: "Deployment", ResourceName: "web", ValidatedAgainstSchema: true},
+		{FileName: "api.yaml", Kind: "Deployment", ResourceName: "api", ValidatedAgainstSchema: true, Errors: invalid},
+		{FileName: "db.yaml", Kind: "Deployment", ResourceName: "db", ValidatedAgainstSchema: true},
+		{FileName: "web.yaml", Kind: "Service", ResourceName: "web", ValidatedAgainstSchema: true},
+		{FileName: "widget.yaml", Kind: "Widget", ResourceName: "thing"},
+		{FileName: "widget.yaml", Kind: "Widget", ResourceName: "broken", SchemaError: errors.New("malformed")},
+		{FileName: "empty.yaml"},
+	}
+}
+
+func TestKindReport(t *testing.T) {
+	kinds, total := KindReport(kindReportResults())
+	assert.Equal(t, []KindPassRate{
+		{Kind: "Widget", Total: 2, Failed: 1, NotValidated: 1, PassRate: 0},
+		{Kind: "Deployment", Total: 3, Passed: 2, Failed: 1, PassRate: 2.0 / 3},
+		{Kind: "Service", Total: 1, Passed: 1, PassRate: 1},
+	}, kinds)
+	assert.Equal(t, KindPassRate{Total: 6, Passed: 3, Failed: 2, NotValidated: 1, PassRate: 0.5}, total)
+}
+
+func Test_kindReportOutputManager(t *testing.T) {
+	buf := new(bytes.Buffer)
+	s := newKindReportOutputManager(log.New(buf, "", 0), false)
+	for _, r := range kindReportResults() {
+		assert.NoError(t, s.Put(r))
+	}
+	assert.NoError(t, s.Flush())
+	assert.Equal(t, "Widget:     0/2 passed (0.0%), 1 failed, 1 not validated\n"+
+		"Deployment: 2/3 passed (66.7%), 1 failed\n"+
+		"Service:    1/1 passed (100.0%)\n"+
+		"Total:      3/6 passed (50.0%), 2 failed, 1 not validated\n", buf.String())
+
+	buf.Reset()
+	s = newKindReportOutputManager(log.New(buf, "", 0), true)
+	assert.NoError(t, s.Put(kindReportResults()[0]))
+	assert.NoError(t, s.Flush())
+	assert.Equal(t, `{
+	"kinds": [
+		{
+			"kind": "Deployment",
+			"total": 1,
+			"passed": 1,
+			"failed": 0,
+			"notValidated": 0,
+			"passRate": 1
+		}
+	],
+	"total": {
+		"total": 1,
+		"passed": 1,
+		"failed": 0,
+		"notValidated": 0,
+		"passRate": 1
+	}
+}
+`, buf.String())
+}
@@ -2,9 +2,12 @@ package kubeval
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/hashicorp/go-multierror"
@@ -29,9 +32,33 @@ type ValidationResult struct {
 	Kind                   string
 	APIVersion             string
 	ValidatedAgainstSchema bool
-	Errors                 []gojsonschema.ResultError
-	ResourceName           string
-	ResourceNamespace      string
+	// Skipped is set when the resource was deliberately not validated,
+	// for example because its kind is in KindsToSkip
+	Skipped bool
+	Errors  []gojsonschema.ResultError
+	// Warnings are the errors for keywords in Config.WarnOnKeywords, which
+	// are reported without making the resource invalid
+	Warnings          []gojsonschema.ResultError
+	ResourceName      string
+	ResourceNamespace string
+	// ValidatedAgainstFallback is set when the schema locations could not be
+	// reached and the resource was validated against the much less thorough
+	// fallback schema bundled with kubeval instead
+	ValidatedAgainstFallback bool
+	// SchemaError is set when a schema was found for the resource but could
+	// not be compiled, because it is not valid JSON or not a valid JSON
+	// schema, so the resource could not be validated
+	SchemaError error
+	// KubernetesVersion is the version of Kubernetes the resource was
+	// validated against when set by the KubernetesVersionAnnotation,
+	// overriding Config.KubernetesVersion
+	KubernetesVersion string
+	// Environment is the environment the resource was rendered for, from
+	// Config.Environment
+	Environment string
+	// Object is the decoded resource, used by checks which compare
+	// resources against each other
+	Object map[string]interface{}
 }
 
 // VersionKind returns a string representation of this result's apiVersion and kind
@@ -39,6 +66,12 @@ func (v *ValidationResult) VersionKind() string {
 	return v.APIVersion + "/" + v.Kind
 }
 
+// Status returns the outcome of validating this result, which is one
+// of ValidStatuses()
+func (v *ValidationResult) Status() string {
+	return string(getStatus(*v))
+}
+
 // QualifiedName returns a string of the [namespace.]name of the k8s resource
 func (v *ValidationResult) QualifiedName() string {
 	if v.ResourceName == "" {
@@ -72,15 +105,27 @@ func determineSchemaURL(baseURL, kind, apiVersion string, config *Config) string
 		return fmt.Sprintf("%s/%s-standalone%s/%s.json", baseURL, normalisedVersion, strictSuffix, strings.ToLower(kind))
 	}
 
+	return fmt.Sprintf("%s/%s-standalone%s/%s.json", baseURL, normalisedVersion, strictSuffix, determineSchemaFileName(kind, apiVersion, config))
+}
+
+// determineSchemaFileName returns the name of the schema file, without
+// extension, for the given kind and apiVersion.
+func determineSchemaFileName(kind, apiVersion string, config *Config) string {
 	groupParts := strings.Split(apiVersion, "/")
-	versionParts := strings.Split(groupParts[0], ".")
 
-	kindSuffix := "-" + strings.ToLower(versionParts[0])
-	if len(groupParts) > 1 {
-		kindSuffix += "-" + strings.ToLower(groupParts[1])
+	// Resources in the core API group (such as `v1 Pod`) have no group
+	// in their apiVersion. Some schema mirrors name these after the
+	// OpenAPI definition rather than the short kind-version form.
+	if len(groupParts) == 1 {
+		version := strings.ToLower(groupParts[0])
+		if config.CoreGroupSchemaFormat == CoreGroupSchemaFormatQualified {
+			return fmt.Sprintf("io.k8s.api.core.%s.%s", version, strings.ToLower(kind))
+		}
+		return fmt.Sprintf("%s-%s", strings.ToLower(kind), version)
 	}
 
-	return fmt.Sprintf("%s/%s-standalone%s/%s%s.json", baseURL, normalisedVersion, strictSuffix, strings.ToLower(kind), kindSuffix)
+	versionParts := strings.Split(groupParts[0], ".")
+	return fmt.Sprintf("%s-%s-%s", strings.ToLower(kind), strings.ToLower(versionParts[0]), strings.ToLower(groupParts[1]))
 }
 
 func determineSchemaBaseURL(config *Config) string {
@@ -89,38 +134,126 @@ func determineSchemaBaseURL(config *Config) string {
 	// 2. If a --schema-location is passed, use it
 	// 3. If the KUBEVAL_SCHEMA_LOCATION is set, use it
 	// 4. Otherwise, use the DefaultSchemaLocation
+	// When a --schema-ref is passed, the schema repositories are used in place
+	// of the OpenShift and default locations so the ref can be resolved.
 
 	if config.OpenShift {
+		if config.SchemaRef != "" {
+			return OpenShiftSchemaRepository + "/" + config.SchemaRef
+		}
 		return OpenShiftSchemaLocation
 	}
 
 	if config.SchemaLocation != "" {
-		return config.SchemaLocation
+		return strings.Replace(config.SchemaLocation, SchemaRefPlaceholder, config.SchemaRef, -1)
 	}
 
 	// We only care that baseURL has a value after this call, so we can
 	// ignore LookupEnv's second return value
 	baseURL, _ := os.LookupEnv("KUBEVAL_SCHEMA_LOCATION")
 	if baseURL != "" {
-		return baseURL
+		return strings.Replace(baseURL, SchemaRefPlaceholder, config.SchemaRef, -1)
+	}
+
+	if config.SchemaRef != "" {
+		return DefaultSchemaRepository + "/" + config.SchemaRef
 	}
 
 	return DefaultSchemaLocation
 }
 
+// checkSchemaRef returns an error if Config.SchemaRef is set but a custom
+// schema location has no SchemaRefPlaceholder for it to replace, as it would
+// otherwise be ignored
+func checkSchemaRef(config *Config) error {
+	if config.SchemaRef == "" || config.OpenShift {
+		return nil
+	}
+	location := config.SchemaLocation
+	if location == "" {
+		location = os.Getenv("KUBEVAL_SCHEMA_LOCATION")
+	}
+	if location != "" && !strings.Contains(location, SchemaRefPlaceholder) {
+		return fmt.Errorf("Schema ref ('--schema-ref' flag) cannot be used with the schema location %s, which does not contain %s to replace with it", location, SchemaRefPlaceholder)
+	}
+	return nil
+}
+
+// isKindSkipped returns whether resources of the kind should be skipped,
+// because the kind is in KindsToSkip or not in a non-empty KindsToValidate
+func isKindSkipped(kind string, config *Config) bool {
+	if in(config.KindsToSkip, kind) {
+		return true
+	}
+	return len(config.KindsToValidate) > 0 && !in(config.KindsToValidate, kind)
+}
+
+// schemaCacheKey returns the key under which the schema for a resource
+// is cached. Schemas pinned to a ref, or for a Kubernetes version set by
+// the resource itself, are cached separately. So are schemas compiled with
+// format checkers, as their formats are renamed to those of the checkers
+// used, see renameFormats.
+func schemaCacheKey(resource *ValidationResult, config *Config) string {
+	key := resource.VersionKind()
+	if resource.KubernetesVersion != "" {
+		key = resource.KubernetesVersion + ":" + key
+	}
+	if config.SchemaRef != "" {
+		key = config.SchemaRef + "@" + key
+	}
+	if names := formatNames(formatCheckers(config)); len(names) > 0 {
+		formats := make([]string, 0, len(names))
+		for _, name := range names {
+			formats = append(formats, name)
+		}
+		sort.Strings(formats)
+		key = strings.Join(formats, ",") + "#" + key
+	}
+	return key
+}
+
+// applyKubernetesVersionAnnotation removes the KubernetesVersionAnnotation
+// from the resource, so it is not validated itself, and returns the config
+// to validate the resource with, using the version from the annotation.
+func applyKubernetesVersionAnnotation(body map[string]interface{}, result *ValidationResult, config *Config) (*Config, gojsonschema.ResultError) {
+	metadata, _ := getObject(body, "metadata")
+	annotations, _ := getObject(metadata, "annotations")
+	value, found := annotations[KubernetesVersionAnnotation]
+	if !found {
+		return config, nil
+	}
+	delete(annotations, KubernetesVersionAnnotation)
+	if len(annotations) == 0 {
+		delete(metadata, "annotations")
+	}
+
+	version, ok := value.(string)
+	version = strings.TrimPrefix(version, "v")
+	if !ok || version == "" {
+		return config, newCheckError("kubernetes_version", []string{"metadata", "annotations", KubernetesVersionAnnotation}, value, `Kubernetes version must be a string, such as "1.25"`)
+	}
+
+	result.KubernetesVersion = version
+	documentConfig := *config
+	documentConfig.KubernetesVersion = version
+	return &documentConfig, nil
+}
+
 // validateResource validates a single Kubernetes resource against
 // the relevant schema, detecting the type of resource automatically.
 // Returns the result and raw YAML body as map.
-func validateResource(data []byte, schemaCache map[string]*gojsonschema.Schema, config *Config) (ValidationResult, map[string]interface{}, error) {
+func validateResource(data []byte, schemaCache schemaStore, config *Config) (ValidationResult, map[string]interface{}, error) {
 	result := ValidationResult{}
 	result.FileName = config.FileName
+	result.Environment = config.Environment
 	var body map[string]interface{}
-	err := yaml.Unmarshal(data, &body)
+	err := unmarshalDocument(data, &body)
 	if err != nil {
 		return result, body, fmt.Errorf("Failed to decode YAML from %s: %s", result.FileName, err.Error())
 	} else if body == nil {
 		return result, body, nil
 	}
+	result.Object = body
 
 	metadata, _ := getObject(body, "metadata")
 	if metadata != nil {
@@ -146,37 +279,87 @@ func validateResource(data []byte, schemaCache map[string]*gojsonschema.Schema,
 	if err != nil {
 		return result, body, fmt.Errorf("%s: %s", result.FileName, err.Error())
 	}
+	// Validate an aliased apiVersion as if it were written in full, as
+	// schemas may only allow the official form
+	if canonical := canonicalAPIVersion(apiVersion, config); canonical != apiVersion {
+		apiVersion = canonical
+		body["apiVersion"] = canonical
+	}
 	result.APIVersion = apiVersion
 
-	if in(config.KindsToSkip, kind) {
+	if isKindSkipped(kind, config) || !config.SelectsAPIGroup(apiVersion) {
+		result.Skipped = true
 		return result, body, nil
 	}
 
+	if config.Selector != "" {
+		requirements, err := parseSelector(config.Selector)
+		if err != nil {
+			return result, body, err
+		}
+		if !matchesSelector(body, requirements) {
+			result.Skipped = true
+			return result, body, nil
+		}
+	}
+
 	if in(config.KindsToReject, kind) {
 		return result, body, fmt.Errorf("Prohibited resource kind '%s' in %s", kind, result.FileName)
 	}
 
+	// A malformed apiVersion is almost certainly a typo, rather than a
+	// kind for which no schema exists, so report it as such
+	if apiVersionErr := checkAPIVersionFormat(apiVersion); apiVersionErr != nil {
+		result.Errors = []gojsonschema.ResultError{apiVersionErr}
+		return result, body, nil
+	}
+
+	config, versionErr := applyKubernetesVersionAnnotation(body, &result, config)
+	if versionErr != nil {
+		result.Errors = []gojsonschema.ResultError{versionErr}
+		return result, body, nil
+	}
+
 	schemaErrors, err := validateAgainstSchema(body, &result, schemaCache, config)
+	if checksumErr, ok := err.(*SchemaChecksumError); ok {
+		return result, body, checksumErr
+	}
 	if err != nil {
 		return result, body, fmt.Errorf("%s: %s", result.FileName, err.Error())
 	}
-	result.Errors = schemaErrors
+	checkErrors := runChecks(body, &result, config)
+	if config.StrictYAMLKeys {
+		checkErrors = append(checkErrors, checkYAMLKeys(data)...)
+	}
+	errors, warnings := applyKeywordSeverity(append(schemaErrors, checkErrors...), config)
+	result.Errors = errors
+	result.Warnings = append(result.Warnings, warnings...)
 	return result, body, nil
 }
 
-func validateAgainstSchema(body interface{}, resource *ValidationResult, schemaCache map[string]*gojsonschema.Schema, config *Config) ([]gojsonschema.ResultError, error) {
-
+func validateAgainstSchema(body interface{}, resource *ValidationResult, schemaCache schemaStore, config *Config) ([]gojsonschema.ResultError, error) {
 	schema, err := downloadSchema(resource, schemaCache, config)
+	if compileErr, ok := err.(*schemaCompileError); ok {
+		resource.SchemaError = compileErr
+		return []gojsonschema.ResultError{}, nil
+	}
+	// A schema which fails verification is never ignored
+	if checksumErr, ok := err.(*SchemaChecksumError); ok {
+		return []gojsonschema.ResultError{}, checksumErr
+	}
+	// A kind missing from the schema map is left unvalidated, unless the
+	// map is strict, in which case it is an error even when missing schemas
+	// are otherwise ignored
+	if unmappedErr, ok := err.(*unmappedKindError); ok {
+		if config.StrictSchemaMap {
+			return []gojsonschema.ResultError{}, unmappedErr
+		}
+		return []gojsonschema.ResultError{}, nil
+	}
 	if err != nil || schema == nil {
 		return handleMissingSchema(err, config)
 	}
-
-	// Without forcing these types the schema fails to load
-	// Need to Work out proper handling for these types
-	gojsonschema.FormatCheckers.Add("int64", ValidFormat{})
-	gojsonschema.FormatCheckers.Add("byte", ValidFormat{})
-	gojsonschema.FormatCheckers.Add("int32", ValidFormat{})
-	gojsonschema.FormatCheckers.Add("int-or-string", ValidFormat{})
+	warnUnlistedSchema(resource, schemaCache, config)
 
 	documentLoader := gojsonschema.NewGoLoader(body)
 	results, err := schema.Validate(documentLoader)
@@ -187,53 +370,212 @@ func validateAgainstSchema(body interface{}, resource *ValidationResult, schemaC
 	}
 	resource.ValidatedAgainstSchema = true
 	if !results.Valid() {
+		restoreFormatNames(results.Errors())
 		return results.Errors(), nil
 	}
 
 	return []gojsonschema.ResultError{}, nil
 }
 
+// splitInput splits input into the documents to validate, whether it is a
+// List, a JSON array of resources or a stream of YAML documents. Whether it
+// is a JSON array is also returned, as the documents then have no file
+// names of their own.
+func splitInput(input []byte, config *Config) ([][]byte, bool) {
+	list := struct {
+		Version string
+		Kind    string
+		Items   []interface{}
+	}{}
+
+	unmarshalErr := unmarshalDocument(input, &list)
+	isYamlList := unmarshalErr == nil && list.Items != nil && len(list.Items) > 0
+
+	// Tools such as cdk8s and jsonnet emit a single JSON document holding
+	// an array of resources
+	var array []interface{}
+	isJSONArray := !isYamlList && bytes.HasPrefix(bytes.TrimSpace(input), []byte("[")) &&
+		unmarshalDocument(input, &array) == nil && len(array) > 0
+
+	var bits [][]byte
+	if isYamlList {
+		bits = make([][]byte, len(list.Items))
+		for i, item := range list.Items {
+			b, _ := yaml.Marshal(item)
+			bits[i] = b
+		}
+	} else if isJSONArray {
+		bits = make([][]byte, len(array))
+		for i, item := range array {
+			b, _ := yaml.Marshal(item)
+			bits[i] = b
+		}
+	} else {
+		bits = splitDocuments(input, config.DocumentSeparators)
+	}
+	return bits, isJSONArray
+}
+
 // returned schema may be nil scehma is missing and missing schemas are allowed
-func downloadSchema(resource *ValidationResult, schemaCache map[string]*gojsonschema.Schema, config *Config) (*gojsonschema.Schema, error) {
-	if schema, ok := schemaCache[resource.VersionKind()]; ok {
-		// If the schema was previously cached, there's no work to be done
+func downloadSchema(resource *ValidationResult, schemaCache schemaStore, config *Config) (*gojsonschema.Schema, error) {
+	cacheKey := schemaCacheKey(resource, config)
+	if schema, ok := cachedSchema(resource, schemaCache, cacheKey, config); ok {
+		return schema, nil
+	}
+	// Another validation sharing the cache may be compiling the schema, in
+	// which case it is cached once the lock is held
+	defer schemaCache.compiling(cacheKey)()
+	if schema, ok := cachedSchema(resource, schemaCache, cacheKey, config); ok {
 		return schema, nil
 	}
 
 	// We haven't cached this schema yet; look for one that works
-	primarySchemaBaseURL := determineSchemaBaseURL(config)
-	primarySchemaRef := determineSchemaURL(primarySchemaBaseURL, resource.Kind, resource.APIVersion, config)
-	schemaRefs := []string{primarySchemaRef}
+	var schemaRefs []string
+	if config.SchemaMap != "" {
+		// The schema map is authoritative, so no other location is tried
+		schemaRef, err := schemaMapRef(resource, config)
+		if err != nil {
+			return nil, err
+		}
+		schemaRefs = []string{schemaRef}
+	} else {
+		primarySchemaBaseURL := determineSchemaBaseURL(config)
+		primarySchemaRef := determineSchemaURL(primarySchemaBaseURL, resource.Kind, resource.APIVersion, config)
+		schemaRefs = []string{primarySchemaRef}
 
-	for _, additionalSchemaURLs := range config.AdditionalSchemaLocations {
-		additionalSchemaRef := determineSchemaURL(additionalSchemaURLs, resource.Kind, resource.APIVersion, config)
-		schemaRefs = append(schemaRefs, additionalSchemaRef)
+		for _, additionalSchemaURLs := range config.AdditionalSchemaLocations {
+			additionalSchemaRef := determineSchemaURL(additionalSchemaURLs, resource.Kind, resource.APIVersion, config)
+			schemaRefs = append(schemaRefs, additionalSchemaRef)
+		}
+
+		schemaRefs = append(schemaRefs, determineCRDCatalogSchemaURLs(resource.Kind, resource.APIVersion, config)...)
 	}
 
 	var errors *multierror.Error
+	var loadErrors []error
+
+	var compileErr error
 
 	for _, schemaRef := range schemaRefs {
 		schemaLoader := gojsonschema.NewReferenceLoader(schemaRef)
-		schema, err := gojsonschema.NewSchema(schemaLoader)
+		listed := true
+		if config.SchemaChecksums != "" {
+			verified, verifiedListed, err := verifiedSchemaLoader(schemaRef, config)
+			if checksumErr, ok := err.(*SchemaChecksumError); ok {
+				return nil, checksumErr
+			}
+			if err != nil {
+				loadErrors = append(loadErrors, err)
+				errors = multierror.Append(errors, fmt.Errorf("Failed initializing schema %s: %s", schemaRef, err))
+				continue
+			}
+			schemaLoader, listed = verified, verifiedListed
+		}
+		schema, unlisted, err := compileSchema(schemaRef, schemaLoader, config)
+		if checksumErr, ok := err.(*SchemaChecksumError); ok {
+			return nil, checksumErr
+		}
 		if err == nil {
+			if !listed {
+				unlisted = append([]string{schemaRef}, unlisted...)
+			}
 			// success! cache this and stop looking
-			schemaCache[resource.VersionKind()] = schema
+			schemaCache.storeUnlisted(cacheKey, unlisted)
+			schemaCache.store(cacheKey, schema)
 			return schema, nil
 		}
+		// A schema which exists but is malformed is reported as such, unless
+		// a later location has a schema which works
+		if malformed, ok := err.(*schemaCompileError); ok {
+			if compileErr == nil {
+				compileErr = malformed
+				continue
+			}
+			err = malformed.err
+		}
 		// We couldn't find a schema for this URL, so take a note, then try the next URL
+		loadErrors = append(loadErrors, err)
 		wrappedErr := fmt.Errorf("Failed initializing schema %s: %s", schemaRef, err)
 		errors = multierror.Append(errors, wrappedErr)
 	}
 
+	// The malformed schema is not cached, so every resource needing it
+	// reports the problem
+	if compileErr != nil {
+		return nil, compileErr
+	}
+
 	if errors != nil {
 		errors.ErrorFormat = singleLineErrorFormat
 	}
 
+	// Policy resources are validated against the bundled schemas of their
+	// CRDs when no schema location has one
+	policySchema, err := loadPolicySchema(resource, config)
+	if err != nil {
+		return nil, err
+	}
+	if policySchema != nil {
+		schemaCache.store(cacheKey, policySchema)
+		return policySchema, nil
+	}
+
 	// We couldn't find a schema for this resource. Cache its lack of existence
-	schemaCache[resource.VersionKind()] = nil
+	schemaCache.store(cacheKey, nil)
+
+	// If a schema location could not be reached, the bundled fallback
+	// schema is better than nothing
+	if config.OfflineFallback && isNetworkFailure(loadErrors) {
+		fallback, err := loadFallbackSchema(resource, schemaCache)
+		if err != nil {
+			return nil, err
+		}
+		if fallback != nil {
+			resource.ValidatedAgainstFallback = true
+			return fallback, nil
+		}
+	}
 	return nil, errors.ErrorOrNil()
 }
 
+// cachedSchema returns the schema cached under cacheKey for a resource and
+// whether there was one. A cached lack of a schema is returned too, unless
+// the fallback was used in its place.
+func cachedSchema(resource *ValidationResult, schemaCache schemaStore, cacheKey string, config *Config) (*gojsonschema.Schema, bool) {
+	schema, ok := schemaCache.load(cacheKey)
+	if !ok {
+		return nil, false
+	}
+	if schema == nil && config.OfflineFallback {
+		if fallback, _ := schemaCache.load(fallbackCacheKey(resource)); fallback != nil {
+			resource.ValidatedAgainstFallback = true
+			return fallback, true
+		}
+	}
+	return schema, true
+}
+
+// schemaCompileError is returned when a schema was found but is malformed
+type schemaCompileError struct {
+	ref string
+	err error
+}
+
+func (e *schemaCompileError) Error() string {
+	return fmt.Sprintf("Failed compiling schema %s: %s", e.ref, e.err)
+}
+
+// isMalformedJSON returns whether err, from loading a schema, is because
+// the schema is not valid JSON, rather than because it is missing or could
+// not be reached
+func isMalformedJSON(err error) bool {
+	switch err.(type) {
+	case *json.SyntaxError, *json.UnmarshalTypeError:
+		return true
+	}
+	return err == io.EOF || err == io.ErrUnexpectedEOF
+}
+
 func handleMissingSchema(err error, config *Config) ([]gojsonschema.ResultError, error) {
 	if config.IgnoreMissingSchemas {
 		return []gojsonschema.ResultError{}, nil
@@ -257,11 +599,39 @@ func Validate(input []byte, conf ...*Config) ([]ValidationResult, error) {
 // ValidateWithCache validates a Kubernetes YAML file, parsing out individual resources
 // and validating them all according to the relevant schemas
 // Allows passing a kubeval.NewSchemaCache() to cache schemas in-memory
-// between validations
+// between validations. A schema cache should only be shared between
+// validations with the same Config, and is not safe for concurrent use; see
+// ValidateWithSharedCache, and Validator, which takes care of both.
 func ValidateWithCache(input []byte, schemaCache map[string]*gojsonschema.Schema, conf ...*Config) ([]ValidationResult, error) {
+	results, err := validateWithStore(input, schemaMap(schemaCache), conf...)
+	return postProcess(results, conf...), err
+}
+
+// ValidateWithSharedCache validates a Kubernetes YAML file in the same way
+// as ValidateWithCache, caching the compiled schemas in a SchemaCache which
+// any number of validations with the same Config may share concurrently
+func ValidateWithSharedCache(input []byte, schemaCache *SchemaCache, conf ...*Config) ([]ValidationResult, error) {
+	results, err := validateWithStore(input, schemaCache, conf...)
+	return postProcess(results, conf...), err
+}
+
+// postProcess passes results through Config.PostProcess, if it is set
+func postProcess(results []ValidationResult, conf ...*Config) []ValidationResult {
+	if len(conf) != 1 || conf[0].PostProcess == nil {
+		return results
+	}
+	return conf[0].PostProcess(results)
+}
+
+// validateWithStore validates a Kubernetes YAML file, caching the compiled
+// schemas in schemaCache
+func validateWithStore(input []byte, schemaCache schemaStore, conf ...*Config) ([]ValidationResult, error) {
 	config := NewDefaultConfig()
 	if len(conf) == 1 {
-		config = conf[0]
+		// The file name is updated as documents are found, so work on a
+		// copy, leaving the caller's Config safe to share
+		copied := *conf[0]
+		config = &copied
 	}
 
 	results := make([]ValidationResult, 0)
@@ -270,32 +640,90 @@ func ValidateWithCache(input []byte, schemaCache map[string]*gojsonschema.Schema
 		return results, fmt.Errorf("Default namespace ('-n/--default-namespace' flag) must not be empty")
 	}
 
+	if config.FetchConcurrency < 0 {
+		return results, fmt.Errorf("Fetch concurrency ('--fetch-concurrency' flag) must not be negative")
+	}
+	useFetchSlots(config, schemaCache)
+
+	if config.CoreGroupSchemaFormat != "" && !in(validCoreGroupSchemaFormats(), config.CoreGroupSchemaFormat) {
+		return results, fmt.Errorf("Core group schema format ('--core-group-schema-format' flag) must be one of %v", validCoreGroupSchemaFormats())
+	}
+
+	for _, catalog := range config.CRDCatalogs {
+		if _, _, err := parseCRDCatalog(catalog); err != nil {
+			return results, err
+		}
+	}
+
+	for _, rule := range config.RequiredFields {
+		if _, err := parseRequiredFieldRule(rule); err != nil {
+			return results, err
+		}
+	}
+
+	if err := config.CheckKindFilters(); err != nil {
+		return results, err
+	}
+
+	if err := checkAPIGroups(config.APIGroups); err != nil {
+		return results, err
+	}
+
+	if err := checkSchemaRef(config); err != nil {
+		return results, err
+	}
+
+	if err := checkKeywords(config.WarnOnKeywords, "warn-on-keyword"); err != nil {
+		return results, err
+	}
+
+	if err := checkKeywords(config.ErrorOnKeywords, "error-on-keyword"); err != nil {
+		return results, err
+	}
+
+	if err := checkFormatCheckers(config); err != nil {
+		return results, err
+	}
+
+	if config.Selector != "" {
+		if _, err := parseSelector(config.Selector); err != nil {
+			return results, err
+		}
+	}
+
+	if !isValidReportFormatVersion(config.ReportFormatVersion) {
+		return results, fmt.Errorf("Report format version ('--report-format-version' flag) must be one of %v", validReportFormatVersions())
+	}
+
+	if config.JSONShape != "" && !in(validJSONShapes(), config.JSONShape) {
+		return results, fmt.Errorf("JSON shape ('--json-shape' flag) must be one of %v", validJSONShapes())
+	}
+
+	if config.UnlistedSchemas != "" && !in(validUnlistedSchemas(), config.UnlistedSchemas) {
+		return results, fmt.Errorf("Unlisted schemas ('--unlisted-schemas' flag) must be one of %v", validUnlistedSchemas())
+	}
+
+	if err := parseConfigFiles(config); err != nil {
+		return results, err
+	}
+
+	if config.CheckObjectSize {
+		if _, err := maxObjectSize(config); err != nil {
+			return results, err
+		}
+	}
+
 	if len(input) == 0 {
 		result := ValidationResult{}
 		result.FileName = config.FileName
+		result.Environment = config.Environment
 		results = append(results, result)
 		return results, nil
 	}
 
-	list := struct {
-		Version string
-		Kind    string
-		Items   []interface{}
-	}{}
+	defer useFormatCheckers(config)()
 
-	unmarshalErr := yaml.Unmarshal(input, &list)
-	isYamlList := unmarshalErr == nil && list.Items != nil && len(list.Items) > 0
-
-	var bits [][]byte
-	if isYamlList {
-		bits = make([][]byte, len(list.Items))
-		for i, item := range list.Items {
-			b, _ := yaml.Marshal(item)
-			bits[i] = b
-		}
-	} else {
-		bits = bytes.Split(input, []byte(detectLineBreak(input)+"---"+detectLineBreak(input)))
-	}
+	bits, isJSONArray := splitInput(input, config)
 
 	var errors *multierror.Error
 
@@ -303,47 +731,44 @@ func ValidateWithCache(input []byte, schemaCache map[string]*gojsonschema.Schema
 	helmSourcePattern := regexp.MustCompile(`^(?:---` + detectLineBreak(input) + `)?# Source: (.*)`)
 
 	// Save the fileName we were provided; if we detect a new fileName
-	// we'll use that, but we'll need to revert to the default afterward
+	// we'll use that for the following documents
 	originalFileName := config.FileName
-	defer func() {
-		// revert the filename back to the original
-		config.FileName = originalFileName
-	}()
 
 	seenResourcesSet := make(map[[4]string]bool) // set of [API version, kind, namespace, name]
 
-	for _, element := range bits {
+	for i, element := range bits {
+		if isJSONArray {
+			// qualify the filename with the index of the resource in the array
+			config.FileName = fmt.Sprintf("%s[%d]", originalFileName, i)
+		}
+
 		if len(element) > 0 {
 			if found := helmSourcePattern.FindStringSubmatch(string(element)); found != nil {
 				config.FileName = found[1]
 			}
 
 			result, body, err := validateResource(element, schemaCache, config)
+			// Validation stops altogether if a schema cannot be trusted
+			if checksumErr, ok := err.(*SchemaChecksumError); ok {
+				return append(results, result), checksumErr
+			}
 			if err != nil {
 				errors = multierror.Append(errors, err)
 				if config.ExitOnError {
 					return results, errors
 				}
 			} else {
-				if !in(config.KindsToSkip, result.Kind) {
+				if !isKindSkipped(result.Kind, config) && config.SelectsAPIGroup(result.APIVersion) {
 
 					metadata, _ := getObject(body, "metadata")
 					if metadata != nil {
-						namespace, _ := getString(metadata, "namespace")
 						name, _ := getString(metadata, "name")
 
-						var resolvedNamespace string
-						if len(namespace) > 0 {
-							resolvedNamespace = namespace
-						} else {
-							resolvedNamespace = config.DefaultNamespace
-						}
-
 						// If resource has `metadata:name` attribute
-						if len(resolvedNamespace) > 0 && len(name) > 0 {
-							key := [4]string{result.APIVersion, result.Kind, resolvedNamespace, name}
+						if len(name) > 0 {
+							key, scope := resourceIdentity(result, name, config)
 							if _, hasDuplicate := seenResourcesSet[key]; hasDuplicate {
-								errors = multierror.Append(errors, fmt.Errorf("%s: Duplicate '%s' resource '%s' in namespace '%s'", result.FileName, result.Kind, name, namespace))
+								errors = multierror.Append(errors, fmt.Errorf("%s: Duplicate '%s' resource '%s' in %s", result.FileName, result.Kind, name, scope))
 							}
 
 							seenResourcesSet[key] = true
@@ -355,6 +780,7 @@ func ValidateWithCache(input []byte, schemaCache map[string]*gojsonschema.Schema
 		} else {
 			result := ValidationResult{}
 			result.FileName = config.FileName
+			result.Environment = config.Environment
 			results = append(results, result)
 		}
 	}
@@ -365,10 +791,56 @@ func ValidateWithCache(input []byte, schemaCache map[string]*gojsonschema.Schema
 	return results, errors.ErrorOrNil()
 }
 
-func singleLineErrorFormat(es []error) string {
-	messages := make([]string, len(es))
-	for i, e := range es {
-		messages[i] = e.Error()
+// CheckResourceSet runs the checks which compare resources against each other,
+// such as evaluating policies, across the results of one or more calls to
+// Validate or ValidateWithCache. Problems are returned as errors.
+func CheckResourceSet(results []ValidationResult, conf ...*Config) error {
+	config := NewDefaultConfig()
+	if len(conf) == 1 {
+		config = conf[0]
 	}
-	return strings.Join(messages, "\n")
+
+	var errors *multierror.Error
+	if config.EvaluatePolicies {
+		errors = multierror.Append(errors, checkPolicies(results, config))
+	}
+	if config.CheckReferences {
+		errors = multierror.Append(errors, checkReferences(results, config))
+	}
+	if config.CheckConfigReferences {
+		errors = multierror.Append(errors, checkConfigReferences(results, config))
+	}
+
+	if errors != nil {
+		errors.ErrorFormat = singleLineErrorFormat
+	}
+	return errors.ErrorOrNil()
+}
+
+// CheckResourceSetWarnings runs the checks which compare resources against
+// each other but which, as they are approximate, should be reported as
+// warnings rather than failing validation, such as comparing workloads with
+// the ResourceQuotas of their namespaces. Problems are returned as errors.
+func CheckResourceSetWarnings(results []ValidationResult, conf ...*Config) error {
+	config := NewDefaultConfig()
+	if len(conf) == 1 {
+		config = conf[0]
+	}
+
+	var errors *multierror.Error
+	if config.CheckResourceQuotas {
+		errors = multierror.Append(errors, checkResourceQuotas(results, config))
+	}
+	if config.CheckImmutableFields {
+		errors = multierror.Append(errors, checkImmutableFields(results, config))
+	}
+
+	if errors != nil {
+		errors.ErrorFormat = singleLineErrorFormat
+	}
+	return errors.ErrorOrNil()
+}
+
+func singleLineErrorFormat(es []error) string {
+	return strings.Join(errorStrings(es), "\n")
 }
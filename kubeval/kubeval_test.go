@@ -1,16 +1,28 @@
 package kubeval
 
 import (
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
 	"github.com/xeipuuv/gojsonschema"
 )
 
+// localSchemaLocation returns the location of the minimal schemas stored
+// alongside the fixtures, for tests which should not need the network
+func localSchemaLocation() string {
+	schemaPath, _ := filepath.Abs("../fixtures/schemas")
+	return "file://" + filepath.ToSlash(schemaPath)
+}
+
 func TestValidateBlankInput(t *testing.T) {
 	blank := []byte("")
 	config := NewDefaultConfig()
@@ -111,6 +123,37 @@ func TestValidateInvalidInputs(t *testing.T) {
 	}
 }
 
+func TestValidateDuplicatesInDefaultNamespace(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/duplicates-with-namespace-default.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.DefaultNamespace = "the-default-namespace"
+	config.FileName = "duplicates-with-namespace-default.yaml"
+	config.IgnoreMissingSchemas = true
+	config.SchemaLocation = localSchemaLocation()
+	_, err := Validate(fileContents, config)
+	expected := "duplicates-with-namespace-default.yaml: Duplicate 'ReplicationController' resource 'bob' in namespace 'the-default-namespace'"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q, got %v", expected, err)
+	}
+}
+
+func TestValidateDuplicatesClusterScoped(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/duplicates-cluster-scoped.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "duplicates-cluster-scoped.yaml"
+	config.IgnoreMissingSchemas = true
+	config.SchemaLocation = localSchemaLocation()
+	_, err := Validate(fileContents, config)
+	assert.EqualError(t, err, "duplicates-cluster-scoped.yaml: Duplicate 'ClusterRole' resource 'reader' in the cluster")
+
+	config.ClusterScopedKinds = []string{"ClusterWidget"}
+	_, err = Validate(fileContents, config)
+	assert.EqualError(t, err, "duplicates-cluster-scoped.yaml: Duplicate 'ClusterRole' resource 'reader' in the cluster\n"+
+		"duplicates-cluster-scoped.yaml: Duplicate 'ClusterWidget' resource 'shared' in the cluster")
+}
+
 func TestValidateSourceExtraction(t *testing.T) {
 	expectedFileNames := []string{
 		"chart/templates/primary.yaml",   // first from primary template
@@ -137,6 +180,191 @@ func TestValidateSourceExtraction(t *testing.T) {
 	}
 }
 
+func TestValidateJSONArray(t *testing.T) {
+	var tests = []struct {
+		fixture        string
+		expectedErrors []int
+	}{
+		{
+			fixture:        "json_array.json",
+			expectedErrors: []int{0, 0},
+		},
+		{
+			fixture:        "json_array_invalid.json",
+			expectedErrors: []int{0, 3},
+		},
+	}
+	for _, test := range tests {
+		filePath, _ := filepath.Abs("../fixtures/" + test.fixture)
+		fileContents, _ := ioutil.ReadFile(filePath)
+		config := NewDefaultConfig()
+		config.FileName = test.fixture
+		config.SchemaLocation = localSchemaLocation()
+		results, err := Validate(fileContents, config)
+		if err != nil {
+			t.Fatalf("Unexpected error validating %s: %v", test.fixture, err)
+		}
+		if len(results) != len(test.expectedErrors) {
+			t.Fatalf("%s: expected %d results, got %d", test.fixture, len(test.expectedErrors), len(results))
+		}
+		for i, r := range results {
+			expectedFileName := fmt.Sprintf("%s[%d]", test.fixture, i)
+			if r.FileName != expectedFileName {
+				t.Errorf("%v: expected filename [%v], got [%v]", i, expectedFileName, r.FileName)
+			}
+			if !r.ValidatedAgainstSchema {
+				t.Errorf("%s: expected %s to be validated against a schema", r.FileName, r.Kind)
+			}
+			if len(r.Errors) != test.expectedErrors[i] {
+				t.Errorf("%s: expected %d errors, got %v", r.FileName, test.expectedErrors[i], r.Errors)
+			}
+		}
+		if config.FileName != test.fixture {
+			t.Errorf("Filename should be reverted to %s after validating, got %s", test.fixture, config.FileName)
+		}
+	}
+}
+
+func TestValidateLeadingComments(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/license_header.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "license_header.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected the license header to be ignored, got %d results", len(results))
+	}
+	if results[0].Kind != "Deployment" || !results[0].ValidatedAgainstSchema || len(results[0].Errors) != 0 {
+		t.Errorf("Expected a valid Deployment, got %+v", results[0])
+	}
+}
+
+func TestValidateDocumentSeparators(t *testing.T) {
+	var tests = []struct {
+		Name       string
+		File       string
+		Separators []string
+		Resources  []string
+	}{
+		{
+			Name:      "end markers",
+			File:      "document_end_markers.yaml",
+			Resources: []string{"Secret/credentials", "Service/web"},
+		},
+		{
+			Name:      "trailing whitespace and comments",
+			File:      "separator_trailing_whitespace.yaml",
+			Resources: []string{"Secret/credentials", "Service/web", "Service/api"},
+		},
+		{
+			Name:      "custom separator not given",
+			File:      "custom_separator.yaml",
+			Resources: []string{"Service/web"},
+		},
+		{
+			Name:       "custom separator",
+			File:       "custom_separator.yaml",
+			Separators: []string{"# ---8<---"},
+			Resources:  []string{"Secret/credentials", "Service/web"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			filePath, _ := filepath.Abs("../fixtures/" + test.File)
+			fileContents, _ := ioutil.ReadFile(filePath)
+			config := NewDefaultConfig()
+			config.FileName = test.File
+			config.SchemaLocation = localSchemaLocation()
+			config.DocumentSeparators = test.Separators
+			results, err := Validate(fileContents, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var resources []string
+			for _, result := range results {
+				if len(result.Errors) != 0 {
+					t.Errorf("Expected %s/%s to be valid, got %v", result.Kind, result.ResourceName, result.Errors)
+				}
+				resources = append(resources, result.Kind+"/"+result.ResourceName)
+			}
+			assert.Equal(t, test.Resources, resources)
+		})
+	}
+}
+
+func TestSplitDocuments(t *testing.T) {
+	var tests = []struct {
+		Name      string
+		Input     string
+		Documents []string
+	}{
+		{
+			Name:      "leading separator",
+			Input:     "---\nkind: A\n",
+			Documents: []string{"kind: A\n"},
+		},
+		{
+			Name:      "comments above the first separator",
+			Input:     "# header\n---\n# second\n---\nkind: A\n",
+			Documents: []string{"# second", "kind: A\n"},
+		},
+		{
+			Name:      "windows line endings",
+			Input:     "kind: A\r\n---\r\nkind: B\r\n",
+			Documents: []string{"kind: A\r", "kind: B\r\n"},
+		},
+		{
+			Name:      "end marker then separator",
+			Input:     "kind: A\n...\n---\nkind: B\n...\n",
+			Documents: []string{"kind: A", "kind: B"},
+		},
+		{
+			Name:      "document after an end marker",
+			Input:     "kind: A\n...\nkind: B\n",
+			Documents: []string{"kind: A", "kind: B\n"},
+		},
+		{
+			Name:      "separator inside a value",
+			Input:     "kind: A\ndata: |\n  ---\n  text\n",
+			Documents: []string{"kind: A\ndata: |\n  ---\n  text\n"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var documents []string
+			for _, document := range splitDocuments([]byte(test.Input), nil) {
+				documents = append(documents, string(document))
+			}
+			assert.Equal(t, test.Documents, documents)
+		})
+	}
+}
+
+func TestValidateMixedFormats(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/mixed_formats.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "mixed_formats.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectedKinds := []string{"Service", "Deployment"}
+	if len(results) != len(expectedKinds) {
+		t.Fatalf("Expected %d results, got %d", len(expectedKinds), len(results))
+	}
+	for i, r := range results {
+		if r.Kind != expectedKinds[i] || !r.ValidatedAgainstSchema || len(r.Errors) != 0 {
+			t.Errorf("Expected a valid %s, got %+v", expectedKinds[i], r)
+		}
+	}
+}
+
 func TestStrictCatchesAdditionalErrors(t *testing.T) {
 	config := NewDefaultConfig()
 	config.Strict = true
@@ -303,6 +531,70 @@ func TestDetermineSchemaURL(t *testing.T) {
 	}
 }
 
+func TestDetermineSchemaURLForCoreGroup(t *testing.T) {
+	var tests = []struct {
+		format   string
+		kind     string
+		expected string
+	}{
+		{
+			format:   "",
+			kind:     "Pod",
+			expected: "https://base/master-standalone/pod-v1.json",
+		},
+		{
+			format:   CoreGroupSchemaFormatShort,
+			kind:     "Service",
+			expected: "https://base/master-standalone/service-v1.json",
+		},
+		{
+			format:   CoreGroupSchemaFormatShort,
+			kind:     "ConfigMap",
+			expected: "https://base/master-standalone/configmap-v1.json",
+		},
+		{
+			format:   CoreGroupSchemaFormatQualified,
+			kind:     "Pod",
+			expected: "https://base/master-standalone/io.k8s.api.core.v1.pod.json",
+		},
+		{
+			format:   CoreGroupSchemaFormatQualified,
+			kind:     "Namespace",
+			expected: "https://base/master-standalone/io.k8s.api.core.v1.namespace.json",
+		},
+		{
+			format:   CoreGroupSchemaFormatQualified,
+			kind:     "ReplicationController",
+			expected: "https://base/master-standalone/io.k8s.api.core.v1.replicationcontroller.json",
+		},
+	}
+	for _, test := range tests {
+		config := NewDefaultConfig()
+		config.CoreGroupSchemaFormat = test.format
+		schemaURL := determineSchemaURL("https://base", test.kind, "v1", config)
+		if schemaURL != test.expected {
+			t.Errorf("Schema URL should be %s, got %s", test.expected, schemaURL)
+		}
+	}
+
+	// the format only applies to the core group
+	config := NewDefaultConfig()
+	config.CoreGroupSchemaFormat = CoreGroupSchemaFormatQualified
+	schemaURL := determineSchemaURL("https://base", "Deployment", "apps/v1", config)
+	if expected := "https://base/master-standalone/deployment-apps-v1.json"; schemaURL != expected {
+		t.Errorf("Schema URL should be %s, got %s", expected, schemaURL)
+	}
+}
+
+func TestValidateUnknownCoreGroupSchemaFormat(t *testing.T) {
+	config := NewDefaultConfig()
+	config.CoreGroupSchemaFormat = "unknown"
+	_, err := Validate([]byte("kind: Pod"), config)
+	if err == nil {
+		t.Errorf("Validate should fail when given an unknown core group schema format")
+	}
+}
+
 func TestDetermineSchemaForSchemaLocation(t *testing.T) {
 	oldVal, found := os.LookupEnv("KUBEVAL_SCHEMA_LOCATION")
 	defer func() {
@@ -338,6 +630,26 @@ func TestDetermineSchemaForSchemaLocation(t *testing.T) {
 			envVar:   "",
 			expected: DefaultSchemaLocation,
 		},
+		{
+			config:   &Config{SchemaRef: "v1.14.0"},
+			envVar:   "",
+			expected: DefaultSchemaRepository + "/v1.14.0",
+		},
+		{
+			config:   &Config{OpenShift: true, SchemaRef: "abc123"},
+			envVar:   "",
+			expected: OpenShiftSchemaRepository + "/abc123",
+		},
+		{
+			config:   &Config{SchemaLocation: "https://mirror/{ref}/schemas", SchemaRef: "v1.14.0"},
+			envVar:   "",
+			expected: "https://mirror/v1.14.0/schemas",
+		},
+		{
+			config:   &Config{SchemaRef: "v1.14.0"},
+			envVar:   "https://base/{ref}",
+			expected: "https://base/v1.14.0",
+		},
 	}
 	for i, test := range tests {
 		os.Setenv("KUBEVAL_SCHEMA_LOCATION", test.envVar)
@@ -348,6 +660,78 @@ func TestDetermineSchemaForSchemaLocation(t *testing.T) {
 	}
 }
 
+func TestCheckSchemaRef(t *testing.T) {
+	oldVal, found := os.LookupEnv("KUBEVAL_SCHEMA_LOCATION")
+	defer func() {
+		if found {
+			os.Setenv("KUBEVAL_SCHEMA_LOCATION", oldVal)
+		} else {
+			os.Unsetenv("KUBEVAL_SCHEMA_LOCATION")
+		}
+	}()
+
+	os.Setenv("KUBEVAL_SCHEMA_LOCATION", "")
+	var tests = []struct {
+		config   *Config
+		expected string
+	}{
+		{config: &Config{SchemaLocation: "https://mirror/schemas"}},
+		{config: &Config{SchemaRef: "v1.14.0"}},
+		{config: &Config{SchemaRef: "v1.14.0", SchemaLocation: "https://mirror/{ref}/schemas"}},
+		{config: &Config{SchemaRef: "v1.14.0", OpenShift: true, SchemaLocation: "https://mirror/schemas"}},
+		{
+			config:   &Config{SchemaRef: "v1.14.0", SchemaLocation: "https://mirror/schemas"},
+			expected: "Schema ref ('--schema-ref' flag) cannot be used with the schema location https://mirror/schemas, which does not contain {ref} to replace with it",
+		},
+	}
+	for i, test := range tests {
+		err := checkSchemaRef(test.config)
+		if test.expected == "" && err != nil {
+			t.Errorf("test #%d: Unexpected error: %v", i, err)
+		}
+		if test.expected != "" && (err == nil || err.Error() != test.expected) {
+			t.Errorf("test #%d: Expected error %s, got %v", i, test.expected, err)
+		}
+	}
+
+	os.Setenv("KUBEVAL_SCHEMA_LOCATION", "https://base")
+	if err := checkSchemaRef(&Config{SchemaRef: "v1.14.0"}); err == nil {
+		t.Errorf("Expected an error for a KUBEVAL_SCHEMA_LOCATION without {ref}")
+	}
+}
+
+func TestValidateReportFormatVersion(t *testing.T) {
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	for _, version := range []int{-1, 3} {
+		config.ReportFormatVersion = version
+		_, err := Validate([]byte(""), config)
+		if err == nil || err.Error() != "Report format version ('--report-format-version' flag) must be one of [1 2]" {
+			t.Errorf("Report format version %d should be rejected, got %v", version, err)
+		}
+	}
+	for _, version := range append(validReportFormatVersions(), 0) {
+		config.ReportFormatVersion = version
+		if _, err := Validate([]byte(""), config); err != nil {
+			t.Errorf("Report format version %d should be accepted, got %v", version, err)
+		}
+	}
+}
+
+func TestSchemaCacheKey(t *testing.T) {
+	resource := &ValidationResult{Kind: "Pod", APIVersion: "v1"}
+	if key := schemaCacheKey(resource, &Config{}); key != "v1/Pod" {
+		t.Errorf("Schema cache key should be v1/Pod, got %s", key)
+	}
+	if key := schemaCacheKey(resource, &Config{SchemaRef: "v1.14.0"}); key != "v1.14.0@v1/Pod" {
+		t.Errorf("Schema cache key should include the ref, got %s", key)
+	}
+	resource.KubernetesVersion = "1.25"
+	if key := schemaCacheKey(resource, &Config{SchemaRef: "v1.14.0"}); key != "v1.14.0@1.25:v1/Pod" {
+		t.Errorf("Schema cache key should include the Kubernetes version from the resource, got %s", key)
+	}
+}
+
 func TestGetString(t *testing.T) {
 	var tests = []struct {
 		body        map[string]interface{}
@@ -471,3 +855,197 @@ func TestFlagAdding(t *testing.T) {
 		}
 	}
 }
+
+func TestKubernetesVersionAnnotation(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/kubernetes_version_annotation.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "kubernetes_version_annotation.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	config.Strict = false
+	schemaCache := NewSchemaCache()
+	results, err := ValidateWithCache(fileContents, schemaCache, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !assert.Len(t, results, 2) {
+		return
+	}
+
+	assert.Equal(t, "", results[0].KubernetesVersion)
+	assert.True(t, results[0].ValidatedAgainstSchema)
+	assert.Equal(t, "1.25", results[1].KubernetesVersion)
+	assert.True(t, results[1].ValidatedAgainstSchema)
+	assert.Empty(t, results[1].Errors)
+	assert.NotContains(t, results[1].Object["metadata"], "annotations")
+	assert.Contains(t, schemaCache, "v1/Service")
+	assert.Contains(t, schemaCache, "1.25:v1/Service")
+	assert.Equal(t, "master", config.KubernetesVersion)
+
+	// a version with no schemas available fails like the global version would
+	_, err = Validate([]byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: pinned\n  annotations:\n    kubeval.io/kubernetes-version: \"1.99\"\n"), config)
+	assert.Error(t, err)
+
+	results, err = Validate([]byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: pinned\n  annotations:\n    kubeval.io/kubernetes-version: 1.25\n"), config)
+	assert.NoError(t, err)
+	if assert.Len(t, results[0].Errors, 1) {
+		assert.Equal(t, `metadata.annotations.kubeval.io/kubernetes-version: Kubernetes version must be a string, such as "1.25"`, results[0].Errors[0].String())
+	}
+}
+
+func TestOnlyKinds(t *testing.T) {
+	input := []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: web\n")
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	config.KindsToValidate = []string{"Secret"}
+	results, err := Validate(input, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, "skipped", results[0].Status())
+		assert.Equal(t, "valid", results[1].Status())
+	}
+}
+
+func TestValidateAPIGroups(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/api_groups.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+
+	var tests = []struct {
+		groups   []string
+		statuses []string
+	}{
+		{groups: []string{"apps", "core"}, statuses: []string{"valid", "skipped", "valid"}},
+		// There is no schema for the Job
+		{groups: []string{"", "batch"}, statuses: []string{"valid", "unvalidated", "skipped"}},
+		{groups: []string{"batch"}, statuses: []string{"skipped", "unvalidated", "skipped"}},
+	}
+	for _, test := range tests {
+		config.APIGroups = test.groups
+		config.IgnoreMissingSchemas = true
+		results, err := Validate(fileContents, config)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		statuses := []string{}
+		for _, r := range results {
+			statuses = append(statuses, r.Status())
+		}
+		assert.Equal(t, test.statuses, statuses, "%v", test.groups)
+	}
+
+	config.APIGroups = []string{"apps/v1"}
+	_, err := Validate(fileContents, config)
+	assert.EqualError(t, err, "API groups ('--api-groups' flag) must not include a version, such as apps rather than apps/v1, got 'apps/v1'")
+}
+
+func TestCheckKindFilters(t *testing.T) {
+	var tests = []struct {
+		skip     []string
+		only     []string
+		expected string
+	}{
+		{skip: []string{"Secret"}},
+		{only: []string{"Secret"}},
+		{skip: []string{"Service"}, only: []string{"Secret"}},
+		{
+			skip:     []string{"Secret"},
+			only:     []string{"Secret", "Service"},
+			expected: "Kinds cannot be passed to both '--only-kinds' and '--skip-kinds': [Secret]",
+		},
+		{
+			skip:     []string{"Secret", "Service"},
+			only:     []string{"Service", "Secret"},
+			expected: "Every kind passed to '--only-kinds' is also passed to '--skip-kinds', so nothing would be validated: [Service Secret]",
+		},
+	}
+	for _, test := range tests {
+		config := &Config{KindsToSkip: test.skip, KindsToValidate: test.only}
+		err := config.CheckKindFilters()
+		if test.expected == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, test.expected)
+		}
+	}
+}
+
+func TestValidateMalformedSchema(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/broken_schema.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	brokenSchemaPath, _ := filepath.Abs("../fixtures/schemas_broken")
+	config := NewDefaultConfig()
+	config.FileName = "broken_schema.yaml"
+	config.SchemaLocation = "file://" + filepath.ToSlash(brokenSchemaPath)
+	config.AdditionalSchemaLocations = []string{localSchemaLocation()}
+	config.IgnoreMissingSchemas = true
+
+	schemaCache := NewSchemaCache()
+	for i := 0; i < 2; i++ {
+		results, err := ValidateWithCache(fileContents, schemaCache, config)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		assert.Len(t, results, 3)
+
+		for _, r := range results[:2] {
+			assert.Equal(t, "schema_error", r.Status(), r.Kind)
+			assert.False(t, r.ValidatedAgainstSchema)
+			assert.Empty(t, r.Errors)
+		}
+		assert.Contains(t, results[0].SchemaError.Error(), "Failed compiling schema file://")
+		assert.Contains(t, results[0].SchemaError.Error(), "widget-example-v1.json")
+		assert.Contains(t, results[1].SchemaError.Error(), "gadget-example-v1.json")
+
+		// Other documents carry on validating
+		assert.Equal(t, "valid", results[2].Status())
+	}
+}
+
+func TestValidateMalformedRemoteSchemaFetchedOnce(t *testing.T) {
+	fileContents, _ := ioutil.ReadFile("../fixtures/broken_schema.yaml")
+	var lock sync.Mutex
+	requests := make(map[string]int)
+	files := http.FileServer(http.Dir("../fixtures/schemas_broken"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests[r.URL.Path]++
+		lock.Unlock()
+		files.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	config := NewDefaultConfig()
+	config.SchemaLocation = server.URL
+	config.AdditionalSchemaLocations = []string{localSchemaLocation()}
+
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if assert.Len(t, results, 3) {
+		assert.Equal(t, "schema_error", results[0].Status())
+		assert.Equal(t, "schema_error", results[1].Status())
+	}
+	assert.Equal(t, 1, requests["/master-standalone/widget-example-v1.json"])
+	assert.Equal(t, 1, requests["/master-standalone/gadget-example-v1.json"])
+}
+
+func TestValidateRecordsEnvironment(t *testing.T) {
+	config := NewDefaultConfig()
+	config.FileName = "overlays/prod"
+	config.Environment = "prod"
+	config.SchemaLocation = localSchemaLocation()
+
+	results, err := Validate([]byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: web\n---\n"), config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, "prod", results[0].Environment)
+		assert.Equal(t, "prod", results[1].Environment)
+		assert.Equal(t, "prod", newEvalResult(results[0]).Environment)
+	}
+}
@@ -0,0 +1,62 @@
+package kubeval
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// recommendedLabelPrefix is the prefix of the recommended common labels, see
+// https://kubernetes.io/docs/concepts/overview/working-with-objects/common-labels/
+const recommendedLabelPrefix = "app.kubernetes.io/"
+
+// defaultRecommendedLabels returns the recommended labels required by
+// Config.CheckRecommendedLabels unless others are given
+func defaultRecommendedLabels() []string {
+	return []string{
+		"app.kubernetes.io/name",
+		"app.kubernetes.io/instance",
+		"app.kubernetes.io/managed-by",
+	}
+}
+
+// labelValuePattern matches a valid label value, which must also be no
+// longer than 63 characters
+var labelValuePattern = regexp.MustCompile(`^(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?$`)
+
+// checkRecommendedLabels checks that a resource has each of the labels in
+// Config.RecommendedLabels with a non-empty value, and that the values of
+// all of its app.kubernetes.io/ labels are valid label values
+func checkRecommendedLabels(body map[string]interface{}, config *Config) []gojsonschema.ResultError {
+	metadata, _ := getObject(body, "metadata")
+	if metadata == nil {
+		return nil
+	}
+	labels, _ := getObject(metadata, "labels")
+
+	required := config.RecommendedLabels
+	if len(required) == 0 {
+		required = defaultRecommendedLabels()
+	}
+
+	var errors []gojsonschema.ResultError
+	for _, key := range required {
+		if value, found := labels[key]; !found || value == nil || value == "" {
+			errors = append(errors, newCheckError("recommended_label", []string{"metadata", "labels", key}, nil, fmt.Sprintf("Label %s is required", key)))
+		}
+	}
+	for _, key := range sortedKeys(labels) {
+		if !strings.HasPrefix(key, recommendedLabelPrefix) && !in(required, key) {
+			continue
+		}
+		value, ok := labels[key].(string)
+		if !ok {
+			errors = append(errors, newCheckError("recommended_label", []string{"metadata", "labels", key}, labels[key], fmt.Sprintf("Label %s must be a string", key)))
+		} else if len(value) > 63 || !labelValuePattern.MatchString(value) {
+			errors = append(errors, newCheckError("recommended_label", []string{"metadata", "labels", key}, value, fmt.Sprintf("Label %s has an invalid value '%s', which must be no more than 63 alphanumeric characters, '-', '_' or '.', starting and ending with an alphanumeric character", key, value)))
+		}
+	}
+	return errors
+}
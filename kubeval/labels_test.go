@@ -0,0 +1,53 @@
+package kubeval
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckRecommendedLabels(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/recommended_labels.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "recommended_labels.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	config.CheckRecommendedLabels = true
+
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assert.Empty(t, results[0].Errors)
+	errors := []string{}
+	for _, e := range results[1].Errors {
+		assert.Equal(t, "recommended_label", e.Type())
+		errors = append(errors, e.String())
+	}
+	assert.Equal(t, []string{
+		"metadata.labels.app.kubernetes.io/instance: Label app.kubernetes.io/instance is required",
+		"metadata.labels.app.kubernetes.io/managed-by: Label app.kubernetes.io/managed-by is required",
+		"metadata.labels.app.kubernetes.io/version: Label app.kubernetes.io/version has an invalid value '-1.0', which must be no more than 63 alphanumeric characters, '-', '_' or '.', starting and ending with an alphanumeric character",
+	}, errors)
+}
+
+func TestCheckRecommendedLabelsConfigured(t *testing.T) {
+	config := NewDefaultConfig()
+	config.RecommendedLabels = []string{"app.kubernetes.io/name", "team"}
+	body := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{
+				"app.kubernetes.io/name": "web",
+				"team":                   "payments team",
+			},
+		},
+	}
+	errors := checkRecommendedLabels(body, config)
+	if assert.Len(t, errors, 1) {
+		assert.Contains(t, errors[0].String(), "metadata.labels.team: Label team has an invalid value 'payments team'")
+	}
+
+	assert.Empty(t, checkRecommendedLabels(map[string]interface{}{"kind": "List"}, config), "resources without metadata are not checked")
+}
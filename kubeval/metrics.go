@@ -0,0 +1,65 @@
+package kubeval
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// metricsLabelEscaper escapes label values in the Prometheus text format
+var metricsLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteMetrics writes metrics about a run, given its results and how long
+// it took, in the Prometheus text exposition format, as read by the
+// node_exporter textfile collector.
+func WriteMetrics(w io.Writer, results []ValidationResult, duration time.Duration, config *Config) error {
+	type key struct {
+		kind   string
+		status status
+	}
+	documents := make(map[key]int)
+	failures := make(map[string]int)
+	for _, r := range results {
+		s := getStatus(r)
+		documents[key{r.Kind, s}]++
+		if isFailure(s, config) {
+			failures[r.Kind]++
+		}
+	}
+
+	keys := make([]key, 0, len(documents))
+	for k := range documents {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].kind != keys[j].kind {
+			return keys[i].kind < keys[j].kind
+		}
+		return keys[i].status < keys[j].status
+	})
+	kinds := make([]string, 0, len(failures))
+	for kind := range failures {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	var b strings.Builder
+	b.WriteString("# HELP kubeval_documents_total Documents validated, by kind and status.\n")
+	b.WriteString("# TYPE kubeval_documents_total counter\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "kubeval_documents_total{kind=\"%s\",status=\"%s\"} %d\n", metricsLabelEscaper.Replace(k.kind), k.status, documents[k])
+	}
+	b.WriteString("# HELP kubeval_failures_total Documents which failed validation, by kind.\n")
+	b.WriteString("# TYPE kubeval_failures_total counter\n")
+	for _, kind := range kinds {
+		fmt.Fprintf(&b, "kubeval_failures_total{kind=\"%s\"} %d\n", metricsLabelEscaper.Replace(kind), failures[kind])
+	}
+	b.WriteString("# HELP kubeval_duration_seconds Time taken by the run.\n")
+	b.WriteString("# TYPE kubeval_duration_seconds gauge\n")
+	fmt.Fprintf(&b, "kubeval_duration_seconds %g\n", duration.Seconds())
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
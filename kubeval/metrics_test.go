@@ -0,0 +1,40 @@
+package kubeval
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	results := []ValidationResult{
+		{Kind: "Deployment", ValidatedAgainstSchema: true},
+		{Kind: "Deployment", ValidatedAgainstSchema: true, Errors: newResultErrors([]string{"error"})},
+		{Kind: "Service", ValidatedAgainstSchema: true},
+		{Kind: "Service", SchemaError: errors.New("bad schema")},
+		{Kind: `Odd"Kind`},
+		{},
+	}
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, WriteMetrics(buf, results, 1500*time.Millisecond, NewDefaultConfig()))
+	assert.Equal(t, `# HELP kubeval_documents_total Documents validated, by kind and status.
+# TYPE kubeval_documents_total counter
+kubeval_documents_total{kind="",status="empty"} 1
+kubeval_documents_total{kind="Deployment",status="invalid"} 1
+kubeval_documents_total{kind="Deployment",status="valid"} 1
+kubeval_documents_total{kind="Odd\"Kind",status="unvalidated"} 1
+kubeval_documents_total{kind="Service",status="schema_error"} 1
+kubeval_documents_total{kind="Service",status="valid"} 1
+# HELP kubeval_failures_total Documents which failed validation, by kind.
+# TYPE kubeval_failures_total counter
+kubeval_failures_total{kind="Deployment"} 1
+kubeval_failures_total{kind="Service"} 1
+# HELP kubeval_duration_seconds Time taken by the run.
+# TYPE kubeval_duration_seconds gauge
+kubeval_duration_seconds 1.5
+`, buf.String())
+}
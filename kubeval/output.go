@@ -4,8 +4,14 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"html"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
 
 	kLog "github.com/instrumenta/kubeval/log"
 )
@@ -23,9 +29,15 @@ type outputManager interface {
 }
 
 const (
-	outputSTD  = "stdout"
-	outputJSON = "json"
-	outputTAP  = "tap"
+	outputSTD      = "stdout"
+	outputJSON     = "json"
+	outputTAP      = "tap"
+	outputPretty   = "pretty"
+	outputMarkdown = "markdown"
+	outputJUnit    = "junit"
+
+	outputKindReport     = "kind-report"
+	outputKindReportJSON = "kind-report-json"
 )
 
 func validOutputs() []string {
@@ -33,121 +45,356 @@ func validOutputs() []string {
 		outputSTD,
 		outputJSON,
 		outputTAP,
+		outputPretty,
+		outputMarkdown,
+		outputJUnit,
+		outputKindReport,
+		outputKindReportJSON,
 	}
 }
 
-func GetOutputManager(outFmt string) outputManager {
+// GetOutputManager returns the outputManager for the given format. An optional
+// Config controls presentation options shared by the output formats.
+func GetOutputManager(outFmt string, conf ...*Config) outputManager {
+	config := NewDefaultConfig()
+	if len(conf) == 1 {
+		config = conf[0]
+	}
+
 	switch outFmt {
 	case outputSTD:
-		return newSTDOutputManager()
+		return newSTDOutputManager(config)
 	case outputJSON:
-		return newDefaultJSONOutputManager()
+		return newDefaultJSONOutputManager(config)
 	case outputTAP:
 		return newDefaultTAPOutputManager()
+	case outputPretty:
+		return newDefaultPrettyOutputManager(config)
+	case outputMarkdown:
+		return newDefaultMarkdownOutputManager(config)
+	case outputJUnit:
+		return newDefaultJUnitOutputManager()
+	case outputKindReport:
+		return newDefaultKindReportOutputManager(false)
+	case outputKindReportJSON:
+		return newDefaultKindReportOutputManager(true)
 	default:
-		return newSTDOutputManager()
+		return newSTDOutputManager(config)
 	}
 }
 
+// errorGroup collects identical errors reported for the same kind
+// across documents.
+type errorGroup struct {
+	kind        string
+	description string
+	count       int
+	fileNames   []string
+}
+
 // STDOutputManager reports `kubeval` results to stdout.
 type STDOutputManager struct {
+	dedupeErrors bool
+	printer      kLog.Printer
+
+	errorGroups     []*errorGroup
+	errorGroupIndex map[[2]string]*errorGroup
 }
 
 // newSTDOutputManager instantiates a new instance of STDOutputManager.
-func newSTDOutputManager() *STDOutputManager {
-	return &STDOutputManager{}
+func newSTDOutputManager(config *Config) *STDOutputManager {
+	return &STDOutputManager{
+		dedupeErrors:    config.DedupeErrors,
+		printer:         kLog.Printer{NoColor: outputNoColor(config)},
+		errorGroupIndex: make(map[[2]string]*errorGroup),
+	}
 }
 
 func (s *STDOutputManager) Put(result ValidationResult) error {
-	if len(result.Errors) > 0 {
+	fileName := result.FileName
+	if result.Environment != "" {
+		fileName = result.Environment + ": " + fileName
+	}
+	if len(result.Errors) > 0 && s.dedupeErrors {
+		for _, desc := range result.Errors {
+			s.addToErrorGroup(result, desc.String())
+		}
+	} else if len(result.Errors) > 0 {
 		for _, desc := range result.Errors {
-			kLog.Warn(result.FileName, "contains an invalid", result.Kind, fmt.Sprintf("(%s)", result.QualifiedName()), "-", desc.String())
+			s.printer.Warn(fileName, "contains an invalid", result.Kind, fmt.Sprintf("(%s)", result.QualifiedName()), "-", desc.String())
 		}
 	} else if result.Kind == "" {
-		kLog.Success(result.FileName, "contains an empty YAML document")
+		s.printer.Success(fileName, "contains an empty YAML document")
+	} else if result.SchemaError != nil {
+		s.printer.Warn(fileName, "containing a", result.Kind, fmt.Sprintf("(%s)", result.QualifiedName()), "could not be validated as its schema is malformed", "-", result.SchemaError.Error())
 	} else if !result.ValidatedAgainstSchema {
-		kLog.Warn(result.FileName, "containing a", result.Kind, fmt.Sprintf("(%s)", result.QualifiedName()), "was not validated against a schema")
+		s.printer.Warn(fileName, "containing a", result.Kind, fmt.Sprintf("(%s)", result.QualifiedName()), "was not validated against a schema")
+	} else if result.ValidatedAgainstFallback {
+		s.printer.Warn(fileName, "contains a", result.Kind, fmt.Sprintf("(%s)", result.QualifiedName()), "which was only validated against the offline fallback schema")
 	} else {
-		kLog.Success(result.FileName, "contains a valid", result.Kind, fmt.Sprintf("(%s)", result.QualifiedName()))
+		s.printer.Success(fileName, "contains a valid", result.Kind, fmt.Sprintf("(%s)", result.QualifiedName()))
+	}
+	for _, desc := range result.Warnings {
+		s.printer.Warn(fileName, "contains a", result.Kind, fmt.Sprintf("(%s)", result.QualifiedName()), "with a warning", "-", desc.String())
 	}
 
 	return nil
 }
 
+// addToErrorGroup records an error against the group of identical errors
+// for the result's kind, creating the group on first sight.
+func (s *STDOutputManager) addToErrorGroup(result ValidationResult, description string) {
+	key := [2]string{result.Kind, description}
+	group, found := s.errorGroupIndex[key]
+	if !found {
+		group = &errorGroup{
+			kind:        result.Kind,
+			description: description,
+		}
+		s.errorGroupIndex[key] = group
+		s.errorGroups = append(s.errorGroups, group)
+	}
+	group.count++
+	if !in(group.fileNames, result.FileName) {
+		group.fileNames = append(group.fileNames, result.FileName)
+	}
+}
+
 func (s *STDOutputManager) Flush() error {
-	// no op
+	// deduplicated errors are held back until all results are known
+	for _, group := range s.errorGroups {
+		s.printer.Warn(group.kind, "-", group.description, fmt.Sprintf("(%d occurrences in %s)", group.count, strings.Join(group.fileNames, ", ")))
+	}
 	return nil
 }
 
+// outputNoColor returns whether output should be left uncolored: unless
+// Config.ForceColor is set, it is only colored when stdout is a terminal.
+// This is decided for each output manager rather than with the global
+// setting of the color package, so that it only depends on config.
+func outputNoColor(config *Config) bool {
+	if config.ForceColor {
+		return false
+	}
+	fd := os.Stdout.Fd()
+	return os.Getenv("TERM") == "dumb" || !(isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd))
+}
+
+// colored returns s in the color attr, unless noColor is set
+func colored(attr color.Attribute, s string, noColor bool) string {
+	c := color.New(attr)
+	if noColor {
+		c.DisableColor()
+	} else {
+		c.EnableColor()
+	}
+	return c.Sprint(s)
+}
+
 type status string
 
 const (
-	statusInvalid = "invalid"
-	statusValid   = "valid"
-	statusSkipped = "skipped"
+	statusInvalid     = "invalid"
+	statusValid       = "valid"
+	statusSkipped     = "skipped"
+	statusEmpty       = "empty"
+	statusUnvalidated = "unvalidated"
+	statusSchemaError = "schema_error"
 )
 
-type dataEvalResult struct {
-	Filename string   `json:"filename"`
-	Kind     string   `json:"kind"`
-	Status   status   `json:"status"`
-	Errors   []string `json:"errors"`
+// ValidStatuses returns the statuses a ValidationResult may have
+func ValidStatuses() []string {
+	return []string{
+		statusValid,
+		statusInvalid,
+		statusSkipped,
+		statusEmpty,
+		statusUnvalidated,
+		statusSchemaError,
+	}
+}
+
+// DefaultExitOn returns the result statuses which fail the run unless
+// configured otherwise
+func DefaultExitOn() []string {
+	return []string{statusInvalid, statusSchemaError}
+}
+
+// isFailure returns whether results with the status fail the run
+func isFailure(s status, config *Config) bool {
+	for _, exitOn := range config.ExitOn {
+		if string(s) == exitOn {
+			return true
+		}
+	}
+	return false
+}
+
+// HasFailures returns whether any of the results has one of the statuses in
+// ExitOn, and so fails the run
+func HasFailures(results []ValidationResult, config *Config) bool {
+	for _, r := range results {
+		if isFailure(getStatus(r), config) {
+			return true
+		}
+	}
+	return false
+}
+
+// EvalResult is a ValidationResult in the form written by the structured
+// output formats and by Server
+type EvalResult struct {
+	Filename   string `json:"filename"`
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+	// Status is one of ValidStatuses
+	Status string   `json:"status"`
+	Errors []string `json:"errors"`
+	// Fallback is set when the result is from the offline fallback schema
+	Fallback bool `json:"fallback,omitempty"`
+	// Warnings are only included when there are any, as they are only
+	// reported for keywords passed to --warn-on-keyword
+	Warnings []string `json:"warnings,omitempty"`
+	// SchemaError is the reason the schema for the resource could not be
+	// compiled, when its status is schema_error
+	SchemaError string `json:"schemaError,omitempty"`
+	// ErrorDetails locate each of Errors within the document, in the same
+	// order, for tools such as editors which highlight them
+	ErrorDetails []ErrorDetail `json:"errorDetails,omitempty"`
+	// Environment is the environment the document was rendered for, when
+	// one was selected with --env
+	Environment string `json:"environment,omitempty"`
+}
+
+// ErrorDetail is where an error was found within a document
+type ErrorDetail struct {
+	// Type is the JSON schema keyword or check which failed
+	Type string `json:"type"`
+	// Field is the dotted path of the value, as shown in the message
+	Field string `json:"field"`
+	// Pointer is a JSON pointer (RFC 6901) to the value
+	Pointer string `json:"pointer"`
+}
+
+// newEvalResult converts a ValidationResult into the structure shared
+// by the structured output formats
+func newEvalResult(r ValidationResult) EvalResult {
+	// stringify gojsonschema errors
+	// use a pre-allocated slice to ensure the json will have an
+	// empty array in the "zero" case
+	errs := make([]string, 0, len(r.Errors))
+	var details []ErrorDetail
+	for _, e := range r.Errors {
+		errs = append(errs, e.String())
+		details = append(details, ErrorDetail{Type: e.Type(), Field: e.Field(), Pointer: JSONPointer(e)})
+	}
+	var warnings []string
+	for _, w := range r.Warnings {
+		warnings = append(warnings, w.String())
+	}
+
+	result := EvalResult{
+		Filename:     r.FileName,
+		Kind:         r.Kind,
+		APIVersion:   r.APIVersion,
+		Name:         r.ResourceName,
+		Namespace:    r.ResourceNamespace,
+		Status:       string(getStatus(r)),
+		Errors:       errs,
+		ErrorDetails: details,
+		Fallback:     r.ValidatedAgainstFallback,
+		Warnings:     warnings,
+		Environment:  r.Environment,
+	}
+	if r.SchemaError != nil {
+		result.SchemaError = r.SchemaError.Error()
+	}
+	return result
+}
+
+// jsonReport is the document written by the json output from report
+// format version 2 onwards
+type jsonReport struct {
+	ReportVersion int         `json:"reportVersion"`
+	Results       interface{} `json:"results"`
+}
+
+// jsonFile holds the results for a single file in the grouped json shape
+type jsonFile struct {
+	Documents []EvalResult `json:"documents"`
 }
 
 // jsonOutputManager reports `ccheck` results to `stdout` as a json array..
 type jsonOutputManager struct {
 	logger *log.Logger
 
-	data []dataEvalResult
+	reportFormatVersion int
+	shape               string
+
+	data []EvalResult
 }
 
-func newDefaultJSONOutputManager() *jsonOutputManager {
-	return newJSONOutputManager(log.New(os.Stdout, "", 0))
+func newDefaultJSONOutputManager(config *Config) *jsonOutputManager {
+	return newJSONOutputManager(log.New(os.Stdout, "", 0), config)
 }
 
-func newJSONOutputManager(l *log.Logger) *jsonOutputManager {
+func newJSONOutputManager(l *log.Logger, config *Config) *jsonOutputManager {
 	return &jsonOutputManager{
-		logger: l,
+		logger:              l,
+		reportFormatVersion: config.ReportFormatVersion,
+		shape:               config.JSONShape,
 	}
 }
 
 func getStatus(r ValidationResult) status {
 	if r.Kind == "" {
-		return statusSkipped
+		return statusEmpty
 	}
 
-	if !r.ValidatedAgainstSchema {
+	if r.Skipped {
 		return statusSkipped
 	}
 
+	if r.SchemaError != nil {
+		return statusSchemaError
+	}
+
 	if len(r.Errors) > 0 {
 		return statusInvalid
 	}
 
+	if !r.ValidatedAgainstSchema {
+		return statusUnvalidated
+	}
+
 	return statusValid
 }
 
 func (j *jsonOutputManager) Put(r ValidationResult) error {
-	// stringify gojsonschema errors
-	// use a pre-allocated slice to ensure the json will have an
-	// empty array in the "zero" case
-	errs := make([]string, 0, len(r.Errors))
-	for _, e := range r.Errors {
-		errs = append(errs, e.String())
-	}
-
-	j.data = append(j.data, dataEvalResult{
-		Filename: r.FileName,
-		Kind:     r.Kind,
-		Status:   getStatus(r),
-		Errors:   errs,
-	})
-
+	j.data = append(j.data, newEvalResult(r))
 	return nil
 }
 
 func (j *jsonOutputManager) Flush() error {
-	b, err := json.Marshal(j.data)
+	var report interface{} = j.data
+	if j.shape == JSONShapeGrouped {
+		report = j.groupByFile()
+	}
+	if j.reportFormatVersion >= ReportFormatVersion2 {
+		// use an empty array rather than null when there are no results
+		var results interface{} = append(make([]EvalResult, 0, len(j.data)), j.data...)
+		if j.shape == JSONShapeGrouped {
+			results = report
+		}
+		report = jsonReport{
+			ReportVersion: j.reportFormatVersion,
+			Results:       results,
+		}
+	}
+
+	b, err := json.Marshal(report)
 	if err != nil {
 		return err
 	}
@@ -162,11 +409,25 @@ func (j *jsonOutputManager) Flush() error {
 	return nil
 }
 
+// groupByFile returns the results keyed by filename, for the grouped json shape
+func (j *jsonOutputManager) groupByFile() map[string]*jsonFile {
+	files := make(map[string]*jsonFile)
+	for _, r := range j.data {
+		file, found := files[r.Filename]
+		if !found {
+			file = &jsonFile{}
+			files[r.Filename] = file
+		}
+		file.Documents = append(file.Documents, r)
+	}
+	return files
+}
+
 // tapOutputManager reports `conftest` results to stdout.
 type tapOutputManager struct {
 	logger *log.Logger
 
-	data []dataEvalResult
+	data []EvalResult
 }
 
 // newDefaultTapOutManager instantiates a new instance of tapOutputManager
@@ -184,18 +445,7 @@ func newTAPOutputManager(l *log.Logger) *tapOutputManager {
 }
 
 func (j *tapOutputManager) Put(r ValidationResult) error {
-	errs := make([]string, 0, len(r.Errors))
-	for _, e := range r.Errors {
-		errs = append(errs, e.String())
-	}
-
-	j.data = append(j.data, dataEvalResult{
-		Filename: r.FileName,
-		Kind:     r.Kind,
-		Status:   getStatus(r),
-		Errors:   errs,
-	})
-
+	j.data = append(j.data, newEvalResult(r))
 	return nil
 }
 
@@ -220,17 +470,404 @@ func (j *tapOutputManager) Flush() error {
 			} else {
 				kindMarker = fmt.Sprintf(" (%s)", r.Kind)
 			}
-			if r.Status == "valid" {
+			if r.Status == statusValid && r.Fallback {
+				j.logger.Print("ok ", count, " - ", r.Filename, kindMarker, " # validated against the offline fallback schema only")
+			} else if r.Status == statusValid {
 				j.logger.Print("ok ", count, " - ", r.Filename, kindMarker)
-			} else if r.Status == "skipped" {
-				j.logger.Print("ok ", count, " #skip - ", r.Filename, kindMarker)
-			} else if r.Status == "invalid" {
+			} else if r.Status == statusSchemaError {
+				j.logger.Print("not ok ", count, " - ", r.Filename, kindMarker, " - ", r.SchemaError)
+			} else if r.Status == statusInvalid {
 				for _, e := range r.Errors {
 					j.logger.Print("not ok ", count, " - ", r.Filename, kindMarker, " - ", e)
 					count = count + 1
 				}
+			} else {
+				j.logger.Print("ok ", count, " #skip - ", r.Filename, kindMarker)
+			}
+			for _, w := range r.Warnings {
+				j.logger.Print("# warning: ", w)
 			}
 		}
 	}
 	return nil
 }
+
+// prettyOutputManager reports results as a tree of directories, files and
+// the documents within them, collapsing directories in which every document
+// is valid into a single line.
+type prettyOutputManager struct {
+	logger  *log.Logger
+	noColor bool
+
+	data []EvalResult
+}
+
+// newDefaultPrettyOutputManager instantiates a new instance of
+// prettyOutputManager using the default logger.
+func newDefaultPrettyOutputManager(config *Config) *prettyOutputManager {
+	return newPrettyOutputManager(log.New(os.Stdout, "", 0), outputNoColor(config))
+}
+
+// newPrettyOutputManager constructs an instance of prettyOutputManager
+// given a logger instance, and whether color is disabled.
+func newPrettyOutputManager(l *log.Logger, noColor bool) *prettyOutputManager {
+	return &prettyOutputManager{
+		logger:  l,
+		noColor: noColor,
+	}
+}
+
+func (p *prettyOutputManager) Put(r ValidationResult) error {
+	p.data = append(p.data, newEvalResult(r))
+	return nil
+}
+
+// prettyStyle is the set of glyphs used to draw the tree. Unicode glyphs
+// are used along with color, and ASCII ones when color is disabled, as
+// that is usually because the output is not a terminal.
+type prettyStyle struct {
+	branch, lastBranch, pipe, space  string
+	valid, invalid, warning, skipped string
+	noColor                          bool
+}
+
+var (
+	prettyUnicodeStyle = prettyStyle{
+		branch: "├── ", lastBranch: "└── ", pipe: "│   ", space: "    ",
+		valid: "✓", invalid: "✗", warning: "!", skipped: "-",
+	}
+	prettyASCIIStyle = prettyStyle{
+		branch: "|-- ", lastBranch: "`-- ", pipe: "|   ", space: "    ",
+		valid: "+", invalid: "x", warning: "!", skipped: "-",
+	}
+)
+
+// prettyDirectory holds the files within a directory, in the order they
+// were first seen
+type prettyDirectory struct {
+	name  string
+	files []*prettyFile
+}
+
+// prettyFile holds the documents within a file
+type prettyFile struct {
+	name      string
+	documents []EvalResult
+}
+
+func (p *prettyOutputManager) Flush() error {
+	style := prettyUnicodeStyle
+	if p.noColor {
+		style = prettyASCIIStyle
+	}
+	style.noColor = p.noColor
+
+	for _, dir := range p.groupByDirectory() {
+		documents := 0
+		valid := true
+		for _, file := range dir.files {
+			documents += len(file.documents)
+			valid = valid && prettyAllValid(file.documents)
+		}
+		if valid {
+			p.logger.Print(style.glyph(statusValid), " ", dir.name, fmt.Sprintf(" (%d files, %d documents valid)", len(dir.files), documents))
+			continue
+		}
+
+		p.logger.Print(dir.name)
+		for i, file := range dir.files {
+			branch, indent := style.branch, style.pipe
+			if i == len(dir.files)-1 {
+				branch, indent = style.lastBranch, style.space
+			}
+			p.logger.Print(branch, style.glyph(prettyFileStatus(file.documents)), " ", file.name)
+			for j, document := range file.documents {
+				docBranch, docIndent := style.branch, style.pipe
+				if j == len(file.documents)-1 {
+					docBranch, docIndent = style.lastBranch, style.space
+				}
+				p.logger.Print(indent, docBranch, style.glyph(prettyDocumentStatus(document)), " ", prettyDocumentLabel(document))
+				messages := append([]string{}, document.Errors...)
+				if document.SchemaError != "" {
+					messages = append(messages, document.SchemaError)
+				}
+				for _, w := range document.Warnings {
+					messages = append(messages, "warning: "+w)
+				}
+				for k, message := range messages {
+					messageBranch := style.branch
+					if k == len(messages)-1 {
+						messageBranch = style.lastBranch
+					}
+					p.logger.Print(indent, docIndent, messageBranch, message)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// groupByDirectory groups the results by directory and then by file
+func (p *prettyOutputManager) groupByDirectory() []*prettyDirectory {
+	var dirs []*prettyDirectory
+	dirIndex := make(map[string]*prettyDirectory)
+	fileIndex := make(map[string]*prettyFile)
+	for _, r := range p.data {
+		dirName := filepath.ToSlash(filepath.Dir(r.Filename)) + "/"
+		dir, found := dirIndex[dirName]
+		if !found {
+			dir = &prettyDirectory{name: dirName}
+			dirIndex[dirName] = dir
+			dirs = append(dirs, dir)
+		}
+		file, found := fileIndex[r.Filename]
+		if !found {
+			file = &prettyFile{name: filepath.Base(r.Filename)}
+			fileIndex[r.Filename] = file
+			dir.files = append(dir.files, file)
+		}
+		file.documents = append(file.documents, r)
+	}
+	return dirs
+}
+
+// glyph returns the colored glyph for a status
+func (s prettyStyle) glyph(st status) string {
+	switch st {
+	case statusValid, statusEmpty:
+		return colored(color.FgGreen, s.valid, s.noColor)
+	case statusInvalid:
+		return colored(color.FgRed, s.invalid, s.noColor)
+	case statusUnvalidated:
+		return colored(color.FgYellow, s.warning, s.noColor)
+	default:
+		return s.skipped
+	}
+}
+
+// prettyDocumentStatus returns the status shown for a document, which
+// treats a result from the offline fallback schema, or with warnings, as a
+// warning, and a malformed schema as a failure
+func prettyDocumentStatus(r EvalResult) status {
+	if r.Status == statusSchemaError {
+		return statusInvalid
+	}
+	if r.Status == statusValid && (r.Fallback || len(r.Warnings) > 0) {
+		return statusUnvalidated
+	}
+	return status(r.Status)
+}
+
+// prettyFileStatus returns the most severe status of the documents in a
+// file: invalid, then unvalidated, then valid
+func prettyFileStatus(documents []EvalResult) status {
+	result := status(statusSkipped)
+	for _, r := range documents {
+		switch prettyDocumentStatus(r) {
+		case statusInvalid:
+			return statusInvalid
+		case statusUnvalidated:
+			result = statusUnvalidated
+		case statusValid, statusEmpty:
+			if result == statusSkipped {
+				result = statusValid
+			}
+		}
+	}
+	return result
+}
+
+// prettyAllValid returns whether every document is valid or empty
+func prettyAllValid(documents []EvalResult) bool {
+	for _, r := range documents {
+		if st := prettyDocumentStatus(r); st != statusValid && st != statusEmpty {
+			return false
+		}
+	}
+	return true
+}
+
+// prettyDocumentLabel describes a document in the tree
+func prettyDocumentLabel(r EvalResult) string {
+	if r.Status == statusEmpty {
+		return "empty document"
+	}
+	name := r.Name
+	if name == "" {
+		name = "unknown"
+	} else if r.Namespace != "" {
+		name = r.Namespace + "." + name
+	}
+	label := fmt.Sprintf("%s %s", r.Kind, name)
+	switch {
+	case r.Status == statusSkipped:
+		label += " (skipped)"
+	case r.Status == statusUnvalidated:
+		label += " (not validated against a schema)"
+	case r.Status == statusSchemaError:
+		label += " (schema is malformed)"
+	case r.Fallback:
+		label += " (offline fallback schema only)"
+	}
+	return label
+}
+
+// markdownOutputManager reports results as a markdown summary suited to
+// posting as a pull request comment: an overall pass or fail, a table of
+// the status of each file, and the errors in each failing file in a
+// collapsible section. The run fails when a document has one of the
+// statuses in ExitOn, as the exit code does.
+type markdownOutputManager struct {
+	logger *log.Logger
+	config *Config
+
+	data []EvalResult
+}
+
+// newDefaultMarkdownOutputManager instantiates a new instance of
+// markdownOutputManager using the default logger.
+func newDefaultMarkdownOutputManager(config *Config) *markdownOutputManager {
+	return newMarkdownOutputManager(log.New(os.Stdout, "", 0), config)
+}
+
+// newMarkdownOutputManager constructs an instance of markdownOutputManager
+// given a logger instance, and the config deciding which statuses fail.
+func newMarkdownOutputManager(l *log.Logger, config *Config) *markdownOutputManager {
+	return &markdownOutputManager{
+		logger: l,
+		config: config,
+	}
+}
+
+func (m *markdownOutputManager) Put(r ValidationResult) error {
+	m.data = append(m.data, newEvalResult(r))
+	return nil
+}
+
+func (m *markdownOutputManager) Flush() error {
+	var files []*prettyFile
+	fileIndex := make(map[string]*prettyFile)
+	for _, r := range m.data {
+		file, found := fileIndex[r.Filename]
+		if !found {
+			file = &prettyFile{name: r.Filename}
+			fileIndex[r.Filename] = file
+			files = append(files, file)
+		}
+		file.documents = append(file.documents, r)
+	}
+
+	if len(m.data) == 0 {
+		m.logger.Print("## :white_check_mark: kubeval passed\n\nNo documents were found to validate.")
+		return nil
+	}
+	if prettyAllValid(m.data) {
+		m.logger.Printf("## :white_check_mark: kubeval passed\n\nAll documents are valid: %s in %s.", markdownCount(len(m.data), "document"), markdownCount(len(files), "file"))
+		return nil
+	}
+
+	failed := false
+	for _, r := range m.data {
+		failed = failed || isFailure(status(r.Status), m.config)
+	}
+	if failed {
+		m.logger.Print("## :x: kubeval failed\n\n")
+	} else {
+		m.logger.Print("## :warning: kubeval passed with warnings\n\n")
+	}
+	m.logger.Printf("%s in %s.\n\n", markdownStatusCounts(m.data), markdownCount(len(files), "file"))
+
+	m.logger.Print("| | File | Documents |")
+	m.logger.Print("|---|---|---|")
+	for _, file := range files {
+		m.logger.Printf("| %s | `%s` | %s |", markdownGlyph(prettyFileStatus(file.documents)), strings.Replace(file.name, "|", "\\|", -1), markdownStatusCounts(file.documents))
+	}
+
+	for _, file := range files {
+		var details []string
+		for _, document := range file.documents {
+			messages := append([]string{}, document.Errors...)
+			if document.SchemaError != "" {
+				messages = append(messages, document.SchemaError)
+			}
+			for _, w := range document.Warnings {
+				messages = append(messages, "warning: "+w)
+			}
+			if len(messages) == 0 && prettyDocumentStatus(document) != statusUnvalidated {
+				continue
+			}
+			label := "**" + markdownEscape(prettyDocumentLabel(document)) + "**"
+			if len(messages) == 0 {
+				details = append(details, "- "+label)
+			}
+			for _, message := range messages {
+				details = append(details, "- "+label+": "+markdownEscape(message))
+			}
+		}
+		if len(details) == 0 {
+			continue
+		}
+		m.logger.Printf("\n<details>\n<summary>%s <code>%s</code></summary>\n\n", markdownGlyph(prettyFileStatus(file.documents)), html.EscapeString(file.name))
+		m.logger.Print(strings.Join(details, "\n"))
+		m.logger.Print("\n</details>")
+	}
+	return nil
+}
+
+// markdownGlyph returns the emoji shortcode shown for a status
+func markdownGlyph(st status) string {
+	switch st {
+	case statusValid, statusEmpty:
+		return ":white_check_mark:"
+	case statusInvalid:
+		return ":x:"
+	case statusUnvalidated:
+		return ":warning:"
+	default:
+		return ":heavy_minus_sign:"
+	}
+}
+
+// markdownStatusCounts describes the number of documents with each status,
+// such as "1 invalid, 2 valid documents"
+func markdownStatusCounts(documents []EvalResult) string {
+	counts := make(map[string]int)
+	for _, r := range documents {
+		counts[string(r.Status)]++
+	}
+	var parts []string
+	for _, st := range ValidStatuses() {
+		if counts[st] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[st], st))
+		}
+	}
+	noun := "documents"
+	if len(documents) == 1 {
+		noun = "document"
+	}
+	return strings.Join(parts, ", ") + " " + noun
+}
+
+// markdownCount describes a number of things, such as "1 file" or "2 files"
+func markdownCount(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// markdownEscaper escapes text so that it is shown as is in a table cell
+// or list item, rather than being taken as markdown or HTML
+var markdownEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	"|", "\\|",
+	"*", "\\*",
+	"`", "\\`",
+)
+
+// markdownEscape escapes text for use in markdown
+func markdownEscape(text string) string {
+	return markdownEscaper.Replace(text)
+}
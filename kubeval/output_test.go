@@ -2,7 +2,9 @@ package kubeval
 
 import (
 	"bytes"
+	"errors"
 	"log"
+	"strings"
 	"testing"
 
 	"github.com/xeipuuv/gojsonschema"
@@ -47,7 +49,10 @@ func Test_jsonOutputManager_put(t *testing.T) {
 	{
 		"filename": "",
 		"kind": "",
-		"status": "skipped",
+		"apiVersion": "",
+		"name": "",
+		"namespace": "",
+		"status": "empty",
 		"errors": []
 	}
 ]
@@ -67,10 +72,61 @@ func Test_jsonOutputManager_put(t *testing.T) {
 	{
 		"filename": "deployment.yaml",
 		"kind": "deployment",
+		"apiVersion": "",
+		"name": "",
+		"namespace": "",
 		"status": "valid",
 		"errors": []
 	}
 ]
+`,
+		},
+		{
+			msg: "skipped file",
+			args: args{
+				vr: ValidationResult{
+					FileName:          "secret.yaml",
+					Kind:              "SealedSecret",
+					APIVersion:        "bitnami.com/v1alpha1",
+					ResourceName:      "test-secret",
+					ResourceNamespace: "test-namespace",
+					Skipped:           true,
+				},
+			},
+			exp: `[
+	{
+		"filename": "secret.yaml",
+		"kind": "SealedSecret",
+		"apiVersion": "bitnami.com/v1alpha1",
+		"name": "test-secret",
+		"namespace": "test-namespace",
+		"status": "skipped",
+		"errors": []
+	}
+]
+`,
+		},
+		{
+			msg: "file not validated against a schema",
+			args: args{
+				vr: ValidationResult{
+					FileName:     "crd.yaml",
+					Kind:         "SealedSecret",
+					APIVersion:   "bitnami.com/v1alpha1",
+					ResourceName: "test-secret",
+				},
+			},
+			exp: `[
+	{
+		"filename": "crd.yaml",
+		"kind": "SealedSecret",
+		"apiVersion": "bitnami.com/v1alpha1",
+		"name": "test-secret",
+		"namespace": "",
+		"status": "unvalidated",
+		"errors": []
+	}
+]
 `,
 		},
 		{
@@ -90,10 +146,25 @@ func Test_jsonOutputManager_put(t *testing.T) {
 	{
 		"filename": "service.yaml",
 		"kind": "service",
+		"apiVersion": "",
+		"name": "",
+		"namespace": "",
 		"status": "invalid",
 		"errors": [
 			"error: i am a error",
 			"error: i am another error"
+		],
+		"errorDetails": [
+			{
+				"type": "",
+				"field": "error",
+				"pointer": ""
+			},
+			{
+				"type": "",
+				"field": "error",
+				"pointer": ""
+			}
 		]
 	}
 ]
@@ -103,7 +174,7 @@ func Test_jsonOutputManager_put(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.msg, func(t *testing.T) {
 			buf := new(bytes.Buffer)
-			s := newJSONOutputManager(log.New(buf, "", 0))
+			s := newJSONOutputManager(log.New(buf, "", 0), NewDefaultConfig())
 
 			// record results
 			err := s.Put(tt.args.vr)
@@ -187,3 +258,323 @@ not ok 2 - service.yaml (Service) - error: i am another error
 		})
 	}
 }
+
+func Test_stdOutputManager_dedupeErrors(t *testing.T) {
+	config := NewDefaultConfig()
+	config.DedupeErrors = true
+	s := newSTDOutputManager(config)
+
+	results := []ValidationResult{
+		{
+			FileName:               "a.yaml",
+			Kind:                   "Service",
+			ValidatedAgainstSchema: true,
+			Errors:                 newResultErrors([]string{"i am a error", "i am another error"}),
+		},
+		{
+			FileName:               "b.yaml",
+			Kind:                   "Service",
+			ValidatedAgainstSchema: true,
+			Errors:                 newResultErrors([]string{"i am a error", "i am a error"}),
+		},
+		{
+			FileName:               "c.yaml",
+			Kind:                   "Deployment",
+			ValidatedAgainstSchema: true,
+			Errors:                 newResultErrors([]string{"i am a error"}),
+		},
+	}
+	for _, r := range results {
+		assert.NoError(t, s.Put(r))
+	}
+
+	assert.Len(t, s.errorGroups, 3)
+	assert.Equal(t, &errorGroup{
+		kind:        "Service",
+		description: "error: i am a error",
+		count:       3,
+		fileNames:   []string{"a.yaml", "b.yaml"},
+	}, s.errorGroups[0])
+	assert.Equal(t, 1, s.errorGroups[1].count)
+	assert.Equal(t, "Deployment", s.errorGroups[2].kind)
+}
+
+func Test_getStatus(t *testing.T) {
+	tests := []struct {
+		msg string
+		vr  ValidationResult
+		exp string
+	}{
+		{msg: "empty", vr: ValidationResult{}, exp: "empty"},
+		{msg: "skipped", vr: ValidationResult{Kind: "Pod", Skipped: true}, exp: "skipped"},
+		{msg: "unvalidated", vr: ValidationResult{Kind: "Pod"}, exp: "unvalidated"},
+		{msg: "valid", vr: ValidationResult{Kind: "Pod", ValidatedAgainstSchema: true}, exp: "valid"},
+		{msg: "invalid", vr: ValidationResult{Kind: "Pod", ValidatedAgainstSchema: true, Errors: newResultErrors([]string{"i am a error"})}, exp: "invalid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			assert.Equal(t, tt.exp, tt.vr.Status())
+			assert.Contains(t, ValidStatuses(), tt.exp)
+		})
+	}
+}
+
+func Test_jsonOutputManager_reportFormatVersion(t *testing.T) {
+	config := NewDefaultConfig()
+	config.ReportFormatVersion = ReportFormatVersion2
+
+	buf := new(bytes.Buffer)
+	s := newJSONOutputManager(log.New(buf, "", 0), config)
+	assert.NoError(t, s.Put(ValidationResult{
+		FileName:               "deployment.yaml",
+		Kind:                   "Deployment",
+		APIVersion:             "apps/v1",
+		ResourceName:           "nginx",
+		ValidatedAgainstSchema: true,
+	}))
+	assert.NoError(t, s.Flush())
+
+	assert.Equal(t, `{
+	"reportVersion": 2,
+	"results": [
+		{
+			"filename": "deployment.yaml",
+			"kind": "Deployment",
+			"apiVersion": "apps/v1",
+			"name": "nginx",
+			"namespace": "",
+			"status": "valid",
+			"errors": []
+		}
+	]
+}
+`, buf.String())
+
+	buf.Reset()
+	s = newJSONOutputManager(log.New(buf, "", 0), config)
+	assert.NoError(t, s.Flush())
+	assert.Equal(t, `{
+	"reportVersion": 2,
+	"results": []
+}
+`, buf.String())
+}
+
+func Test_jsonOutputManager_groupedShape(t *testing.T) {
+	config := NewDefaultConfig()
+	config.JSONShape = JSONShapeGrouped
+
+	results := []ValidationResult{
+		{
+			FileName:               "app.yaml",
+			Kind:                   "Deployment",
+			APIVersion:             "apps/v1",
+			ResourceName:           "web",
+			ValidatedAgainstSchema: true,
+		},
+		{
+			FileName:               "app.yaml",
+			Kind:                   "Service",
+			APIVersion:             "v1",
+			ResourceName:           "web",
+			ValidatedAgainstSchema: true,
+		},
+		{
+			FileName: "empty.yaml",
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	s := newJSONOutputManager(log.New(buf, "", 0), config)
+	for _, r := range results {
+		assert.NoError(t, s.Put(r))
+	}
+	assert.NoError(t, s.Flush())
+
+	assert.Equal(t, `{
+	"app.yaml": {
+		"documents": [
+			{
+				"filename": "app.yaml",
+				"kind": "Deployment",
+				"apiVersion": "apps/v1",
+				"name": "web",
+				"namespace": "",
+				"status": "valid",
+				"errors": []
+			},
+			{
+				"filename": "app.yaml",
+				"kind": "Service",
+				"apiVersion": "v1",
+				"name": "web",
+				"namespace": "",
+				"status": "valid",
+				"errors": []
+			}
+		]
+	},
+	"empty.yaml": {
+		"documents": [
+			{
+				"filename": "empty.yaml",
+				"kind": "",
+				"apiVersion": "",
+				"name": "",
+				"namespace": "",
+				"status": "empty",
+				"errors": []
+			}
+		]
+	}
+}
+`, buf.String())
+
+	config.ReportFormatVersion = ReportFormatVersion2
+	buf.Reset()
+	s = newJSONOutputManager(log.New(buf, "", 0), config)
+	assert.NoError(t, s.Flush())
+	assert.Equal(t, `{
+	"reportVersion": 2,
+	"results": {}
+}
+`, buf.String())
+}
+
+func Test_prettyOutputManager(t *testing.T) {
+	results := []ValidationResult{
+		{FileName: "base/deployment.yaml", Kind: "Deployment", ResourceName: "web", ValidatedAgainstSchema: true},
+		{FileName: "base/service.yaml", Kind: "Service", ResourceName: "web", ValidatedAgainstSchema: true},
+		{FileName: "overlays/prod/deployment.yaml", Kind: "Deployment", ResourceName: "web", ResourceNamespace: "prod", ValidatedAgainstSchema: true},
+		{FileName: "overlays/prod/deployment.yaml", Kind: "Service", ResourceName: "api", ValidatedAgainstSchema: true, Errors: newResultErrors([]string{"port is required", "selector is required"})},
+		{FileName: "overlays/prod/widget.yaml", Kind: "Widget", ResourceName: "thing"},
+		{FileName: "overlays/prod/widget.yaml"},
+		{FileName: "overlays/prod/secret.yaml", Kind: "Secret", ResourceName: "credentials", Skipped: true},
+	}
+
+	buf := new(bytes.Buffer)
+	s := newPrettyOutputManager(log.New(buf, "", 0), true)
+	for _, r := range results {
+		assert.NoError(t, s.Put(r))
+	}
+	assert.NoError(t, s.Flush())
+	assert.Equal(t, `+ base/ (2 files, 2 documents valid)
+overlays/prod/
+|-- x deployment.yaml
+|   |-- + Deployment prod.web
+|   `+"`"+`-- x Service api
+|       |-- error: port is required
+|       `+"`"+`-- error: selector is required
+|-- ! widget.yaml
+|   |-- ! Widget thing (not validated against a schema)
+|   `+"`"+`-- + empty document
+`+"`"+`-- - secret.yaml
+    `+"`"+`-- - Secret credentials (skipped)
+`, buf.String())
+}
+
+func Test_prettyOutputManagerColor(t *testing.T) {
+	buf := new(bytes.Buffer)
+	s := newPrettyOutputManager(log.New(buf, "", 0), false)
+	assert.NoError(t, s.Put(ValidationResult{FileName: "base/deployment.yaml", Kind: "Deployment", ResourceName: "web", ValidatedAgainstSchema: true}))
+	assert.NoError(t, s.Flush())
+	assert.Equal(t, "\x1b[32m✓\x1b[0m base/ (1 files, 1 documents valid)\n", buf.String())
+}
+
+func Test_markdownOutputManager(t *testing.T) {
+	results := []ValidationResult{
+		{FileName: "base/deployment.yaml", Kind: "Deployment", ResourceName: "web", ValidatedAgainstSchema: true},
+		{FileName: "overlays/prod/deployment.yaml", Kind: "Service", ResourceName: "api", ValidatedAgainstSchema: true, Errors: newResultErrors([]string{"image must be pinned, use image@sha256:<digest>"})},
+		{FileName: "overlays/prod/widget.yaml", Kind: "Widget", ResourceName: "thing"},
+		{FileName: "overlays/prod/secret.yaml", Kind: "Secret", ResourceName: "credentials", Skipped: true},
+	}
+
+	buf := new(bytes.Buffer)
+	s := newMarkdownOutputManager(log.New(buf, "", 0), NewDefaultConfig())
+	for _, r := range results {
+		assert.NoError(t, s.Put(r))
+	}
+	assert.NoError(t, s.Flush())
+	assert.Equal(t, "## :x: kubeval failed\n"+
+		"\n"+
+		"1 valid, 1 invalid, 1 skipped, 1 unvalidated documents in 4 files.\n"+
+		"\n"+
+		"| | File | Documents |\n"+
+		"|---|---|---|\n"+
+		"| :white_check_mark: | `base/deployment.yaml` | 1 valid document |\n"+
+		"| :x: | `overlays/prod/deployment.yaml` | 1 invalid document |\n"+
+		"| :warning: | `overlays/prod/widget.yaml` | 1 unvalidated document |\n"+
+		"| :heavy_minus_sign: | `overlays/prod/secret.yaml` | 1 skipped document |\n"+
+		"\n"+
+		"<details>\n"+
+		"<summary>:x: <code>overlays/prod/deployment.yaml</code></summary>\n"+
+		"\n"+
+		"- **Service api**: error: image must be pinned, use image@sha256:&lt;digest&gt;\n"+
+		"\n"+
+		"</details>\n"+
+		"\n"+
+		"<details>\n"+
+		"<summary>:warning: <code>overlays/prod/widget.yaml</code></summary>\n"+
+		"\n"+
+		"- **Widget thing (not validated against a schema)**\n"+
+		"\n"+
+		"</details>\n", buf.String())
+
+	// Runs in which everything is valid get a concise message
+	buf.Reset()
+	s = newMarkdownOutputManager(log.New(buf, "", 0), NewDefaultConfig())
+	assert.NoError(t, s.Put(results[0]))
+	assert.NoError(t, s.Flush())
+	assert.Equal(t, "## :white_check_mark: kubeval passed\n\nAll documents are valid: 1 document in 1 file.\n", buf.String())
+
+	// The run fails on the statuses which give a non-zero exit code
+	config := NewDefaultConfig()
+	config.ExitOn = []string{"unvalidated"}
+	buf.Reset()
+	s = newMarkdownOutputManager(log.New(buf, "", 0), config)
+	assert.NoError(t, s.Put(results[2]))
+	assert.NoError(t, s.Flush())
+	assert.True(t, strings.HasPrefix(buf.String(), "## :x: kubeval failed\n"), buf.String())
+
+	buf.Reset()
+	s = newMarkdownOutputManager(log.New(buf, "", 0), config)
+	assert.NoError(t, s.Put(results[1]))
+	assert.NoError(t, s.Flush())
+	assert.True(t, strings.HasPrefix(buf.String(), "## :warning: kubeval passed with warnings\n"), buf.String())
+}
+
+func Test_junitOutputManager(t *testing.T) {
+	results := []ValidationResult{
+		{FileName: "web.yaml", Kind: "Deployment", ResourceName: "web", ResourceNamespace: "prod", ValidatedAgainstSchema: true, Warnings: newResultErrors([]string{"image uses the latest tag"})},
+		{FileName: "web.yaml", Kind: "Service", ResourceName: "web", ValidatedAgainstSchema: true, Errors: newResultErrors([]string{"port is < 1", "port is required"})},
+		{FileName: "crd.yaml", Kind: "Widget", ResourceName: "thing", SchemaError: errors.New("invalid regular expression")},
+		{FileName: "crd.yaml", Kind: "Gadget", ResourceName: "thing"},
+	}
+
+	buf := new(bytes.Buffer)
+	s := newJUnitOutputManager(log.New(buf, "", 0))
+	for _, r := range results {
+		assert.NoError(t, s.Put(r))
+	}
+	assert.NoError(t, s.Flush())
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="kubeval" tests="4" failures="1" errors="1" skipped="1">
+  <testsuite name="web.yaml" tests="2" failures="1" errors="0" skipped="0">
+    <testcase name="Deployment (prod.web)" classname="web.yaml">
+      <system-out>error: image uses the latest tag</system-out>
+    </testcase>
+    <testcase name="Service (web)" classname="web.yaml">
+      <failure message="Service (web) is invalid">error: port is &lt; 1&#xA;error: port is required</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="crd.yaml" tests="2" failures="0" errors="1" skipped="1">
+    <testcase name="Widget (thing)" classname="crd.yaml">
+      <error message="The schema could not be compiled">invalid regular expression</error>
+    </testcase>
+    <testcase name="Gadget (thing)" classname="crd.yaml">
+      <skipped message="Not validated against a schema"></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, buf.String())
+}
@@ -0,0 +1,24 @@
+package kubeval
+
+// parseConfigFiles reads and parses the files config refers to, such as
+// SchemaChecksums, keeping their contents in config, so that each is read
+// once for the run rather than for every schema. config must be the run's
+// own copy, see validateWithStore, which the Config of each document is
+// then copied from.
+func parseConfigFiles(config *Config) error {
+	if config.SchemaChecksums != "" && config.parsedSchemaChecksums == nil {
+		checksums, err := readSchemaChecksums(config.SchemaChecksums)
+		if err != nil {
+			return err
+		}
+		config.parsedSchemaChecksums = checksums
+	}
+	if config.SchemaMap != "" && config.parsedSchemaMap == nil {
+		schemaMap, err := readSchemaMap(config.SchemaMap)
+		if err != nil {
+			return err
+		}
+		config.parsedSchemaMap = schemaMap
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package kubeval
+
+import "strconv"
+
+// podTemplatePaths are the paths of the pod template within each kind of
+// workload which embeds one. The template holds the metadata and spec of
+// the pods created. A Pod is its own template.
+var podTemplatePaths = map[string][]string{
+	"Pod":                   {},
+	"PodTemplate":           {"template"},
+	"ReplicationController": {"spec", "template"},
+	"ReplicaSet":            {"spec", "template"},
+	"Deployment":            {"spec", "template"},
+	"DaemonSet":             {"spec", "template"},
+	"StatefulSet":           {"spec", "template"},
+	"Job":                   {"spec", "template"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template"},
+}
+
+// podTemplate is a pod template found within a resource
+type podTemplate struct {
+	// metadataPath and specPath are the paths of the metadata and spec of
+	// the template within the resource
+	metadataPath []string
+	specPath     []string
+
+	metadata map[string]interface{}
+	spec     map[string]interface{}
+}
+
+// podTemplates returns the pod templates within a resource, so that checks
+// of pods apply in the same way to every kind of workload. Kinds which are
+// not known to embed a pod template, such as custom resources, are taken to
+// have one at spec.template if that holds a spec with containers.
+func podTemplates(body map[string]interface{}) []podTemplate {
+	kind, _ := body["kind"].(string)
+	path, known := podTemplatePaths[kind]
+	if !known {
+		path = []string{"spec", "template"}
+	}
+
+	var template map[string]interface{}
+	if len(path) == 0 {
+		template = body
+	} else if typed, ok := lookupPath(body, path).(map[string]interface{}); ok {
+		template = typed
+	}
+	spec, _ := getObject(template, "spec")
+	if spec == nil {
+		return nil
+	}
+	if _, ok := spec["containers"].([]interface{}); !known && !ok {
+		return nil
+	}
+	metadata, _ := getObject(template, "metadata")
+	return []podTemplate{{
+		metadataPath: append(append([]string{}, path...), "metadata"),
+		specPath:     append(append([]string{}, path...), "spec"),
+		metadata:     metadata,
+		spec:         spec,
+	}}
+}
+
+// containerListKeys are the keys of a pod spec holding lists of containers
+var containerListKeys = []string{"initContainers", "containers", "ephemeralContainers"}
+
+// podContainer is a container, init container or ephemeral container
+// within a pod template
+type podContainer struct {
+	// path is the path of the container within the resource
+	path      []string
+	name      string
+	container map[string]interface{}
+}
+
+// podContainers returns every container in the pod templates of a resource
+func podContainers(body map[string]interface{}) []podContainer {
+	var containers []podContainer
+	for _, template := range podTemplates(body) {
+		for _, key := range containerListKeys {
+			list, _ := template.spec[key].([]interface{})
+			for i, item := range list {
+				container, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _ := container["name"].(string)
+				path := append(append([]string{}, template.specPath...), key, strconv.Itoa(i))
+				containers = append(containers, podContainer{path: path, name: name, container: container})
+			}
+		}
+	}
+	return containers
+}
@@ -0,0 +1,75 @@
+package kubeval
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPodTemplates(t *testing.T) {
+	var tests = []struct {
+		kind     string
+		expected []string
+	}{
+		{"Pod", []string{"spec"}},
+		{"PodTemplate", []string{"template", "spec"}},
+		{"CronJob", []string{"spec", "jobTemplate", "spec", "template", "spec"}},
+		{"Deployment", []string{"spec", "template", "spec"}},
+	}
+	for _, test := range tests {
+		body := map[string]interface{}{"kind": test.kind}
+		parent := body
+		for _, key := range test.expected {
+			child := map[string]interface{}{}
+			parent[key] = child
+			parent = child
+		}
+		templates := podTemplates(body)
+		if assert.Len(t, templates, 1, test.kind) {
+			assert.Equal(t, test.expected, templates[0].specPath, test.kind)
+		}
+	}
+
+	// Other kinds only have a pod template if it holds containers
+	body := map[string]interface{}{
+		"kind": "Widget",
+		"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{"size": 1}}},
+	}
+	assert.Empty(t, podTemplates(body))
+	assert.Empty(t, podTemplates(map[string]interface{}{"kind": "Service"}))
+}
+
+func TestChecksApplyToEveryPodTemplate(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/pod_templates.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "pod_templates.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	config.IgnoreMissingSchemas = true
+	config.RequireImageDigests = true
+	config.CheckSchedulingKeys = true
+
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	kinds := []string{"Pod", "PodTemplate", "ReplicationController", "ReplicaSet", "Deployment", "DaemonSet", "StatefulSet", "Job", "CronJob", "Rollout"}
+	if !assert.Len(t, results, len(kinds)) {
+		return
+	}
+	for i, result := range results {
+		assert.Equal(t, kinds[i], result.Kind)
+		spec := strings.Join(podTemplates(result.Object)[0].specPath, ".")
+		errors := []string{}
+		for _, e := range result.Errors {
+			errors = append(errors, e.String())
+		}
+		assert.Equal(t, []string{
+			spec + ".containers.0.image: Image 'nginx:1.17' of container 'web' is not pinned by digest, use image@sha256:<digest>",
+			spec + ".tolerations.0.key: Invalid key 'dedicated/' in tolerations: the name '' must be 1 to 63 alphanumeric characters, '-', '_' or '.', starting and ending with an alphanumeric character",
+		}, errors, result.Kind)
+	}
+}
@@ -0,0 +1,42 @@
+package kubeval
+
+import (
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// jsonPointerEscaper escapes a reference token of a JSON pointer, as
+// described in RFC 6901
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// contextSeparator separates the parts of a gojsonschema context while
+// they are split apart, as it cannot appear in a YAML or JSON key
+const contextSeparator = "\x00"
+
+// JSONPointer returns a JSON pointer (RFC 6901) to the value an error was
+// reported for within the document, such as /spec/ports/0/port, or an empty
+// string for the whole document. Unlike the dotted field of the error, it is
+// unambiguous for keys which contain dots or slashes, such as labels. An
+// additional property is pointed to itself, rather than the object holding
+// it, as it is present in the document.
+func JSONPointer(err gojsonschema.ResultError) string {
+	var tokens []string
+	if context := err.Context(); context != nil {
+		tokens = strings.Split(context.String(contextSeparator), contextSeparator)
+		// The first part is always the root of the document
+		tokens = tokens[1:]
+	}
+	if err.Type() == "additional_property_not_allowed" {
+		if property, ok := err.Details()["property"].(string); ok {
+			tokens = append(tokens, property)
+		}
+	}
+
+	var pointer strings.Builder
+	for _, token := range tokens {
+		pointer.WriteString("/")
+		pointer.WriteString(jsonPointerEscaper.Replace(token))
+	}
+	return pointer.String()
+}
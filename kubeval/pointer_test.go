@@ -0,0 +1,45 @@
+package kubeval
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+func TestJSONPointerForNestedFailure(t *testing.T) {
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+
+	results, err := Validate([]byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\nspec:\n  ports:\n  - port: 80\n  - port: http\n"), config)
+	require.NoError(t, err)
+	require.Len(t, results[0].Errors, 1)
+	assert.Equal(t, "spec.ports.1.port", results[0].Errors[0].Field())
+	assert.Equal(t, "/spec/ports/1/port", JSONPointer(results[0].Errors[0]))
+
+	details := newEvalResult(results[0]).ErrorDetails
+	assert.Equal(t, []ErrorDetail{{Type: "invalid_type", Field: "spec.ports.1.port", Pointer: "/spec/ports/1/port"}}, details)
+}
+
+func TestJSONPointerEscaping(t *testing.T) {
+	err := newCheckError("recommended_label", []string{"metadata", "labels", "app.kubernetes.io/name~x"}, "", "")
+	assert.Equal(t, "/metadata/labels/app.kubernetes.io~1name~0x", JSONPointer(err))
+
+	assert.Equal(t, "", JSONPointer(newCheckError("api_version", nil, "", "")))
+}
+
+func TestJSONPointerForAdditionalProperty(t *testing.T) {
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(map[string]interface{}{
+		"properties": map[string]interface{}{
+			"metadata": map[string]interface{}{"additionalProperties": false},
+		},
+	}))
+	require.NoError(t, err)
+	result, err := schema.Validate(gojsonschema.NewGoLoader(map[string]interface{}{
+		"metadata": map[string]interface{}{"labels/app": "web"},
+	}))
+	require.NoError(t, err)
+	require.Len(t, result.Errors(), 1)
+	assert.Equal(t, "/metadata/labels~1app", JSONPointer(result.Errors()[0]))
+}
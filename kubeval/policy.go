@@ -0,0 +1,320 @@
+package kubeval
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// kyvernoPolicy is a Kyverno Policy or ClusterPolicy found in the
+// resources being validated
+type kyvernoPolicy struct {
+	result ValidationResult
+	rules  []kyvernoRule
+}
+
+// kyvernoRule is a single `validate.pattern` rule from a Kyverno policy
+type kyvernoRule struct {
+	name    string
+	kinds   []string
+	message string
+	pattern interface{}
+}
+
+// isKyvernoPolicy returns whether the result is a Kyverno Policy or ClusterPolicy
+func isKyvernoPolicy(r ValidationResult) bool {
+	return strings.HasPrefix(r.APIVersion, "kyverno.io/") && (r.Kind == "ClusterPolicy" || r.Kind == "Policy")
+}
+
+// parseKyvernoPolicy extracts the `validate.pattern` rules from a Kyverno
+// policy. Rules using other validation methods, such as `deny` or
+// `anyPattern`, are ignored.
+func parseKyvernoPolicy(r ValidationResult) (kyvernoPolicy, error) {
+	policy := kyvernoPolicy{result: r}
+
+	spec, err := getObject(r.Object, "spec")
+	if err != nil {
+		return policy, err
+	}
+	rules, ok := spec["rules"].([]interface{})
+	if !ok {
+		return policy, fmt.Errorf("Expected array value for key 'spec.rules'")
+	}
+
+	for i, item := range rules {
+		rule, ok := item.(map[string]interface{})
+		if !ok {
+			return policy, fmt.Errorf("Expected object value for key 'spec.rules[%d]'", i)
+		}
+		name, err := getString(rule, "name")
+		if err != nil {
+			return policy, fmt.Errorf("spec.rules[%d]: %s", i, err.Error())
+		}
+		validate, _ := getObject(rule, "validate")
+		if validate == nil {
+			continue
+		}
+		pattern, found := validate["pattern"]
+		if !found {
+			continue
+		}
+		message, _ := getString(validate, "message")
+
+		policy.rules = append(policy.rules, kyvernoRule{
+			name:    name,
+			kinds:   matchedKinds(rule),
+			message: message,
+			pattern: pattern,
+		})
+	}
+	return policy, nil
+}
+
+// matchedKinds returns the kinds matched by a rule, from both the
+// `match.resources` and `match.any`/`match.all` forms
+func matchedKinds(rule map[string]interface{}) []string {
+	match, _ := getObject(rule, "match")
+	if match == nil {
+		return nil
+	}
+
+	filters := []interface{}{match}
+	for _, key := range []string{"any", "all"} {
+		if list, ok := match[key].([]interface{}); ok {
+			filters = append(filters, list...)
+		}
+	}
+
+	var kinds []string
+	for _, filter := range filters {
+		typed, ok := filter.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		resources, _ := getObject(typed, "resources")
+		if resources == nil {
+			continue
+		}
+		list, _ := resources["kinds"].([]interface{})
+		for _, kind := range list {
+			if s, ok := kind.(string); ok {
+				// kinds may be qualified with a group and version, as in apps/v1/Deployment
+				parts := strings.Split(s, "/")
+				kinds = append(kinds, parts[len(parts)-1])
+			}
+		}
+	}
+	return kinds
+}
+
+// appliesTo returns whether the policy should be evaluated against the
+// resource. Namespaced policies only apply within their own namespace.
+func (p kyvernoPolicy) appliesTo(r ValidationResult, config *Config) bool {
+	if r.Object == nil || isKyvernoPolicy(r) {
+		return false
+	}
+	if p.result.Kind == "Policy" {
+		return resolveNamespace(p.result.ResourceNamespace, config) == resolveNamespace(r.ResourceNamespace, config)
+	}
+	return true
+}
+
+// checkPolicies evaluates the `validate.pattern` rules of any Kyverno
+// policies in results against the other resources in results.
+func checkPolicies(results []ValidationResult, config *Config) error {
+	var errors *multierror.Error
+
+	var policies []kyvernoPolicy
+	for _, r := range results {
+		if !isKyvernoPolicy(r) || r.Object == nil {
+			continue
+		}
+		policy, err := parseKyvernoPolicy(r)
+		if err != nil {
+			errors = multierror.Append(errors, fmt.Errorf("%s: Invalid %s '%s': %s", r.FileName, r.Kind, r.QualifiedName(), err.Error()))
+			continue
+		}
+		policies = append(policies, policy)
+	}
+
+	for _, policy := range policies {
+		for _, rule := range policy.rules {
+			for _, r := range results {
+				if !in(rule.kinds, r.Kind) || !policy.appliesTo(r, config) {
+					continue
+				}
+				if path, ok := matchPattern(rule.pattern, r.Object, "", true); !ok {
+					message := rule.message
+					if message == "" {
+						message = "validation rule failed"
+					}
+					errors = multierror.Append(errors, fmt.Errorf("%s: %s '%s' violates %s '%s' rule '%s': %s (at %s)", r.FileName, r.Kind, r.QualifiedName(), policy.result.Kind, policy.result.ResourceName, rule.name, message, path))
+				}
+			}
+		}
+	}
+
+	return errors.ErrorOrNil()
+}
+
+// matchPattern checks value against a Kyverno pattern, returning whether it
+// matched and, if not, the path of the first mismatch. Supported are nested
+// objects and arrays, the `(key)` conditional, `=(key)` equality and `X(key)`
+// negation anchors, wildcards, `|` alternatives, `!` negation and numeric
+// comparisons.
+func matchPattern(pattern, value interface{}, path string, present bool) (string, bool) {
+	switch typedPattern := pattern.(type) {
+	case map[string]interface{}:
+		typedValue, ok := value.(map[string]interface{})
+		if !ok {
+			return rootPath(path), false
+		}
+		return matchMapPattern(typedPattern, typedValue, path)
+	case []interface{}:
+		typedValue, ok := value.([]interface{})
+		if !ok {
+			return rootPath(path), false
+		}
+		if len(typedPattern) == 0 {
+			return "", true
+		}
+		// every element must match the first element of the pattern
+		for i, element := range typedValue {
+			if mismatch, ok := matchPattern(typedPattern[0], element, fmt.Sprintf("%s[%d]", path, i), true); !ok {
+				return mismatch, false
+			}
+		}
+		return "", true
+	default:
+		if !present {
+			return rootPath(path), false
+		}
+		if !matchScalarPattern(typedPattern, value) {
+			return rootPath(path), false
+		}
+		return "", true
+	}
+}
+
+func matchMapPattern(pattern, value map[string]interface{}, path string) (string, bool) {
+	// conditional anchors decide whether the rest of the pattern applies at all
+	for _, key := range sortedKeys(pattern) {
+		if anchor, name := parseAnchor(key); anchor == "(" {
+			v, found := value[name]
+			if _, ok := matchPattern(pattern[key], v, joinPath(path, name), found); !ok {
+				return "", true
+			}
+		}
+	}
+
+	for _, key := range sortedKeys(pattern) {
+		anchor, name := parseAnchor(key)
+		v, found := value[name]
+		switch anchor {
+		case "(":
+			continue
+		case "=(":
+			if !found {
+				continue
+			}
+		case "X(":
+			if found {
+				return joinPath(path, name), false
+			}
+			continue
+		}
+		if mismatch, ok := matchPattern(pattern[key], v, joinPath(path, name), found); !ok {
+			return mismatch, false
+		}
+	}
+	return "", true
+}
+
+// parseAnchor splits a pattern key into its anchor, if any, and the
+// field name it refers to
+func parseAnchor(key string) (string, string) {
+	for _, anchor := range []string{"=(", "X(", "("} {
+		if strings.HasPrefix(key, anchor) && strings.HasSuffix(key, ")") {
+			return anchor, key[len(anchor) : len(key)-1]
+		}
+	}
+	return "", key
+}
+
+func matchScalarPattern(pattern, value interface{}) bool {
+	typedPattern, ok := pattern.(string)
+	if !ok {
+		if pattern == nil {
+			return value == nil
+		}
+		return fmt.Sprint(pattern) == fmt.Sprint(value)
+	}
+	if value == nil {
+		return false
+	}
+
+	for _, alternative := range strings.Split(typedPattern, "|") {
+		if matchScalarAlternative(strings.TrimSpace(alternative), value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchScalarAlternative(pattern string, value interface{}) bool {
+	if strings.HasPrefix(pattern, "!") {
+		return !matchScalarAlternative(pattern[1:], value)
+	}
+
+	for _, operator := range []string{">=", "<=", ">", "<"} {
+		if !strings.HasPrefix(pattern, operator) {
+			continue
+		}
+		expected, err := strconv.ParseFloat(strings.TrimSpace(pattern[len(operator):]), 64)
+		if err != nil {
+			break
+		}
+		actual, err := strconv.ParseFloat(fmt.Sprint(value), 64)
+		if err != nil {
+			return false
+		}
+		switch operator {
+		case ">=":
+			return actual >= expected
+		case "<=":
+			return actual <= expected
+		case ">":
+			return actual > expected
+		default:
+			return actual < expected
+		}
+	}
+
+	return wildcardToRegexp(pattern).MatchString(fmt.Sprint(value))
+}
+
+// wildcardToRegexp converts a pattern using `*` and `?` wildcards into
+// an anchored regular expression
+func wildcardToRegexp(pattern string) *regexp.Regexp {
+	expression := regexp.QuoteMeta(pattern)
+	expression = strings.Replace(expression, `\*`, ".*", -1)
+	expression = strings.Replace(expression, `\?`, ".", -1)
+	return regexp.MustCompile("^" + expression + "$")
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func rootPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
@@ -0,0 +1,168 @@
+package kubeval
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/assert"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+func TestCheckResourceSetEvaluatesPolicies(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/kyverno_policy.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "kyverno_policy.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	config.IgnoreMissingSchemas = true
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := CheckResourceSet(results, NewDefaultConfig()); err != nil {
+		t.Errorf("Policies should not be evaluated unless enabled, got %v", err)
+	}
+
+	config.EvaluatePolicies = true
+	err = CheckResourceSet(results, config)
+	merr, ok := err.(*multierror.Error)
+	if !ok {
+		t.Fatalf("Expected policy violations, got %v", err)
+	}
+	assert.Equal(t, []string{
+		"kyverno_policy.yaml: Deployment 'non-compliant' violates ClusterPolicy 'require-team-label' rule 'check-team-label': The label `team` is required. (at metadata.labels)",
+		"kyverno_policy.yaml: Deployment 'non-compliant' violates ClusterPolicy 'require-team-label' rule 'disallow-latest-tag': Using a mutable image tag e.g. 'latest' is not allowed. (at spec.template.spec.containers[1].image)",
+	}, errorStrings(merr.Errors))
+}
+
+func TestMatchPattern(t *testing.T) {
+	var tests = []struct {
+		name     string
+		pattern  interface{}
+		value    interface{}
+		path     string
+		expected bool
+	}{
+		{
+			name:     "equal scalar",
+			pattern:  map[string]interface{}{"a": "b"},
+			value:    map[string]interface{}{"a": "b"},
+			expected: true,
+		},
+		{
+			name:     "missing key",
+			pattern:  map[string]interface{}{"a": "*"},
+			value:    map[string]interface{}{},
+			path:     "a",
+			expected: false,
+		},
+		{
+			name:     "alternatives",
+			pattern:  map[string]interface{}{"a": "x|y"},
+			value:    map[string]interface{}{"a": "y"},
+			expected: true,
+		},
+		{
+			name:     "numeric comparison",
+			pattern:  map[string]interface{}{"replicas": ">1"},
+			value:    map[string]interface{}{"replicas": float64(1)},
+			path:     "replicas",
+			expected: false,
+		},
+		{
+			name:     "failed conditional anchor skips pattern",
+			pattern:  map[string]interface{}{"(kind)": "Pod", "a": "b"},
+			value:    map[string]interface{}{"kind": "Service"},
+			expected: true,
+		},
+		{
+			name:     "equality anchor only checks present keys",
+			pattern:  map[string]interface{}{"=(hostNetwork)": false},
+			value:    map[string]interface{}{},
+			expected: true,
+		},
+		{
+			name:     "negation anchor",
+			pattern:  map[string]interface{}{"X(hostPath)": "null"},
+			value:    map[string]interface{}{"hostPath": map[string]interface{}{}},
+			path:     "hostPath",
+			expected: false,
+		},
+		{
+			name:     "array elements",
+			pattern:  map[string]interface{}{"c": []interface{}{map[string]interface{}{"image": "gcr.io/*"}}},
+			value:    map[string]interface{}{"c": []interface{}{map[string]interface{}{"image": "gcr.io/a/b:1"}, map[string]interface{}{"image": "nginx"}}},
+			path:     "c[1].image",
+			expected: false,
+		},
+	}
+	for _, test := range tests {
+		path, ok := matchPattern(test.pattern, test.value, "", true)
+		assert.Equal(t, test.expected, ok, test.name)
+		assert.Equal(t, test.path, path, test.name)
+	}
+}
+
+func TestValidatePolicyResourcesAgainstBundledSchemas(t *testing.T) {
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+
+	for _, fixture := range []string{"kyverno_policy.yaml", "gatekeeper_policy.yaml"} {
+		fileContents, _ := ioutil.ReadFile(filepath.Join("../fixtures", fixture))
+		config.FileName = fixture
+		results, err := Validate(fileContents, config)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, r := range results {
+			assert.Equal(t, "valid", r.Status(), "%s %s", fixture, r.Kind)
+		}
+	}
+
+	invalid := []byte(`apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: broken
+spec:
+  validationFailureAction: block
+  rules:
+  - match:
+      resources:
+        kinds: Deployment
+`)
+	results, err := Validate(invalid, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assert.True(t, results[0].ValidatedAgainstSchema)
+	assert.ElementsMatch(t, []string{
+		"spec.validationFailureAction: spec.validationFailureAction must be one of the following: \"audit\", \"enforce\", \"Audit\", \"Enforce\", null",
+		"name: name is required",
+		"spec.rules.0.match.resources.kinds: Invalid type. Expected: array, given: string",
+	}, resultErrorStrings(results[0].Errors))
+
+	// Fields outside the CRDs are only rejected when validating strictly
+	unknownField := []byte(`apiVersion: constraints.gatekeeper.sh/v1beta1
+kind: K8sAllowedRepos
+metadata:
+  name: repos
+spec:
+  enforcementActon: deny
+`)
+	results, _ = Validate(unknownField, config)
+	assert.Empty(t, results[0].Errors)
+	config.Strict = true
+	results, _ = Validate(unknownField, config)
+	assert.Equal(t, []string{"enforcementActon: Additional property enforcementActon is not allowed"}, resultErrorStrings(results[0].Errors))
+}
+
+func resultErrorStrings(errs []gojsonschema.ResultError) []string {
+	var strings []string
+	for _, e := range errs {
+		strings = append(strings, e.String())
+	}
+	return strings
+}
@@ -0,0 +1,207 @@
+package kubeval
+
+import (
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// The policy schemas below are used for Kyverno policies and Gatekeeper
+// constraint templates and constraints when no schema location has a schema
+// for them, which the Kubernetes schema locations never do, so that these
+// resources are validated out of the box and without network access. They
+// follow the structure of the CRDs of Kyverno 1.13 and Gatekeeper 3.17, but
+// leave the contents of patterns, mutations and Rego open.
+
+// gatekeeperConstraintsGroup is the API group of Gatekeeper constraints,
+// whose kinds are defined by constraint templates
+const gatekeeperConstraintsGroup = "constraints.gatekeeper.sh"
+
+// policySchemaBuilder builds the policy schemas, closing the objects whose
+// fields are all listed when validating strictly
+type policySchemaBuilder struct {
+	strict bool
+}
+
+// closed returns an object schema with properties, which may have no other
+// properties when validating strictly
+func (b policySchemaBuilder) closed(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := fallbackObject(properties, required...)
+	if b.strict {
+		schema["additionalProperties"] = false
+	}
+	return schema
+}
+
+func (b policySchemaBuilder) resource(spec map[string]interface{}) map[string]interface{} {
+	return b.closed(map[string]interface{}{
+		"apiVersion": fallbackType("string"),
+		"kind":       fallbackType("string"),
+		"metadata":   fallbackObjectMeta(),
+		"spec":       spec,
+		"status":     fallbackType("object"),
+	}, "spec")
+}
+
+func policyEnum(values ...interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": []string{"string", "null"}, "enum": append(values, nil)}
+}
+
+func policyStrings() map[string]interface{} {
+	return fallbackArray(fallbackType("string"))
+}
+
+// kyvernoResourceFilter is an entry of the any or all of a match or exclude
+func (b policySchemaBuilder) kyvernoResourceFilter() map[string]interface{} {
+	return b.closed(map[string]interface{}{
+		"resources": b.closed(map[string]interface{}{
+			"kinds":             policyStrings(),
+			"name":              fallbackType("string"),
+			"names":             policyStrings(),
+			"namespaces":        policyStrings(),
+			"annotations":       fallbackStringMap(),
+			"selector":          fallbackLabelSelector(),
+			"namespaceSelector": fallbackLabelSelector(),
+			"operations":        fallbackArray(policyEnum("CREATE", "CONNECT", "UPDATE", "DELETE")),
+		}),
+		"subjects":     fallbackArray(fallbackObject(map[string]interface{}{"kind": fallbackType("string"), "name": fallbackType("string")}, "kind", "name")),
+		"roles":        policyStrings(),
+		"clusterRoles": policyStrings(),
+	})
+}
+
+func (b policySchemaBuilder) kyvernoMatch() map[string]interface{} {
+	filter := b.kyvernoResourceFilter()
+	properties := map[string]interface{}{
+		"any": fallbackArray(filter),
+		"all": fallbackArray(filter),
+	}
+	for key, value := range filter["properties"].(map[string]interface{}) {
+		properties[key] = value
+	}
+	return b.closed(properties)
+}
+
+func (b policySchemaBuilder) kyvernoRule() map[string]interface{} {
+	return b.closed(map[string]interface{}{
+		"name":                   map[string]interface{}{"type": "string", "maxLength": 63},
+		"match":                  b.kyvernoMatch(),
+		"exclude":                b.kyvernoMatch(),
+		"context":                fallbackArray(fallbackObject(map[string]interface{}{"name": fallbackType("string")})),
+		"preconditions":          map[string]interface{}{},
+		"celPreconditions":       fallbackArray(fallbackType("object")),
+		"skipBackgroundRequests": fallbackType("boolean"),
+		"imageExtractors":        fallbackType("object"),
+		"reportProperties":       fallbackStringMap(),
+		"validate": b.closed(map[string]interface{}{
+			"message":                 fallbackType("string"),
+			"failureAction":           policyEnum("Audit", "Enforce"),
+			"failureActionOverrides":  fallbackArray(fallbackType("object")),
+			"allowExistingViolations": fallbackType("boolean"),
+			"pattern":                 map[string]interface{}{},
+			"anyPattern":              map[string]interface{}{},
+			"deny":                    fallbackType("object"),
+			"foreach":                 fallbackArray(fallbackType("object")),
+			"manifests":               fallbackType("object"),
+			"podSecurity":             fallbackType("object"),
+			"cel":                     fallbackType("object"),
+			"assert":                  map[string]interface{}{},
+		}),
+		"mutate":       fallbackType("object"),
+		"generate":     fallbackType("object"),
+		"verifyImages": fallbackArray(fallbackType("object")),
+	}, "name")
+}
+
+func (b policySchemaBuilder) kyvernoPolicy() map[string]interface{} {
+	return b.resource(b.closed(map[string]interface{}{
+		"admission":                        fallbackType("boolean"),
+		"applyRules":                       policyEnum("All", "One"),
+		"background":                       fallbackType("boolean"),
+		"emitWarning":                      fallbackType("boolean"),
+		"failurePolicy":                    policyEnum("Ignore", "Fail"),
+		"generateExisting":                 fallbackType("boolean"),
+		"generateExistingOnPolicyUpdate":   fallbackType("boolean"),
+		"mutateExistingOnPolicyUpdate":     fallbackType("boolean"),
+		"rules":                            fallbackArray(b.kyvernoRule()),
+		"schemaValidation":                 fallbackType("boolean"),
+		"useServerSideApply":               fallbackType("boolean"),
+		"validationFailureAction":          policyEnum("audit", "enforce", "Audit", "Enforce"),
+		"validationFailureActionOverrides": fallbackArray(fallbackObject(map[string]interface{}{"action": policyEnum("audit", "enforce", "Audit", "Enforce"), "namespaces": policyStrings(), "namespaceSelector": fallbackLabelSelector()})),
+		"webhookConfiguration":             fallbackType("object"),
+		"webhookTimeoutSeconds":            fallbackType("integer"),
+	}))
+}
+
+func (b policySchemaBuilder) gatekeeperConstraintTemplate() map[string]interface{} {
+	return b.resource(b.closed(map[string]interface{}{
+		"crd": b.closed(map[string]interface{}{
+			"spec": b.closed(map[string]interface{}{
+				"names": b.closed(map[string]interface{}{
+					"kind":       fallbackType("string"),
+					"shortNames": policyStrings(),
+				}),
+				"validation": b.closed(map[string]interface{}{
+					"openAPIV3Schema": fallbackType("object"),
+					"legacySchema":    fallbackType("boolean"),
+				}),
+			}),
+		}),
+		"targets": fallbackArray(b.closed(map[string]interface{}{
+			"target":     fallbackType("string"),
+			"rego":       fallbackType("string"),
+			"libs":       policyStrings(),
+			"code":       fallbackArray(fallbackObject(map[string]interface{}{"engine": fallbackType("string"), "source": map[string]interface{}{}}, "engine", "source")),
+			"operations": policyStrings(),
+		})),
+	}))
+}
+
+func (b policySchemaBuilder) gatekeeperConstraint() map[string]interface{} {
+	return b.resource(b.closed(map[string]interface{}{
+		"enforcementAction":        fallbackType("string"),
+		"scopedEnforcementActions": fallbackArray(fallbackType("object")),
+		"match": b.closed(map[string]interface{}{
+			"kinds":              fallbackArray(b.closed(map[string]interface{}{"apiGroups": policyStrings(), "kinds": policyStrings()})),
+			"scope":              policyEnum("*", "Cluster", "Namespaced"),
+			"namespaces":         policyStrings(),
+			"excludedNamespaces": policyStrings(),
+			"labelSelector":      fallbackLabelSelector(),
+			"namespaceSelector":  fallbackLabelSelector(),
+			"name":               fallbackType("string"),
+			"source":             policyEnum("All", "Generated", "Original"),
+		}),
+		"parameters": map[string]interface{}{},
+	}))
+}
+
+// policySchemaDefinition returns the policy schema for a resource, or nil
+// if it is not a policy resource
+func policySchemaDefinition(resource *ValidationResult, config *Config) map[string]interface{} {
+	b := policySchemaBuilder{strict: config.Strict}
+	switch resource.APIVersion + "/" + resource.Kind {
+	case "kyverno.io/v1/ClusterPolicy", "kyverno.io/v1/Policy", "kyverno.io/v2beta1/ClusterPolicy", "kyverno.io/v2beta1/Policy":
+		return b.kyvernoPolicy()
+	case "templates.gatekeeper.sh/v1/ConstraintTemplate", "templates.gatekeeper.sh/v1beta1/ConstraintTemplate":
+		return b.gatekeeperConstraintTemplate()
+	}
+	if strings.HasPrefix(resource.APIVersion, gatekeeperConstraintsGroup+"/") {
+		return b.gatekeeperConstraint()
+	}
+	return nil
+}
+
+// loadPolicySchema returns the compiled policy schema for a resource, or
+// nil if it is not a policy resource
+func loadPolicySchema(resource *ValidationResult, config *Config) (*gojsonschema.Schema, error) {
+	definition := policySchemaDefinition(resource, config)
+	if definition == nil {
+		return nil, nil
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(definition))
+	if err != nil {
+		return nil, multierror.Prefix(err, "Failed initializing policy schema:")
+	}
+	return schema, nil
+}
@@ -0,0 +1,137 @@
+package kubeval
+
+import (
+	"sync"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// prefetchJob is a schema to load ahead of validation
+type prefetchJob struct {
+	resource ValidationResult
+	config   *Config
+}
+
+// PrefetchSchemas collects the kinds of every resource in inputs and loads
+// their schemas concurrently into schemaCache, so that validating inputs
+// with ValidateWithCache afterwards does not wait on each schema in turn.
+// Config.PrefetchWorkers schemas are loaded at once.
+//
+// Only schemas which load successfully are cached. Any which are missing or
+// cannot be loaded are left to be loaded again during validation, so that
+// the failure is reported against the resources concerned as usual.
+func PrefetchSchemas(inputs [][]byte, schemaCache map[string]*gojsonschema.Schema, conf ...*Config) {
+	config := NewDefaultConfig()
+	if len(conf) == 1 {
+		config = conf[0]
+	}
+	prefetchSchemas(inputs, schemaMap(schemaCache), config)
+}
+
+// prefetchSchemas prefetches the schemas for inputs into schemaCache, see
+// PrefetchSchemas
+func prefetchSchemas(inputs [][]byte, schemaCache schemaStore, config *Config) {
+	// The files the Config refers to are parsed once for all the jobs,
+	// which are left to validation to report if they cannot be
+	copied := *config
+	config = &copied
+	if err := parseConfigFiles(config); err != nil {
+		return
+	}
+	useFetchSlots(config, schemaCache)
+	jobs := prefetchJobs(inputs, schemaCache, config)
+	if len(jobs) == 0 {
+		return
+	}
+
+	workers := config.PrefetchWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+
+	// Format checkers must be registered before any schema is loaded
+	defer useFormatCheckers(config)()
+
+	// Each worker loads schemas into its own cache, as the cache passed in
+	// need not be safe for concurrent use, and these are merged once all are
+	// done
+	queue := make(chan prefetchJob)
+	caches := make([]schemaMap, workers)
+	var wg sync.WaitGroup
+	for i := range caches {
+		caches[i] = NewSchemaCache()
+		wg.Add(1)
+		go func(cache schemaMap) {
+			defer wg.Done()
+			for job := range queue {
+				downloadSchema(&job.resource, cache, job.config)
+			}
+		}(caches[i])
+	}
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+
+	for _, cache := range caches {
+		for key, schema := range cache {
+			if schema != nil {
+				schemaCache.storeUnlisted(key, cache.unlisted(key))
+				schemaCache.store(key, schema)
+			}
+		}
+	}
+}
+
+// prefetchJobs returns a job for each distinct schema needed to validate
+// the resources in inputs which is not already in schemaCache. Resources
+// which would not be validated against a schema, such as those of skipped
+// kinds, are left out, as are documents which cannot be decoded, which are
+// reported during validation.
+func prefetchJobs(inputs [][]byte, schemaCache schemaStore, config *Config) []prefetchJob {
+	var requirements []labelRequirement
+	if config.Selector != "" {
+		requirements, _ = parseSelector(config.Selector)
+	}
+
+	var jobs []prefetchJob
+	queued := make(map[string]bool)
+	for _, input := range inputs {
+		documents, _ := splitInput(input, config)
+		for _, document := range documents {
+			var body map[string]interface{}
+			if err := unmarshalDocument(document, &body); err != nil || body == nil {
+				continue
+			}
+			kind, kindErr := getString(body, "kind")
+			apiVersion, apiVersionErr := getString(body, "apiVersion")
+			apiVersion = canonicalAPIVersion(apiVersion, config)
+			if kindErr != nil || apiVersionErr != nil || checkAPIVersionFormat(apiVersion) != nil {
+				continue
+			}
+			if isKindSkipped(kind, config) || !config.SelectsAPIGroup(apiVersion) || in(config.KindsToReject, kind) {
+				continue
+			}
+			if requirements != nil && !matchesSelector(body, requirements) {
+				continue
+			}
+
+			resource := ValidationResult{Kind: kind, APIVersion: apiVersion}
+			documentConfig, err := applyKubernetesVersionAnnotation(body, &resource, config)
+			if err != nil {
+				continue
+			}
+			key := schemaCacheKey(&resource, documentConfig)
+			if _, cached := schemaCache.load(key); cached || queued[key] {
+				continue
+			}
+			queued[key] = true
+			jobs = append(jobs, prefetchJob{resource: resource, config: documentConfig})
+		}
+	}
+	return jobs
+}
@@ -0,0 +1,94 @@
+package kubeval
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var prefetchInputs = [][]byte{
+	[]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`),
+	[]byte(`apiVersion: v1
+kind: Service
+metadata:
+  name: api
+  annotations:
+    kubeval.io/kubernetes-version: "1.25"
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: credentials
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: missing
+---
+not: [valid
+`),
+}
+
+func TestPrefetchSchemas(t *testing.T) {
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	config.KindsToSkip = []string{"Secret"}
+	schemaCache := NewSchemaCache()
+
+	PrefetchSchemas(prefetchInputs, schemaCache, config)
+
+	// Missing schemas and skipped kinds are not cached
+	assert.Len(t, schemaCache, 3)
+	for _, key := range []string{"apps/v1/Deployment", "v1/Service", "1.25:v1/Service"} {
+		assert.NotNil(t, schemaCache[key], key)
+	}
+
+	// Validation uses the prefetched schemas, and still reports the
+	// missing schema and the document which cannot be decoded
+	results, err := ValidateWithCache(prefetchInputs[1], schemaCache, config)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed initializing schema")
+	assert.Contains(t, err.Error(), "Failed to decode YAML")
+	assert.True(t, results[0].ValidatedAgainstSchema)
+}
+
+func TestPrefetchSchemasSingleWorker(t *testing.T) {
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	config.PrefetchWorkers = 0
+	schemaCache := NewSchemaCache()
+
+	PrefetchSchemas(prefetchInputs, schemaCache, config)
+	assert.Len(t, schemaCache, 4)
+}
+
+func TestValidateFilesPrefetch(t *testing.T) {
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	config.IgnoreMissingSchemas = true
+	config.Prefetch = true
+	schemaCache := NewSchemaCache()
+
+	reads := 0
+	file := NewFile("web.yaml", prefetchInputs[0])
+	read := file.Read
+	file.Read = func() ([]byte, error) {
+		reads++
+		return read()
+	}
+
+	results, err := ValidateFiles(StaticDiscoverer([]File{file}), schemaCache, config)
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, 1, reads)
+	assert.NotNil(t, schemaCache["apps/v1/Deployment"])
+}
@@ -0,0 +1,212 @@
+package kubeval
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// quantityPattern matches a Kubernetes resource quantity, such as 500m,
+// 1.5, 128Mi or 1e3
+var quantityPattern = regexp.MustCompile(`^([+-]?(?:[0-9]+\.?[0-9]*|\.[0-9]+)(?:[eE][+-]?[0-9]+)?)(Ki|Mi|Gi|Ti|Pi|Ei|n|u|m|k|M|G|T|P|E)?$`)
+
+// quantitySuffixes are the multipliers of the suffixes of quantities
+var quantitySuffixes = map[string]*big.Rat{
+	"":   big.NewRat(1, 1),
+	"n":  big.NewRat(1, 1000000000),
+	"u":  big.NewRat(1, 1000000),
+	"m":  big.NewRat(1, 1000),
+	"k":  big.NewRat(1000, 1),
+	"M":  big.NewRat(1000000, 1),
+	"G":  new(big.Rat).SetInt64(1000000000),
+	"T":  new(big.Rat).SetInt64(1000000000000),
+	"P":  new(big.Rat).SetInt64(1000000000000000),
+	"E":  new(big.Rat).SetInt64(1000000000000000000),
+	"Ki": new(big.Rat).SetInt64(1 << 10),
+	"Mi": new(big.Rat).SetInt64(1 << 20),
+	"Gi": new(big.Rat).SetInt64(1 << 30),
+	"Ti": new(big.Rat).SetInt64(1 << 40),
+	"Pi": new(big.Rat).SetInt64(1 << 50),
+	"Ei": new(big.Rat).SetInt64(1 << 60),
+}
+
+// parseQuantity parses a resource quantity, which may be a string such as
+// 500m or 128Mi, or a plain number
+func parseQuantity(value interface{}) (*big.Rat, bool) {
+	var text string
+	switch typed := value.(type) {
+	case string:
+		text = strings.TrimSpace(typed)
+	case float64:
+		return new(big.Rat).SetFloat64(typed), true
+	case int:
+		return new(big.Rat).SetInt64(int64(typed)), true
+	case int64:
+		return new(big.Rat).SetInt64(typed), true
+	default:
+		return nil, false
+	}
+	found := quantityPattern.FindStringSubmatch(text)
+	if found == nil {
+		return nil, false
+	}
+	number, ok := new(big.Rat).SetString(found[1])
+	if !ok {
+		return nil, false
+	}
+	return number.Mul(number, quantitySuffixes[found[2]]), true
+}
+
+// formatQuantity formats a quantity of a resource for reporting: CPU in
+// cores or millicores, memory and storage with the largest binary suffix
+// which divides it exactly, and anything else as a plain number
+func formatQuantity(resource string, quantity *big.Rat) string {
+	if resource == "cpu" {
+		if quantity.IsInt() {
+			return quantity.RatString()
+		}
+		millicores := new(big.Rat).Mul(quantity, big.NewRat(1000, 1))
+		return millicores.FloatString(0) + "m"
+	}
+	if !quantity.IsInt() {
+		return quantity.FloatString(3)
+	}
+	if resource == "memory" || resource == "ephemeral-storage" {
+		for _, suffix := range []string{"Ei", "Pi", "Ti", "Gi", "Mi", "Ki"} {
+			scaled := new(big.Rat).Quo(quantity, quantitySuffixes[suffix])
+			if scaled.IsInt() && scaled.Sign() > 0 {
+				return scaled.RatString() + suffix
+			}
+		}
+	}
+	return quantity.RatString()
+}
+
+// quotaResource is a resource limited by a ResourceQuota: the requests or
+// limits of a compute resource, or the number of pods
+type quotaResource struct {
+	// field is requests or limits, or empty for the number of pods
+	field    string
+	resource string
+}
+
+// parseQuotaResource returns the resource limited by a key of the hard
+// limits of a ResourceQuota, such as requests.cpu or memory, which is a
+// shorthand for requests.memory. Object counts other than pods are not
+// supported.
+func parseQuotaResource(key string) (quotaResource, bool) {
+	switch key {
+	case "pods":
+		return quotaResource{resource: "pods"}, true
+	case "cpu", "memory", "ephemeral-storage":
+		return quotaResource{field: "requests", resource: key}, true
+	}
+	parts := strings.SplitN(key, ".", 2)
+	if len(parts) == 2 && (parts[0] == "requests" || parts[0] == "limits") && !strings.Contains(parts[1], "/") {
+		return quotaResource{field: parts[0], resource: parts[1]}, true
+	}
+	return quotaResource{}, false
+}
+
+// podReplicas returns the number of pods a workload runs at once. Kinds
+// without a fixed count, such as DaemonSets, run one pod, as the number
+// of nodes is not known. PodTemplates run none.
+func podReplicas(body map[string]interface{}) int64 {
+	count := func(path ...string) int64 {
+		value, ok := parseQuantity(lookupPath(body, path))
+		if !ok || !value.IsInt() {
+			return 1
+		}
+		return value.Num().Int64()
+	}
+	kind, _ := body["kind"].(string)
+	switch kind {
+	case "Pod", "DaemonSet":
+		return 1
+	case "PodTemplate":
+		return 0
+	case "Job":
+		return count("spec", "parallelism")
+	case "CronJob":
+		return count("spec", "jobTemplate", "spec", "parallelism")
+	default:
+		return count("spec", "replicas")
+	}
+}
+
+// podUsage returns the amount of a resource used by a single pod of a
+// workload: the sum over its containers, or the largest amount used by any
+// one init container if that is more, as they run one at a time
+func podUsage(template podTemplate, resource quotaResource) *big.Rat {
+	if resource.resource == "pods" {
+		return big.NewRat(1, 1)
+	}
+	usage := func(item interface{}) *big.Rat {
+		container, _ := item.(map[string]interface{})
+		amount, ok := parseQuantity(lookupPath(container, []string{"resources", resource.field, resource.resource}))
+		if !ok {
+			return new(big.Rat)
+		}
+		return amount
+	}
+	total := new(big.Rat)
+	containers, _ := template.spec["containers"].([]interface{})
+	for _, container := range containers {
+		total.Add(total, usage(container))
+	}
+	initContainers, _ := template.spec["initContainers"].([]interface{})
+	for _, container := range initContainers {
+		if amount := usage(container); amount.Cmp(total) > 0 {
+			total = amount
+		}
+	}
+	return total
+}
+
+// checkResourceQuotas sums the resources requested by the workloads in
+// each namespace, and reports any ResourceQuota in the same namespace which
+// they would exceed
+func checkResourceQuotas(results []ValidationResult, config *Config) error {
+	var errors *multierror.Error
+
+	for _, quota := range results {
+		if quota.Object == nil || quota.Kind != "ResourceQuota" {
+			continue
+		}
+		namespace := resolveNamespace(quota.ResourceNamespace, config)
+		hard, _ := lookupPath(quota.Object, []string{"spec", "hard"}).(map[string]interface{})
+		for _, key := range sortedKeys(hard) {
+			resource, supported := parseQuotaResource(key)
+			allowed, ok := parseQuantity(hard[key])
+			if !supported || !ok {
+				continue
+			}
+
+			total := new(big.Rat)
+			var contributors []string
+			for _, r := range results {
+				if r.Object == nil || resolveNamespace(r.ResourceNamespace, config) != namespace {
+					continue
+				}
+				replicas := podReplicas(r.Object)
+				for _, template := range podTemplates(r.Object) {
+					usage := new(big.Rat).Mul(podUsage(template, resource), new(big.Rat).SetInt64(replicas))
+					if usage.Sign() == 0 {
+						continue
+					}
+					total.Add(total, usage)
+					contributors = append(contributors, fmt.Sprintf("%s '%s' %s", r.Kind, r.ResourceName, formatQuantity(resource.resource, usage)))
+				}
+			}
+
+			if total.Cmp(allowed) > 0 {
+				errors = multierror.Append(errors, fmt.Errorf("%s: Workloads in namespace '%s' need %s of %s, more than ResourceQuota '%s' allows (%s): %s", quota.FileName, namespace, key, formatQuantity(resource.resource, total), quota.ResourceName, formatQuantity(resource.resource, allowed), strings.Join(contributors, ", ")))
+			}
+		}
+	}
+
+	return errors.ErrorOrNil()
+}
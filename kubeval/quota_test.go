@@ -0,0 +1,80 @@
+package kubeval
+
+import (
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuantity(t *testing.T) {
+	var tests = []struct {
+		value    interface{}
+		expected *big.Rat
+	}{
+		{"500m", big.NewRat(1, 2)},
+		{"1.5", big.NewRat(3, 2)},
+		{"128Mi", big.NewRat(128<<20, 1)},
+		{"1G", big.NewRat(1000000000, 1)},
+		{"1e3", big.NewRat(1000, 1)},
+		{float64(2), big.NewRat(2, 1)},
+	}
+	for _, test := range tests {
+		actual, ok := parseQuantity(test.value)
+		if assert.True(t, ok, test.value) {
+			assert.Equal(t, 0, test.expected.Cmp(actual), test.value)
+		}
+	}
+	for _, value := range []interface{}{"", "lots", "1Qi", true} {
+		_, ok := parseQuantity(value)
+		assert.False(t, ok, value)
+	}
+}
+
+func TestFormatQuantity(t *testing.T) {
+	assert.Equal(t, "2", formatQuantity("cpu", big.NewRat(2, 1)))
+	assert.Equal(t, "2800m", formatQuantity("cpu", big.NewRat(28, 10)))
+	assert.Equal(t, "1280Mi", formatQuantity("memory", big.NewRat(1280<<20, 1)))
+	assert.Equal(t, "1000", formatQuantity("memory", big.NewRat(1000, 1)))
+	assert.Equal(t, "4", formatQuantity("pods", big.NewRat(4, 1)))
+}
+
+func TestCheckResourceQuotas(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/resource_quota.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "resource_quota.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	config.IgnoreMissingSchemas = true
+	config.CheckResourceQuotas = true
+
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assert.NoError(t, CheckResourceSet(results, config))
+	assert.EqualError(t, CheckResourceSetWarnings(results, config), "resource_quota.yaml: Workloads in namespace 'team-a' need limits.memory of 1280Mi, more than ResourceQuota 'compute' allows (1Gi): Deployment 'web' 768Mi, StatefulSet 'db' 512Mi\n"+
+		"resource_quota.yaml: Workloads in namespace 'team-a' need requests.cpu of 2800m, more than ResourceQuota 'compute' allows (2): Deployment 'web' 1800m, StatefulSet 'db' 1")
+
+	config.CheckResourceQuotas = false
+	assert.NoError(t, CheckResourceSetWarnings(results, config))
+}
+
+func TestPodUsageInitContainers(t *testing.T) {
+	body := map[string]interface{}{
+		"kind": "Pod",
+		"spec": map[string]interface{}{
+			"initContainers": []interface{}{
+				map[string]interface{}{"resources": map[string]interface{}{"requests": map[string]interface{}{"cpu": "2"}}},
+			},
+			"containers": []interface{}{
+				map[string]interface{}{"resources": map[string]interface{}{"requests": map[string]interface{}{"cpu": "500m"}}},
+				map[string]interface{}{"resources": map[string]interface{}{"requests": map[string]interface{}{"cpu": "500m"}}},
+			},
+		},
+	}
+	usage := podUsage(podTemplates(body)[0], quotaResource{field: "requests", resource: "cpu"})
+	assert.Equal(t, "2", formatQuantity("cpu", usage))
+}
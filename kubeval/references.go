@@ -0,0 +1,278 @@
+package kubeval
+
+import (
+	"fmt"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// serviceBackend is a reference from an Ingress to a port of a Service
+type serviceBackend struct {
+	name string
+	// port is either the number or the name of the port
+	port interface{}
+}
+
+// ingressBackends returns the Service backends referenced by an Ingress,
+// in either the networking.k8s.io/v1 form or the older v1beta1 form
+func ingressBackends(body map[string]interface{}) []serviceBackend {
+	spec, _ := getObject(body, "spec")
+	if spec == nil {
+		return nil
+	}
+
+	var backends []interface{}
+	for _, key := range []string{"defaultBackend", "backend"} {
+		if backend, found := spec[key]; found {
+			backends = append(backends, backend)
+		}
+	}
+	rules, _ := spec["rules"].([]interface{})
+	for _, rule := range rules {
+		typed, ok := rule.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		paths, _ := lookupPath(typed, []string{"http", "paths"}).([]interface{})
+		for _, path := range paths {
+			if typed, ok := path.(map[string]interface{}); ok {
+				backends = append(backends, typed["backend"])
+			}
+		}
+	}
+
+	var references []serviceBackend
+	for _, backend := range backends {
+		typed, ok := backend.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if service, _ := getObject(typed, "service"); service != nil {
+			name, _ := getString(service, "name")
+			port, _ := getObject(service, "port")
+			var portRef interface{}
+			if port != nil {
+				portRef = port["number"]
+				if portRef == nil {
+					portRef = port["name"]
+				}
+			}
+			references = append(references, serviceBackend{name: name, port: portRef})
+		} else if name, err := getString(typed, "serviceName"); err == nil {
+			references = append(references, serviceBackend{name: name, port: typed["servicePort"]})
+		}
+	}
+	return references
+}
+
+// exposesPort returns whether a Service exposes the port given by number
+// or by name. A missing port matches any Service.
+func exposesPort(service map[string]interface{}, port interface{}) bool {
+	if port == nil {
+		return true
+	}
+	spec, _ := getObject(service, "spec")
+	if spec == nil {
+		return false
+	}
+	ports, _ := spec["ports"].([]interface{})
+	for _, item := range ports {
+		typed, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if fmt.Sprint(typed["port"]) == fmt.Sprint(port) || (typed["name"] != nil && fmt.Sprint(typed["name"]) == fmt.Sprint(port)) {
+			return true
+		}
+	}
+	return false
+}
+
+// podTemplateLabels returns the labels of each pod template within a
+// workload, or of the pod itself
+func podTemplateLabels(body map[string]interface{}) []map[string]interface{} {
+	var labels []map[string]interface{}
+	for _, template := range podTemplates(body) {
+		if typed, err := getObject(template.metadata, "labels"); err == nil {
+			labels = append(labels, typed)
+		}
+	}
+	return labels
+}
+
+// selectorMatches returns whether every label in selector is in labels
+func selectorMatches(selector, labels map[string]interface{}) bool {
+	for key, value := range selector {
+		if actual, found := labels[key]; !found || fmt.Sprint(actual) != fmt.Sprint(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// formatSelector formats a selector as a stable, comma-separated list
+func formatSelector(selector map[string]interface{}) string {
+	parts := make([]string, 0, len(selector))
+	for _, key := range sortedKeys(selector) {
+		parts = append(parts, fmt.Sprintf("%s=%v", key, selector[key]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// checkReferences checks the references between resources in results:
+// that each Ingress routes to a Service and port defined in the same
+// namespace, and that each Service selects at least one workload.
+func checkReferences(results []ValidationResult, config *Config) error {
+	var errors *multierror.Error
+
+	services := make(map[string]map[string]interface{})
+	var workloads []ValidationResult
+	for _, r := range results {
+		if r.Object == nil {
+			continue
+		}
+		if r.Kind == "Service" && r.APIVersion == "v1" {
+			services[resolveNamespace(r.ResourceNamespace, config)+"/"+r.ResourceName] = r.Object
+		} else if len(podTemplateLabels(r.Object)) > 0 {
+			workloads = append(workloads, r)
+		}
+	}
+
+	for _, r := range results {
+		if r.Object == nil {
+			continue
+		}
+		namespace := resolveNamespace(r.ResourceNamespace, config)
+		switch {
+		case r.Kind == "Ingress":
+			for _, backend := range ingressBackends(r.Object) {
+				service, found := services[namespace+"/"+backend.name]
+				if !found {
+					errors = multierror.Append(errors, fmt.Errorf("%s: Ingress '%s' references Service '%s' which is not defined in namespace '%s'", r.FileName, r.QualifiedName(), backend.name, namespace))
+				} else if !exposesPort(service, backend.port) {
+					errors = multierror.Append(errors, fmt.Errorf("%s: Ingress '%s' references port '%v' of Service '%s' which the Service does not expose", r.FileName, r.QualifiedName(), backend.port, backend.name))
+				}
+			}
+		case r.Kind == "Service" && r.APIVersion == "v1":
+			spec, _ := getObject(r.Object, "spec")
+			if spec == nil {
+				continue
+			}
+			selector, _ := getObject(spec, "selector")
+			if len(selector) == 0 {
+				continue
+			}
+			if !selectsWorkload(selector, namespace, workloads, config) {
+				errors = multierror.Append(errors, fmt.Errorf("%s: Service '%s' selector %s matches no workload in namespace '%s'", r.FileName, r.QualifiedName(), formatSelector(selector), namespace))
+			}
+		}
+	}
+
+	return errors.ErrorOrNil()
+}
+
+// selectsWorkload returns whether the selector matches the pod template
+// of any of the workloads in the namespace
+func selectsWorkload(selector map[string]interface{}, namespace string, workloads []ValidationResult, config *Config) bool {
+	for _, w := range workloads {
+		if resolveNamespace(w.ResourceNamespace, config) != namespace {
+			continue
+		}
+		for _, labels := range podTemplateLabels(w.Object) {
+			if selectorMatches(selector, labels) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// configReference is a reference from a pod spec to a ConfigMap or Secret
+type configReference struct {
+	kind string
+	name string
+}
+
+// podConfigReferences returns the ConfigMaps and Secrets referenced by the
+// pod specs of a workload, through `envFrom`, `env[].valueFrom` and
+// volumes, including projected volumes. References marked optional are
+// left out, as they need not exist.
+func podConfigReferences(body map[string]interface{}) []configReference {
+	var references []configReference
+	add := func(kind string, source interface{}, nameKey string) {
+		typed, ok := source.(map[string]interface{})
+		if !ok || typed["optional"] == true {
+			return
+		}
+		if name, err := getString(typed, nameKey); err == nil && name != "" {
+			references = append(references, configReference{kind: kind, name: name})
+		}
+	}
+
+	for _, c := range podContainers(body) {
+		envFrom, _ := c.container["envFrom"].([]interface{})
+		for _, source := range envFrom {
+			if typed, ok := source.(map[string]interface{}); ok {
+				add("ConfigMap", typed["configMapRef"], "name")
+				add("Secret", typed["secretRef"], "name")
+			}
+		}
+		env, _ := c.container["env"].([]interface{})
+		for _, variable := range env {
+			if typed, ok := variable.(map[string]interface{}); ok {
+				add("ConfigMap", lookupPath(typed, []string{"valueFrom", "configMapKeyRef"}), "name")
+				add("Secret", lookupPath(typed, []string{"valueFrom", "secretKeyRef"}), "name")
+			}
+		}
+	}
+	for _, template := range podTemplates(body) {
+		volumes, _ := template.spec["volumes"].([]interface{})
+		for _, volume := range volumes {
+			typed, ok := volume.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			add("ConfigMap", typed["configMap"], "name")
+			add("Secret", typed["secret"], "secretName")
+			sources, _ := lookupPath(typed, []string{"projected", "sources"}).([]interface{})
+			for _, source := range sources {
+				if typed, ok := source.(map[string]interface{}); ok {
+					add("ConfigMap", typed["configMap"], "name")
+					add("Secret", typed["secret"], "name")
+				}
+			}
+		}
+	}
+	return references
+}
+
+// checkConfigReferences checks that every ConfigMap and Secret referenced
+// by a workload is defined in the same namespace within results
+func checkConfigReferences(results []ValidationResult, config *Config) error {
+	var errors *multierror.Error
+
+	defined := make(map[string]bool)
+	for _, r := range results {
+		if r.Object != nil && r.APIVersion == "v1" && (r.Kind == "ConfigMap" || r.Kind == "Secret") {
+			defined[r.Kind+"/"+resolveNamespace(r.ResourceNamespace, config)+"/"+r.ResourceName] = true
+		}
+	}
+
+	for _, r := range results {
+		if r.Object == nil {
+			continue
+		}
+		namespace := resolveNamespace(r.ResourceNamespace, config)
+		reported := make(map[configReference]bool)
+		for _, reference := range podConfigReferences(r.Object) {
+			if reported[reference] || defined[reference.kind+"/"+namespace+"/"+reference.name] {
+				continue
+			}
+			reported[reference] = true
+			errors = multierror.Append(errors, fmt.Errorf("%s: %s '%s' references %s '%s' which is not defined in namespace '%s'", r.FileName, r.Kind, r.QualifiedName(), reference.kind, reference.name, namespace))
+		}
+	}
+
+	return errors.ErrorOrNil()
+}
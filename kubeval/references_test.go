@@ -0,0 +1,85 @@
+package kubeval
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckReferences(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/references.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "references.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	config.IgnoreMissingSchemas = true
+	config.CheckReferences = true
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	err = CheckResourceSet(results, config)
+	assert.EqualError(t, err, "references.yaml: Service 'api' selector app=api matches no workload in namespace 'default'\n"+
+		"references.yaml: Ingress 'web' references port '8443' of Service 'web' which the Service does not expose\n"+
+		"references.yaml: Ingress 'web' references Service 'static' which is not defined in namespace 'default'")
+
+	config.CheckReferences = false
+	assert.NoError(t, CheckResourceSet(results, config))
+}
+
+func TestIngressBackends(t *testing.T) {
+	body := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"defaultBackend": map[string]interface{}{
+				"service": map[string]interface{}{
+					"name": "default",
+					"port": map[string]interface{}{"number": 80},
+				},
+			},
+			"rules": []interface{}{
+				map[string]interface{}{
+					"http": map[string]interface{}{
+						"paths": []interface{}{
+							map[string]interface{}{
+								"backend": map[string]interface{}{
+									"serviceName": "legacy",
+									"servicePort": "http",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	assert.Equal(t, []serviceBackend{
+		{name: "default", port: 80},
+		{name: "legacy", port: "http"},
+	}, ingressBackends(body))
+}
+
+func TestCheckConfigReferences(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/config_references.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "config_references.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	config.IgnoreMissingSchemas = true
+	config.CheckConfigReferences = true
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	err = CheckResourceSet(results, config)
+	assert.EqualError(t, err, "config_references.yaml: Deployment 'web' references Secret 'credentials' which is not defined in namespace 'default'\n"+
+		"config_references.yaml: Deployment 'web' references Secret 'api-token' which is not defined in namespace 'default'\n"+
+		"config_references.yaml: Deployment 'web' references Secret 'web-tls' which is not defined in namespace 'default'\n"+
+		"config_references.yaml: Deployment 'web' references ConfigMap 'ca-bundle' which is not defined in namespace 'default'")
+
+	config.CheckConfigReferences = false
+	assert.NoError(t, CheckResourceSet(results, config))
+}
@@ -0,0 +1,205 @@
+package kubeval
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// kubernetesRefPattern matches a $ref to the definition of a Kubernetes
+// type, such as #/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta
+// or _definitions.json#/definitions/io.k8s.api.core.v1.PodSpec
+var kubernetesRefPattern = regexp.MustCompile(`^(?:[^#]*_definitions\.json)?#/definitions/(io\.k8s\..+)$`)
+
+// kubernetesDefinitionsURL returns the URL of the file defining the
+// Kubernetes types, such as ObjectMeta, for the version of Kubernetes in
+// use. It is part of the schemas which are not standalone, alongside the
+// standalone ones.
+func kubernetesDefinitionsURL(config *Config) string {
+	normalisedVersion := config.KubernetesVersion
+	if normalisedVersion != "master" {
+		normalisedVersion = "v" + normalisedVersion
+	}
+	strictSuffix := ""
+	if config.Strict {
+		strictSuffix = "-strict"
+	}
+	return fmt.Sprintf("%s/%s%s/_definitions.json", determineSchemaBaseURL(config), normalisedVersion, strictSuffix)
+}
+
+// resolveKubernetesRefs points the $refs in document to Kubernetes
+// definitions which it does not define itself at the file defining them,
+// so that schemas of custom resources reusing core types, such as
+// ObjectMeta, validate them fully. A ref to a file, such as
+// _definitions.json#/definitions/..., is resolved against ref, the URL of
+// the schema itself, if loads says the file can be loaded from there, as
+// with the schemas in a schema map. Otherwise, as for a ref within the
+// schema itself, the definitions at definitionsURL are used. The names of
+// the definitions referenced are returned, keyed by the URL of the file
+// they are referenced in.
+func resolveKubernetesRefs(document interface{}, ref, definitionsURL string, loads func(location string) bool) map[string][]string {
+	root, _ := document.(map[string]interface{})
+	local, _ := root["definitions"].(map[string]interface{})
+	base, _ := url.Parse(ref)
+	names := make(map[string]map[string]bool)
+
+	var walk func(node interface{})
+	walk = func(node interface{}) {
+		switch typed := node.(type) {
+		case map[string]interface{}:
+			if value, ok := typed["$ref"].(string); ok {
+				if found := kubernetesRefPattern.FindStringSubmatch(value); found != nil {
+					file := value[:strings.Index(value, "#")]
+					if _, defined := local[found[1]]; !defined || file != "" {
+						target := definitionsURL
+						if file != "" && base != nil {
+							if resolved, err := base.Parse(file); err == nil && loads(resolved.String()) {
+								target = resolved.String()
+							}
+						}
+						typed["$ref"] = target + "#/definitions/" + found[1]
+						if names[target] == nil {
+							names[target] = make(map[string]bool)
+						}
+						names[target][found[1]] = true
+					}
+				}
+			}
+			for _, child := range typed {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range typed {
+				walk(child)
+			}
+		}
+	}
+	walk(document)
+
+	referenced := make(map[string][]string, len(names))
+	for target, defined := range names {
+		for name := range defined {
+			referenced[target] = append(referenced[target], name)
+		}
+		sort.Strings(referenced[target])
+	}
+	return referenced
+}
+
+// kubernetesDefinitions is a file of Kubernetes definitions, such as
+// _definitions.json, as loaded by loadKubernetesDefinitions
+type kubernetesDefinitions struct {
+	document interface{}
+	listed   bool
+	err      error
+}
+
+// loadKubernetesDefinitions loads the Kubernetes definitions at location,
+// verifying them against Config.SchemaChecksums, as schemas are
+func loadKubernetesDefinitions(location string, config *Config) kubernetesDefinitions {
+	if config.SchemaChecksums == "" {
+		release := fetchSlot(location, config)
+		document, err := gojsonschema.NewReferenceLoader(location).LoadJSON()
+		release()
+		return kubernetesDefinitions{document: document, listed: true, err: err}
+	}
+	verified, listed, err := verifiedSchemaLoader(location, config)
+	if err != nil {
+		return kubernetesDefinitions{err: err}
+	}
+	document, err := verified.LoadJSON()
+	return kubernetesDefinitions{document: document, listed: listed, err: err}
+}
+
+// compileSchema compiles the schema loaded by loader from ref, resolving
+// any references it makes to Kubernetes definitions, see
+// resolveKubernetesRefs, and other relative references against ref. A
+// schema which was found but is malformed is reported with a
+// schemaCompileError. The Kubernetes definitions are verified against
+// Config.SchemaChecksums, as the schema itself is, and the formats of both
+// are renamed to those of the format checkers in use, see renameFormats.
+// The refs of any definitions used which are not listed in the checksums
+// are returned alongside the schema.
+func compileSchema(ref string, loader gojsonschema.JSONLoader, config *Config) (*gojsonschema.Schema, []string, error) {
+	release := fetchSlot(ref, config)
+	document, err := loader.LoadJSON()
+	release()
+	if isMalformedJSON(err) {
+		return nil, nil, &schemaCompileError{ref: ref, err: err}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Each file of definitions is loaded once, whether to see if a ref
+	// resolves to it or to compile the schema with it
+	definitions := make(map[string]kubernetesDefinitions)
+	load := func(location string) kubernetesDefinitions {
+		if _, found := definitions[location]; !found {
+			definitions[location] = loadKubernetesDefinitions(location, config)
+		}
+		return definitions[location]
+	}
+	referenced := resolveKubernetesRefs(document, ref, kubernetesDefinitionsURL(config), func(location string) bool {
+		// definitions which fail verification are there, but cannot be used
+		_, untrusted := load(location).err.(*SchemaChecksumError)
+		return load(location).err == nil || untrusted
+	})
+	formats := formatNames(formatCheckers(config))
+	renameFormats(document, formats)
+
+	locations := make([]string, 0, len(referenced))
+	for location := range referenced {
+		locations = append(locations, location)
+	}
+	sort.Strings(locations)
+
+	schemaLoader := gojsonschema.NewSchemaLoader()
+	var unlisted []string
+	for _, location := range locations {
+		names := referenced[location]
+		loaded := load(location)
+		if checksumErr, ok := loaded.err.(*SchemaChecksumError); ok {
+			return nil, nil, checksumErr
+		}
+		if loaded.err != nil {
+			return nil, nil, &schemaCompileError{ref: ref, err: fmt.Errorf("it references the Kubernetes definition %s, but the definitions at %s could not be loaded: %s", names[0], location, loaded.err)}
+		}
+		if err := checkKubernetesDefinitions(names, location, loaded.document); err != nil {
+			return nil, nil, &schemaCompileError{ref: ref, err: err}
+		}
+		if !loaded.listed {
+			unlisted = append(unlisted, location)
+		}
+		renameFormats(loaded.document, formats)
+		if err := schemaLoader.AddSchema(location, gojsonschema.NewGoLoader(loaded.document)); err != nil {
+			return nil, nil, &schemaCompileError{ref: ref, err: err}
+		}
+	}
+	if err := schemaLoader.AddSchema(ref, gojsonschema.NewGoLoader(document)); err != nil {
+		return nil, nil, &schemaCompileError{ref: ref, err: err}
+	}
+
+	schema, err := schemaLoader.Compile(gojsonschema.NewReferenceLoader(ref))
+	if err != nil {
+		return nil, nil, &schemaCompileError{ref: ref, err: err}
+	}
+	return schema, unlisted, nil
+}
+
+// checkKubernetesDefinitions returns an error if any of the Kubernetes
+// definitions named are not in document, the definitions at definitionsURL
+func checkKubernetesDefinitions(names []string, definitionsURL string, document interface{}) error {
+	root, _ := document.(map[string]interface{})
+	definitions, _ := root["definitions"].(map[string]interface{})
+	for _, name := range names {
+		if _, found := definitions[name]; !found {
+			return fmt.Errorf("it references the Kubernetes definition %s, which is not in %s", name, definitionsURL)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package kubeval
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateResolvesKubernetesRefs(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/crd_object_meta.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	crdSchemas, _ := filepath.Abs("../fixtures/crd_schemas")
+	config := NewDefaultConfig()
+	config.FileName = "crd_object_meta.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	config.AdditionalSchemaLocations = []string{"file://" + filepath.ToSlash(crdSchemas)}
+
+	results, err := Validate(fileContents, config)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.True(t, results[0].ValidatedAgainstSchema)
+	assert.Empty(t, results[0].Errors)
+
+	if assert.Len(t, results[1].Errors, 1) {
+		assert.Equal(t, "metadata.labels: Invalid type. Expected: object, given: array", results[1].Errors[0].String())
+	}
+
+	if assert.Error(t, results[2].SchemaError) {
+		assert.Contains(t, results[2].SchemaError.Error(), "gadget-example-v1.json: it references the Kubernetes definition io.k8s.api.core.v1.PodSpec, which is not in "+localSchemaLocation()+"/master/_definitions.json")
+	}
+}
+
+func TestKubernetesRefsResolvedAlongsideSchema(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeval-refs-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	schema := `{"properties": {"spec": {"$ref": "_definitions.json#/definitions/io.k8s.example.v1.ThingSpec"}}}`
+	definitions := `{"definitions": {"io.k8s.example.v1.ThingSpec": {"properties": {"size": {"type": "integer"}}}}}`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "thing.json"), []byte(schema), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "_definitions.json"), []byte(definitions), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "schema_map.json"), []byte(`{"example.com/v1/Thing": "thing.json"}`), 0644))
+
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	config.SchemaMap = filepath.Join(dir, "schema_map.json")
+
+	// The definitions next to the schema are used, rather than those of
+	// the Kubernetes schemas in use, which do not have this one
+	results, err := Validate([]byte("apiVersion: example.com/v1\nkind: Thing\nmetadata:\n  name: a\nspec:\n  size: big\n"), config)
+	require.NoError(t, err)
+	assert.NoError(t, results[0].SchemaError)
+	if assert.Len(t, results[0].Errors, 1) {
+		assert.Equal(t, "spec.size: Invalid type. Expected: integer, given: string", results[0].Errors[0].String())
+	}
+}
+
+func TestKubernetesDefinitionsVerifiedAgainstChecksums(t *testing.T) {
+	widget := []byte("apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: small\nspec:\n  size: 1\n")
+	crdSchemas, _ := filepath.Abs("../fixtures/crd_schemas")
+	dir, err := ioutil.TempDir("", "kubeval-checksums-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	config.AdditionalSchemaLocations = []string{"file://" + filepath.ToSlash(crdSchemas)}
+	config.SchemaChecksums = filepath.Join(dir, "sums.txt")
+
+	// definitions which do not match stop validation, like any schema
+	require.NoError(t, ioutil.WriteFile(config.SchemaChecksums, []byte("0000000000000000000000000000000000000000000000000000000000000000  master/_definitions.json\n"), 0644))
+	_, err = Validate(widget, config)
+	checksumErr, ok := err.(*SchemaChecksumError)
+	if assert.True(t, ok, "expected a SchemaChecksumError, got %v", err) {
+		assert.Equal(t, localSchemaLocation()+"/master/_definitions.json", checksumErr.Ref)
+	}
+
+	// and those which are not listed are warned about
+	require.NoError(t, ioutil.WriteFile(config.SchemaChecksums, []byte("# nothing listed\n"), 0644))
+	results, err := Validate(widget, config)
+	require.NoError(t, err)
+	assert.True(t, results[0].ValidatedAgainstSchema)
+	var warnings []string
+	for _, w := range results[0].Warnings {
+		warnings = append(warnings, w.Description())
+	}
+	assert.Contains(t, warnings, "Schema "+localSchemaLocation()+"/master/_definitions.json is not listed in "+config.SchemaChecksums+", so was not verified")
+}
+
+func TestResolveKubernetesRefs(t *testing.T) {
+	document := map[string]interface{}{
+		"definitions": map[string]interface{}{
+			"io.k8s.api.core.v1.Container": map[string]interface{}{"type": "object"},
+		},
+		"properties": map[string]interface{}{
+			"metadata": map[string]interface{}{"$ref": "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"},
+			"template": map[string]interface{}{"$ref": "_definitions.json#/definitions/io.k8s.api.core.v1.PodTemplateSpec"},
+			"sidecar":  map[string]interface{}{"$ref": "#/definitions/io.k8s.api.core.v1.Container"},
+			"options":  map[string]interface{}{"anyOf": []interface{}{map[string]interface{}{"$ref": "#/definitions/options"}}},
+			"spec":     map[string]interface{}{"$ref": "../master/_definitions.json#/definitions/io.k8s.api.core.v1.PodSpec"},
+		},
+	}
+	loads := func(location string) bool {
+		return location == "https://example.com/crds/master/_definitions.json"
+	}
+	referenced := resolveKubernetesRefs(document, "https://example.com/crds/master-standalone/widget.json", "https://example.com/master/_definitions.json", loads)
+	assert.Equal(t, map[string][]string{
+		"https://example.com/master/_definitions.json":      {"io.k8s.api.core.v1.PodTemplateSpec", "io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"},
+		"https://example.com/crds/master/_definitions.json": {"io.k8s.api.core.v1.PodSpec"},
+	}, referenced)
+
+	properties := document["properties"].(map[string]interface{})
+	assert.Equal(t, "https://example.com/master/_definitions.json#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta", lookupPath(properties, []string{"metadata", "$ref"}))
+	// A file of definitions which is not alongside the schema is replaced
+	assert.Equal(t, "https://example.com/master/_definitions.json#/definitions/io.k8s.api.core.v1.PodTemplateSpec", lookupPath(properties, []string{"template", "$ref"}))
+	// while one which is is used
+	assert.Equal(t, "https://example.com/crds/master/_definitions.json#/definitions/io.k8s.api.core.v1.PodSpec", lookupPath(properties, []string{"spec", "$ref"}))
+	// Definitions of the schema itself are left alone
+	assert.Equal(t, "#/definitions/io.k8s.api.core.v1.Container", lookupPath(properties, []string{"sidecar", "$ref"}))
+	assert.Equal(t, "#/definitions/options", properties["options"].(map[string]interface{})["anyOf"].([]interface{})[0].(map[string]interface{})["$ref"])
+}
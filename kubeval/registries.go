@@ -0,0 +1,96 @@
+package kubeval
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// defaultRegistry is the registry images are pulled from when their
+// reference does not name one
+const defaultRegistry = "docker.io"
+
+// imageRepository returns the registry and the full repository, including
+// the registry, of an image reference, applying the same defaults as the
+// container runtime: an image such as nginx:1.17 is docker.io/library/nginx,
+// and myorg/app is docker.io/myorg/app.
+func imageRepository(image string) (string, string) {
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+
+	registry := defaultRegistry
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		registry = parts[0]
+		name = parts[1]
+	} else if len(parts) == 1 {
+		name = "library/" + name
+	}
+	if registry == "index.docker.io" {
+		registry = defaultRegistry
+	}
+	return registry, registry + "/" + name
+}
+
+// imageTag returns the tag of an image reference, or an empty string if it
+// has none. A port of the registry, as in localhost:5000/app, is not a tag.
+func imageTag(image string) string {
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		return name[i+1:]
+	}
+	return ""
+}
+
+// normalizeRegistry returns an entry of Config.AllowedRegistries or
+// Config.DeniedRegistries, which is a registry optionally followed by a
+// path within it, as it is compared with image repositories
+func normalizeRegistry(entry string) string {
+	entry = strings.TrimSuffix(strings.TrimSpace(entry), "/")
+	if entry == "index.docker.io" || strings.HasPrefix(entry, "index.docker.io/") {
+		entry = strings.TrimPrefix(entry, "index.")
+	}
+	return entry
+}
+
+// matchRegistry returns the first of entries which repository is within,
+// or an empty string if there is none
+func matchRegistry(repository string, entries []string) string {
+	for _, entry := range entries {
+		normalized := normalizeRegistry(entry)
+		if normalized == "" {
+			continue
+		}
+		if repository == normalized || strings.HasPrefix(repository, normalized+"/") {
+			return entry
+		}
+	}
+	return ""
+}
+
+// checkImageRegistries ensures that every container image is pulled from
+// one of Config.AllowedRegistries, when any are set, and from none of
+// Config.DeniedRegistries.
+func checkImageRegistries(body map[string]interface{}, config *Config) []gojsonschema.ResultError {
+	var errors []gojsonschema.ResultError
+	for _, c := range containerImages(body) {
+		registry, repository := imageRepository(c.image)
+		if len(config.AllowedRegistries) > 0 && matchRegistry(repository, config.AllowedRegistries) == "" {
+			errors = append(errors, newCheckError("image_registry", c.path, c.image, fmt.Sprintf("Image '%s' of container '%s' is from registry '%s' (%s), which is not allowed by --allowed-registries", c.image, c.container, registry, repository)))
+			continue
+		}
+		if denied := matchRegistry(repository, config.DeniedRegistries); denied != "" {
+			errors = append(errors, newCheckError("image_registry", c.path, c.image, fmt.Sprintf("Image '%s' of container '%s' is from '%s', which is denied by --denied-registries", c.image, c.container, denied)))
+		}
+	}
+	return errors
+}
@@ -0,0 +1,76 @@
+package kubeval
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageRepository(t *testing.T) {
+	for image, expected := range map[string][2]string{
+		"nginx":                              {"docker.io", "docker.io/library/nginx"},
+		"nginx:1.17":                         {"docker.io", "docker.io/library/nginx"},
+		"myorg/app:v1":                       {"docker.io", "docker.io/myorg/app"},
+		"index.docker.io/myorg/app":          {"docker.io", "docker.io/myorg/app"},
+		"gcr.io/project/app@sha256:abc":      {"gcr.io", "gcr.io/project/app"},
+		"localhost/app":                      {"localhost", "localhost/app"},
+		"registry.example.com:5000/team/app": {"registry.example.com:5000", "registry.example.com:5000/team/app"},
+	} {
+		registry, repository := imageRepository(image)
+		assert.Equal(t, expected[0], registry, image)
+		assert.Equal(t, expected[1], repository, image)
+	}
+}
+
+func TestImageTag(t *testing.T) {
+	for image, expected := range map[string]string{
+		"nginx":                            "",
+		"nginx:1.17":                       "1.17",
+		"nginx:latest":                     "latest",
+		"localhost:5000/app":               "",
+		"localhost:5000/app:v1":            "v1",
+		"gcr.io/project/app:v2@sha256:abc": "v2",
+		"gcr.io/project/app@sha256:abc":    "",
+	} {
+		assert.Equal(t, expected, imageTag(image), image)
+	}
+}
+
+func TestCheckImageRegistries(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/image_digests.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "image_digests.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	config.AllowedRegistries = []string{"example.com", "docker.io/library"}
+
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	errors := []string{}
+	for _, e := range results[0].Errors {
+		assert.Equal(t, "image_registry", e.Type())
+		errors = append(errors, e.String())
+	}
+	assert.Equal(t, []string{
+		"spec.template.spec.containers.1.image: Image 'envoyproxy/envoy:latest' of container 'proxy' is from registry 'docker.io' (docker.io/envoyproxy/envoy), which is not allowed by --allowed-registries",
+	}, errors)
+
+	config.AllowedRegistries = nil
+	config.DeniedRegistries = []string{"index.docker.io"}
+	results, err = Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	errors = []string{}
+	for _, e := range results[0].Errors {
+		errors = append(errors, e.String())
+	}
+	assert.Equal(t, []string{
+		"spec.template.spec.containers.0.image: Image 'nginx@sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31' of container 'web' is from 'index.docker.io', which is denied by --denied-registries",
+		"spec.template.spec.containers.1.image: Image 'envoyproxy/envoy:latest' of container 'proxy' is from 'index.docker.io', which is denied by --denied-registries",
+	}, errors)
+}
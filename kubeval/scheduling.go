@@ -0,0 +1,160 @@
+package kubeval
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+var (
+	// qualifiedNamePattern matches the name part of a label or taint key,
+	// which must also be no longer than 63 characters
+	qualifiedNamePattern = regexp.MustCompile(`^([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]$`)
+
+	// dnsSubdomainPattern matches the optional prefix of a label or taint
+	// key, which must also be no longer than 253 characters
+	dnsSubdomainPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// qualifiedKeyError returns why key is not a valid label or taint key, of
+// the form name or prefix/name, or an empty string if it is valid
+func qualifiedKeyError(key string) string {
+	name := key
+	if parts := strings.Split(key, "/"); len(parts) == 2 {
+		prefix := parts[0]
+		name = parts[1]
+		if len(prefix) > 253 || !dnsSubdomainPattern.MatchString(prefix) {
+			return fmt.Sprintf("the prefix '%s' must be a lowercase DNS subdomain of no more than 253 characters", prefix)
+		}
+	} else if len(parts) > 2 {
+		return "a key must be a name with an optional prefix separated by a single '/'"
+	}
+	if len(name) > 63 || !qualifiedNamePattern.MatchString(name) {
+		return fmt.Sprintf("the name '%s' must be 1 to 63 alphanumeric characters, '-', '_' or '.', starting and ending with an alphanumeric character", name)
+	}
+	return ""
+}
+
+// schedulingKeyChecker collects the invalid keys found by
+// checkSchedulingKeys
+type schedulingKeyChecker struct {
+	errors []gojsonschema.ResultError
+}
+
+// checkKey reports key at path if it is not a valid label or taint key
+func (c *schedulingKeyChecker) checkKey(path []string, key string, field string) {
+	if reason := qualifiedKeyError(key); reason != "" {
+		c.errors = append(c.errors, newCheckError("scheduling_key", path, key, fmt.Sprintf("Invalid key '%s' in %s: %s", key, field, reason)))
+	}
+}
+
+// objectList returns the objects in the list at key of object, along with
+// their paths
+func objectList(object map[string]interface{}, path []string, key string) ([]map[string]interface{}, [][]string) {
+	list, _ := object[key].([]interface{})
+	var found []map[string]interface{}
+	var paths [][]string
+	for i, item := range list {
+		if element, ok := item.(map[string]interface{}); ok {
+			found = append(found, element)
+			paths = append(paths, append(append([]string{}, path...), key, strconv.Itoa(i)))
+		}
+	}
+	return found, paths
+}
+
+// childObject returns the object at key of object, along with its path
+func childObject(object map[string]interface{}, path []string, key string) (map[string]interface{}, []string) {
+	found, _ := object[key].(map[string]interface{})
+	return found, append(append([]string{}, path...), key)
+}
+
+// checkMatchExpressions checks the keys of the matchExpressions of a node
+// selector term or label selector
+func (c *schedulingKeyChecker) checkMatchExpressions(selector map[string]interface{}, path []string, field string) {
+	expressions, paths := objectList(selector, path, "matchExpressions")
+	for i, expression := range expressions {
+		if key, ok := expression["key"].(string); ok {
+			c.checkKey(append(paths[i], "key"), key, field)
+		}
+	}
+}
+
+// checkLabelSelector checks the keys of a label selector of a pod affinity
+// term
+func (c *schedulingKeyChecker) checkLabelSelector(selector map[string]interface{}, path []string, field string) {
+	matchLabels, labelsPath := childObject(selector, path, "matchLabels")
+	for _, key := range sortedKeys(matchLabels) {
+		c.checkKey(append(append([]string{}, labelsPath...), key), key, field)
+	}
+	c.checkMatchExpressions(selector, path, field)
+}
+
+// checkPodAffinityTerm checks the label selector and topology key of a pod
+// affinity or anti-affinity term
+func (c *schedulingKeyChecker) checkPodAffinityTerm(term map[string]interface{}, path []string, field string) {
+	selector, selectorPath := childObject(term, path, "labelSelector")
+	c.checkLabelSelector(selector, selectorPath, field)
+	if key, ok := term["topologyKey"].(string); ok {
+		c.checkKey(append(append([]string{}, path...), "topologyKey"), key, field)
+	}
+}
+
+// checkSchedulingKeys ensures that the keys of the node selector, the node
+// and pod affinity match expressions and the tolerations of each pod spec
+// in a resource are valid label and taint keys, as a key with a typo means
+// a pod silently cannot be scheduled where intended.
+func checkSchedulingKeys(body map[string]interface{}) []gojsonschema.ResultError {
+	c := &schedulingKeyChecker{}
+	for _, template := range podTemplates(body) {
+		spec, specPath := template.spec, template.specPath
+
+		nodeSelector, nodeSelectorPath := childObject(spec, specPath, "nodeSelector")
+		for _, key := range sortedKeys(nodeSelector) {
+			c.checkKey(append(append([]string{}, nodeSelectorPath...), key), key, "nodeSelector")
+		}
+
+		affinity, affinityPath := childObject(spec, specPath, "affinity")
+		nodeAffinity, nodeAffinityPath := childObject(affinity, affinityPath, "nodeAffinity")
+		required, requiredPath := childObject(nodeAffinity, nodeAffinityPath, "requiredDuringSchedulingIgnoredDuringExecution")
+		terms, termPaths := objectList(required, requiredPath, "nodeSelectorTerms")
+		for i, term := range terms {
+			c.checkMatchExpressions(term, termPaths[i], "nodeAffinity")
+		}
+		preferred, preferredPaths := objectList(nodeAffinity, nodeAffinityPath, "preferredDuringSchedulingIgnoredDuringExecution")
+		for i, term := range preferred {
+			preference, preferencePath := childObject(term, preferredPaths[i], "preference")
+			c.checkMatchExpressions(preference, preferencePath, "nodeAffinity")
+		}
+
+		for _, field := range []string{"podAffinity", "podAntiAffinity"} {
+			podAffinity, podAffinityPath := childObject(affinity, affinityPath, field)
+			required, requiredPaths := objectList(podAffinity, podAffinityPath, "requiredDuringSchedulingIgnoredDuringExecution")
+			for i, term := range required {
+				c.checkPodAffinityTerm(term, requiredPaths[i], field)
+			}
+			preferred, preferredPaths := objectList(podAffinity, podAffinityPath, "preferredDuringSchedulingIgnoredDuringExecution")
+			for i, weighted := range preferred {
+				term, termPath := childObject(weighted, preferredPaths[i], "podAffinityTerm")
+				c.checkPodAffinityTerm(term, termPath, field)
+			}
+		}
+
+		tolerations, tolerationPaths := objectList(spec, specPath, "tolerations")
+		for i, toleration := range tolerations {
+			key, _ := toleration["key"].(string)
+			keyPath := append(tolerationPaths[i], "key")
+			if key == "" {
+				if operator, _ := toleration["operator"].(string); operator != "Exists" {
+					c.errors = append(c.errors, newCheckError("scheduling_key", keyPath, key, "Toleration key must be set unless the operator is Exists"))
+				}
+				continue
+			}
+			c.checkKey(keyPath, key, "tolerations")
+		}
+	}
+	return c.errors
+}
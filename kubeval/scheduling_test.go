@@ -0,0 +1,49 @@
+package kubeval
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSchedulingKeys(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/scheduling_keys.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "scheduling_keys.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	config.IgnoreMissingSchemas = true
+	config.CheckSchedulingKeys = true
+
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	errors := []string{}
+	for _, e := range results[0].Errors {
+		assert.Equal(t, "scheduling_key", e.Type())
+		errors = append(errors, e.String())
+	}
+	spec := "spec.template.spec."
+	assert.Equal(t, []string{
+		spec + "nodeSelector.disk type: Invalid key 'disk type' in nodeSelector: the name 'disk type' must be 1 to 63 alphanumeric characters, '-', '_' or '.', starting and ending with an alphanumeric character",
+		spec + "affinity.nodeAffinity.requiredDuringSchedulingIgnoredDuringExecution.nodeSelectorTerms.0.matchExpressions.1.key: Invalid key 'Node.Example.com/pool' in nodeAffinity: the prefix 'Node.Example.com' must be a lowercase DNS subdomain of no more than 253 characters",
+		spec + "affinity.podAntiAffinity.preferredDuringSchedulingIgnoredDuringExecution.0.podAffinityTerm.topologyKey: Invalid key 'kubernetes.io/hostname/' in podAntiAffinity: a key must be a name with an optional prefix separated by a single '/'",
+		spec + "tolerations.1.key: Invalid key 'example.com/gpu_' in tolerations: the name 'gpu_' must be 1 to 63 alphanumeric characters, '-', '_' or '.', starting and ending with an alphanumeric character",
+		spec + "tolerations.2.key: Toleration key must be set unless the operator is Exists",
+	}, errors)
+
+	// A toleration without a key tolerates every taint with Exists
+	assert.Empty(t, results[1].Errors)
+}
+
+func TestQualifiedKeyError(t *testing.T) {
+	for _, key := range []string{"app", "kubernetes.io/os", "node-role.kubernetes.io/control-plane", "a_b.c-d"} {
+		assert.Empty(t, qualifiedKeyError(key), key)
+	}
+	for _, key := range []string{"", "-app", "app.", "example.com/", "/app", "a/b/c", "UPPER_Prefix.com/app"} {
+		assert.NotEmpty(t, qualifiedKeyError(key), key)
+	}
+}
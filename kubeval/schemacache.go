@@ -0,0 +1,164 @@
+package kubeval
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// schemaStore holds compiled schemas under the keys schemaCacheKey gives
+// them, with a nil schema recording that there is none for a key
+type schemaStore interface {
+	load(key string) (*gojsonschema.Schema, bool)
+	store(key string, schema *gojsonschema.Schema)
+
+	// unlisted returns the refs stored for the schema under key by
+	// storeUnlisted: those of the schemas it was compiled from which are
+	// not listed in Config.SchemaChecksums, see warnUnlistedSchema
+	unlisted(key string) []string
+	storeUnlisted(key string, refs []string)
+
+	// fetchSlots returns the places among the schemas fetched at once by
+	// the validations sharing the store, of which there are limit
+	fetchSlots(limit int) chan struct{}
+
+	// compiling is held while the schema for key is looked for and
+	// compiled, until the returned function is called, so that validations
+	// sharing the store compile each schema once between them
+	compiling(key string) func()
+}
+
+// schemaMap is a schema cache made by NewSchemaCache, which is used by one
+// validation at a time
+type schemaMap map[string]*gojsonschema.Schema
+
+func (m schemaMap) load(key string) (*gojsonschema.Schema, bool) {
+	schema, ok := m[key]
+	return schema, ok
+}
+
+func (m schemaMap) store(key string, schema *gojsonschema.Schema) {
+	m[key] = schema
+}
+
+// unlistedKeyPrefix prefixes the keys under which a schemaMap stores the
+// unlisted refs of the schema under key, one for each, as the map made by
+// NewSchemaCache only holds schemas
+func unlistedKeyPrefix(key string) string {
+	return "unlisted@" + key + "@"
+}
+
+func (m schemaMap) unlisted(key string) []string {
+	prefix := unlistedKeyPrefix(key)
+	var refs []string
+	for k := range m {
+		if strings.HasPrefix(k, prefix) {
+			refs = append(refs, strings.TrimPrefix(k, prefix))
+		}
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+func (m schemaMap) storeUnlisted(key string, refs []string) {
+	for _, ref := range refs {
+		m[unlistedKeyPrefix(key)+ref] = nil
+	}
+}
+
+func (m schemaMap) fetchSlots(limit int) chan struct{} {
+	return make(chan struct{}, limit)
+}
+
+func (m schemaMap) compiling(key string) func() {
+	return func() {}
+}
+
+// SchemaCache is a cache of compiled schemas which, unlike the maps made by
+// NewSchemaCache, is safe for concurrent use. Validations sharing one run
+// side by side, and compiling a schema is CPU work done once between them:
+// a validation which needs a schema another is compiling waits for it,
+// rather than compiling it again, while schemas for other kinds are
+// compiled in parallel. As with any schema cache, it should only be shared
+// between validations with the same Config.
+type SchemaCache struct {
+	mu           sync.RWMutex
+	schemas      map[string]*gojsonschema.Schema
+	unlistedRefs map[string][]string
+
+	// locks are held while the schema for their key is being compiled
+	locks map[string]*sync.Mutex
+
+	// slots limits the schemas fetched at once, see fetchSlots
+	slots chan struct{}
+}
+
+// NewSharedSchemaCache returns a new, empty SchemaCache, to be used with
+// ValidateWithSharedCache
+func NewSharedSchemaCache() *SchemaCache {
+	return &SchemaCache{
+		schemas:      make(map[string]*gojsonschema.Schema),
+		unlistedRefs: make(map[string][]string),
+		locks:        make(map[string]*sync.Mutex),
+	}
+}
+
+func (c *SchemaCache) load(key string) (*gojsonschema.Schema, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	schema, ok := c.schemas[key]
+	return schema, ok
+}
+
+func (c *SchemaCache) store(key string, schema *gojsonschema.Schema) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.schemas[key] = schema
+}
+
+func (c *SchemaCache) unlisted(key string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.unlistedRefs[key]
+}
+
+func (c *SchemaCache) storeUnlisted(key string, refs []string) {
+	if len(refs) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.unlistedRefs[key] = refs
+}
+
+func (c *SchemaCache) fetchSlots(limit int) chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.slots == nil {
+		c.slots = make(chan struct{}, limit)
+	}
+	return c.slots
+}
+
+func (c *SchemaCache) compiling(key string) func() {
+	c.mu.Lock()
+	lock, found := c.locks[key]
+	if !found {
+		lock = &sync.Mutex{}
+		c.locks[key] = lock
+	}
+	c.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// Len returns the number of schemas in the cache, counting each kind for
+// which no schema was found as well
+func (c *SchemaCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.schemas)
+}
@@ -0,0 +1,155 @@
+package kubeval
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// homogeneousManifests returns count Deployments, each with a Service, as
+// found in a large repository of similar applications
+func homogeneousManifests(count int) []byte {
+	var documents []string
+	for i := 0; i < count; i++ {
+		documents = append(documents, fmt.Sprintf(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app-%[1]d
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: app-%[1]d
+  template:
+    metadata:
+      labels:
+        app: app-%[1]d
+    spec:
+      containers:
+        - name: app
+          image: example.com/app-%[1]d:1.0.0
+          ports:
+            - containerPort: 8080
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: app-%[1]d
+spec:
+  selector:
+    app: app-%[1]d
+  ports:
+    - port: 80
+      targetPort: 8080
+`, i))
+	}
+	return []byte(strings.Join(documents, "---\n"))
+}
+
+func TestValidateWithSharedCacheConcurrently(t *testing.T) {
+	input := homogeneousManifests(5)
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	cache := NewSharedSchemaCache()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			fileConfig := *config
+			fileConfig.FileName = fmt.Sprintf("app-%d.yaml", i)
+			results, err := ValidateWithSharedCache(input, cache, &fileConfig)
+			if assert.NoError(t, err) && assert.Len(t, results, 10) {
+				for _, r := range results {
+					assert.Equal(t, fileConfig.FileName, r.FileName)
+					assert.True(t, r.ValidatedAgainstSchema)
+					assert.Empty(t, r.Errors)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	require.Equal(t, 2, cache.Len())
+	deployment, _ := cache.load("apps/v1/Deployment")
+	service, _ := cache.load("v1/Service")
+	assert.NotNil(t, deployment)
+	assert.NotNil(t, service)
+}
+
+func TestSchemaCacheWaitsForCompilingSchema(t *testing.T) {
+	cache := NewSharedSchemaCache()
+	done := cache.compiling("v1/Service")
+
+	found := make(chan *gojsonschema.Schema)
+	go func() {
+		defer cache.compiling("v1/Service")()
+		schema, _ := cache.load("v1/Service")
+		found <- schema
+	}()
+
+	// The schema is cached before the compiling lock is released, so the
+	// waiting validation finds it rather than compiling it again
+	schema := &gojsonschema.Schema{}
+	cache.store("v1/Service", schema)
+	done()
+	assert.True(t, schema == <-found)
+}
+
+// benchmarkConfig returns the Config of the benchmarks, which each validate
+// the manifests of one application, as if validating each file of a large
+// repository in turn
+func benchmarkConfig() *Config {
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	return config
+}
+
+// BenchmarkValidateRecompilingSchemas compiles the schemas for every
+// validation, as happens without a schema cache
+func BenchmarkValidateRecompilingSchemas(b *testing.B) {
+	input := homogeneousManifests(1)
+	config := benchmarkConfig()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Validate(input, config); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkValidateWithCompiledSchemaCache reuses the schemas compiled by
+// earlier validations
+func BenchmarkValidateWithCompiledSchemaCache(b *testing.B) {
+	input := homogeneousManifests(1)
+	config := benchmarkConfig()
+	cache := NewSharedSchemaCache()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ValidateWithSharedCache(input, cache, config); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkValidateConcurrentlyWithCompiledSchemaCache validates on every
+// CPU at once, sharing the compiled schemas between them
+func BenchmarkValidateConcurrentlyWithCompiledSchemaCache(b *testing.B) {
+	input := homogeneousManifests(1)
+	config := benchmarkConfig()
+	cache := NewSharedSchemaCache()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := ValidateWithSharedCache(input, cache, config); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
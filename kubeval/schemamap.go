@@ -0,0 +1,69 @@
+package kubeval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// unmappedKindError is returned when Config.SchemaMap is set and has no
+// schema for the kind of a resource
+type unmappedKindError struct {
+	versionKind string
+	schemaMap   string
+}
+
+func (e *unmappedKindError) Error() string {
+	return fmt.Sprintf("No schema for %s in the schema map %s", e.versionKind, e.schemaMap)
+}
+
+// readSchemaMap reads a schema map: a JSON object mapping the apiVersion
+// and kind of resources, such as apps/v1/Deployment or v1/Service, to the
+// schema to validate them against
+func readSchemaMap(path string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read schema map ('--schema-map' flag): %s", err)
+	}
+	var schemaMap map[string]string
+	if err := json.Unmarshal(b, &schemaMap); err != nil {
+		return nil, fmt.Errorf("Invalid schema map %s, expected a JSON object mapping apiVersion/kind to a schema path or URL: %s", path, err)
+	}
+	return schemaMap, nil
+}
+
+// loadSchemaMap returns the schema map in Config.SchemaMap, as parsed for
+// the run by parseConfigFiles if it has been
+func loadSchemaMap(config *Config) (map[string]string, error) {
+	if config.parsedSchemaMap != nil {
+		return config.parsedSchemaMap, nil
+	}
+	return readSchemaMap(config.SchemaMap)
+}
+
+// schemaMapRef returns the schema Config.SchemaMap maps the kind of a
+// resource to, which is the only schema used for it. Paths are relative to
+// the directory holding the schema map.
+func schemaMapRef(resource *ValidationResult, config *Config) (string, error) {
+	schemaMap, err := loadSchemaMap(config)
+	if err != nil {
+		return "", err
+	}
+	ref, found := schemaMap[resource.VersionKind()]
+	if !found || ref == "" {
+		return "", &unmappedKindError{versionKind: resource.VersionKind(), schemaMap: config.SchemaMap}
+	}
+	if strings.Contains(ref, "://") {
+		return ref, nil
+	}
+	if !filepath.IsAbs(ref) {
+		dir, err := filepath.Abs(filepath.Dir(config.SchemaMap))
+		if err != nil {
+			return "", err
+		}
+		ref = filepath.Join(dir, ref)
+	}
+	return "file://" + filepath.ToSlash(ref), nil
+}
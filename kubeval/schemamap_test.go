@@ -0,0 +1,110 @@
+package kubeval
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const schemaMapInput = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.17
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports: 80
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+`
+
+func TestSchemaMapResolution(t *testing.T) {
+	config := NewDefaultConfig()
+	config.SchemaMap = "../fixtures/schema_map.json"
+	// The schema map is authoritative, so the schema location is never used
+	config.SchemaLocation = "file:///nonexistent"
+
+	results, err := Validate([]byte(schemaMapInput), config)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.True(t, results[0].ValidatedAgainstSchema)
+	assert.Empty(t, results[0].Errors)
+	assert.True(t, results[1].ValidatedAgainstSchema)
+	assert.Len(t, results[1].Errors, 1)
+	assert.False(t, results[2].ValidatedAgainstSchema)
+	assert.Equal(t, "unvalidated", results[2].Status())
+}
+
+func TestStrictSchemaMap(t *testing.T) {
+	config := NewDefaultConfig()
+	config.SchemaMap = "../fixtures/schema_map.json"
+	config.StrictSchemaMap = true
+	config.IgnoreMissingSchemas = true
+
+	_, err := Validate([]byte(schemaMapInput), config)
+	assert.EqualError(t, err, "stdin: No schema for v1/ConfigMap in the schema map ../fixtures/schema_map.json")
+}
+
+func TestSchemaMapRef(t *testing.T) {
+	dir, _ := filepath.Abs("../fixtures")
+	config := NewDefaultConfig()
+	config.SchemaMap = "../fixtures/schema_map.json"
+
+	ref, err := schemaMapRef(&ValidationResult{APIVersion: "apps/v1", Kind: "Deployment"}, config)
+	assert.NoError(t, err)
+	assert.Equal(t, "file://"+filepath.ToSlash(dir)+"/schemas/master-standalone/deployment-apps-v1.json", ref)
+
+	_, err = schemaMapRef(&ValidationResult{APIVersion: "v1", Kind: "Secret"}, config)
+	assert.IsType(t, &unmappedKindError{}, err)
+
+	config.SchemaMap = "../fixtures/not-here.json"
+	_, err = Validate([]byte(schemaMapInput), config)
+	assert.Error(t, err)
+}
+
+func TestSchemaMapReadOncePerRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeval-schema-map-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	config := NewDefaultConfig()
+	config.SchemaMap = filepath.Join(dir, "schema_map.json")
+	require.NoError(t, ioutil.WriteFile(config.SchemaMap, []byte(`{"v1/Service": "a.json"}`), 0644))
+	service := &ValidationResult{APIVersion: "v1", Kind: "Service"}
+	require.NoError(t, parseConfigFiles(config))
+
+	// The schema map is not read again for each lookup during the run
+	require.NoError(t, ioutil.WriteFile(config.SchemaMap, []byte(`{"v1/Service": "b.json"}`), 0644))
+	ref, err := schemaMapRef(service, config)
+	require.NoError(t, err)
+	assert.Equal(t, "file://"+filepath.ToSlash(dir)+"/a.json", ref)
+
+	// but is by a new one
+	config.parsedSchemaMap = nil
+	require.NoError(t, parseConfigFiles(config))
+	ref, err = schemaMapRef(service, config)
+	require.NoError(t, err)
+	assert.Equal(t, "file://"+filepath.ToSlash(dir)+"/b.json", ref)
+}
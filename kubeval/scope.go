@@ -0,0 +1,49 @@
+package kubeval
+
+// clusterScopedKinds returns the built-in kinds whose resources are not in
+// a namespace, and so have names which are unique across the cluster
+func clusterScopedKinds() []string {
+	return []string{
+		"APIService",
+		"CSIDriver",
+		"CSINode",
+		"CertificateSigningRequest",
+		"ClusterRole",
+		"ClusterRoleBinding",
+		"ComponentStatus",
+		"CustomResourceDefinition",
+		"FlowSchema",
+		"IngressClass",
+		"MutatingWebhookConfiguration",
+		"Namespace",
+		"Node",
+		"PersistentVolume",
+		"PodSecurityPolicy",
+		"PriorityClass",
+		"PriorityLevelConfiguration",
+		"RuntimeClass",
+		"StorageClass",
+		"ValidatingAdmissionPolicy",
+		"ValidatingAdmissionPolicyBinding",
+		"ValidatingWebhookConfiguration",
+		"VolumeAttachment",
+	}
+}
+
+// isClusterScoped returns whether resources of kind are cluster-scoped,
+// either as one of the built-in kinds or as one of Config.ClusterScopedKinds
+func isClusterScoped(kind string, config *Config) bool {
+	return in(clusterScopedKinds(), kind) || in(config.ClusterScopedKinds, kind)
+}
+
+// resourceIdentity returns the key identifying a resource for duplicate
+// detection, and a description of the scope within which it must be
+// unique. The namespace of a cluster-scoped resource is ignored, as the API
+// server ignores it.
+func resourceIdentity(result ValidationResult, name string, config *Config) ([4]string, string) {
+	if isClusterScoped(result.Kind, config) {
+		return [4]string{result.APIVersion, result.Kind, "", name}, "the cluster"
+	}
+	namespace := resolveNamespace(result.ResourceNamespace, config)
+	return [4]string{result.APIVersion, result.Kind, namespace, name}, "namespace '" + namespace + "'"
+}
@@ -0,0 +1,124 @@
+package kubeval
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// labelRequirement is a single requirement of a label selector
+type labelRequirement struct {
+	key      string
+	operator string
+	values   []string
+}
+
+const (
+	selectorEquals       = "="
+	selectorNotEquals    = "!="
+	selectorIn           = "in"
+	selectorNotIn        = "notin"
+	selectorExists       = "exists"
+	selectorDoesNotExist = "!"
+)
+
+var (
+	// setRequirementPattern matches set-based requirements, such as
+	// `tier in (frontend, backend)`
+	setRequirementPattern = regexp.MustCompile(`^([^\s!=()]+)\s+(in|notin)\s+\(([^()]*)\)$`)
+
+	// equalityRequirementPattern matches equality-based requirements, such as
+	// `team=payments`, `team==payments` or `team!=payments`
+	equalityRequirementPattern = regexp.MustCompile(`^([^\s!=()]+)\s*(!=|==|=)\s*([^\s!=(),]*)$`)
+
+	// existenceRequirementPattern matches `team` and `!team`
+	existenceRequirementPattern = regexp.MustCompile(`^(!?)\s*([^\s!=()]+)$`)
+)
+
+// parseSelector parses a label selector in the syntax used by kubectl,
+// a comma-separated list of requirements which must all be met. Both
+// equality-based and set-based requirements are supported.
+func parseSelector(selector string) ([]labelRequirement, error) {
+	var requirements []labelRequirement
+	for _, part := range splitSelector(selector) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("Invalid selector '%s' ('--selector' flag): empty requirement", selector)
+		}
+		if found := setRequirementPattern.FindStringSubmatch(part); found != nil {
+			var values []string
+			for _, value := range strings.Split(found[3], ",") {
+				values = append(values, strings.TrimSpace(value))
+			}
+			requirements = append(requirements, labelRequirement{key: found[1], operator: found[2], values: values})
+		} else if found := equalityRequirementPattern.FindStringSubmatch(part); found != nil {
+			operator := found[2]
+			if operator == "==" {
+				operator = selectorEquals
+			}
+			requirements = append(requirements, labelRequirement{key: found[1], operator: operator, values: []string{found[3]}})
+		} else if found := existenceRequirementPattern.FindStringSubmatch(part); found != nil {
+			operator := selectorExists
+			if found[1] == "!" {
+				operator = selectorDoesNotExist
+			}
+			requirements = append(requirements, labelRequirement{key: found[2], operator: operator})
+		} else {
+			return nil, fmt.Errorf("Invalid selector '%s' ('--selector' flag): could not parse '%s'", selector, part)
+		}
+	}
+	return requirements, nil
+}
+
+// splitSelector splits a selector on the commas between requirements,
+// ignoring those within the parentheses of set-based requirements
+func splitSelector(selector string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range selector {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, selector[start:])
+}
+
+// matches returns whether the labels meet the requirement
+func (r labelRequirement) matches(labels map[string]interface{}) bool {
+	value, found := labels[r.key]
+	actual := fmt.Sprint(value)
+	switch r.operator {
+	case selectorEquals:
+		return found && actual == r.values[0]
+	case selectorNotEquals:
+		return !found || actual != r.values[0]
+	case selectorIn:
+		return found && in(r.values, actual)
+	case selectorNotIn:
+		return !found || !in(r.values, actual)
+	case selectorExists:
+		return found
+	default:
+		return !found
+	}
+}
+
+// matchesSelector returns whether the `metadata.labels` of the resource
+// meet all of the requirements of the selector
+func matchesSelector(body map[string]interface{}, requirements []labelRequirement) bool {
+	labels, _ := lookupPath(body, []string{"metadata", "labels"}).(map[string]interface{})
+	for _, requirement := range requirements {
+		if !requirement.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
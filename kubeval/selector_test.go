@@ -0,0 +1,88 @@
+package kubeval
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchesSelector(t *testing.T) {
+	body := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{
+				"team": "payments",
+				"tier": "web",
+			},
+		},
+	}
+	var tests = []struct {
+		selector string
+		expected bool
+	}{
+		{"team=payments", true},
+		{"team==payments", true},
+		{"team=search", false},
+		{"team!=search", true},
+		{"team=payments,tier=web", true},
+		{"team=payments,tier=db", false},
+		{"tier in (web, db)", true},
+		{"tier in (db)", false},
+		{"tier notin (db)", true},
+		{"team=payments,tier notin (web,db)", false},
+		{"team", true},
+		{"!team", false},
+		{"!owner", true},
+		{"owner!=someone", true},
+		{"owner in (someone)", false},
+	}
+	for _, test := range tests {
+		requirements, err := parseSelector(test.selector)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %s: %v", test.selector, err)
+			continue
+		}
+		assert.Equal(t, test.expected, matchesSelector(body, requirements), test.selector)
+	}
+
+	requirements, _ := parseSelector("team=payments")
+	assert.False(t, matchesSelector(map[string]interface{}{}, requirements), "resources without labels")
+}
+
+func TestParseInvalidSelector(t *testing.T) {
+	for _, selector := range []string{"team=payments,", "tier in web", "team=a=b", "(team)"} {
+		_, err := parseSelector(selector)
+		assert.Error(t, err, selector)
+	}
+}
+
+func TestValidateWithSelector(t *testing.T) {
+	input := []byte(`apiVersion: v1
+kind: Service
+metadata:
+  name: payments
+  labels:
+    team: payments
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: search
+  labels:
+    team: search
+`)
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	config.Selector = "team=payments"
+	results, err := Validate(input, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, "valid", results[0].Status())
+		assert.Equal(t, "skipped", results[1].Status())
+	}
+
+	config.Selector = "team in payments"
+	_, err = Validate(input, config)
+	assert.Error(t, err)
+}
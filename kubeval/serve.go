@@ -0,0 +1,108 @@
+package kubeval
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// ServeRequest is a request to validate a manifest, read from a connection
+// to a Server as a single line of JSON
+type ServeRequest struct {
+	// FileName is reported in the results, defaulting to Config.FileName
+	FileName string `json:"filename"`
+	// Content is the manifest to validate, which may hold several documents
+	Content string `json:"content"`
+}
+
+// ServeResponse is written in reply to each ServeRequest as a single line
+// of JSON
+type ServeResponse struct {
+	// Results hold a result for each document, as in the json output
+	Results []EvalResult `json:"results"`
+	// Error is set if the manifest could not be validated, or if it failed
+	// any of the checks across resources
+	Error string `json:"error,omitempty"`
+	// Warnings are the problems found by the checks across resources which
+	// do not fail validation, see CheckResourceSetWarnings
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Server validates manifests sent to it over connections, such as those on
+// a Unix domain socket, with a single Validator, so that schemas are only
+// loaded once for the life of the server
+type Server struct {
+	validator *Validator
+}
+
+// NewServer returns a Server which validates manifests using config
+func NewServer(config *Config) *Server {
+	return &Server{
+		validator: NewValidator(config),
+	}
+}
+
+// Serve accepts connections on listener and serves each of them
+// concurrently with ServeConn, until listener is closed
+func (s *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go s.ServeConn(conn)
+	}
+}
+
+// ServeConn reads newline-framed JSON requests from conn, and writes a
+// newline-framed JSON response for each, until conn is closed. Blank lines
+// are ignored, and a request which cannot be decoded is answered with an
+// error rather than closing the connection.
+func (s *Server) ServeConn(conn io.ReadWriteCloser) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	encoder := json.NewEncoder(conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if encodeErr := encoder.Encode(s.handle(line)); encodeErr != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// handle validates the manifest in a single request line
+func (s *Server) handle(line []byte) ServeResponse {
+	response := ServeResponse{Results: []EvalResult{}}
+
+	var request ServeRequest
+	if err := json.Unmarshal(line, &request); err != nil {
+		response.Error = "invalid request: " + err.Error()
+		return response
+	}
+
+	results, err := s.validator.Validate([]byte(request.Content), request.FileName)
+	if err == nil {
+		err = s.validator.CheckResourceSet(results)
+	}
+	if err != nil {
+		response.Error = err.Error()
+	}
+	if merr, ok := s.validator.CheckResourceSetWarnings(results).(*multierror.Error); ok {
+		response.Warnings = errorStrings(merr.Errors)
+	}
+
+	for _, r := range results {
+		response.Results = append(response.Results, newEvalResult(r))
+	}
+	return response
+}
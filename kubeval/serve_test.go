@@ -0,0 +1,62 @@
+package kubeval
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerServeConn(t *testing.T) {
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	config.FileName = "editor"
+	server := NewServer(config)
+
+	client, conn := net.Pipe()
+	go server.ServeConn(conn)
+	defer client.Close()
+
+	reader := bufio.NewReader(client)
+	send := func(line string) ServeResponse {
+		_, err := client.Write([]byte(line + "\n"))
+		require.NoError(t, err)
+		reply, err := reader.ReadBytes('\n')
+		require.NoError(t, err)
+		var response ServeResponse
+		require.NoError(t, json.Unmarshal(reply, &response))
+		return response
+	}
+
+	request, _ := json.Marshal(ServeRequest{
+		FileName: "service.yaml",
+		Content:  "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\nspec:\n  ports: 80\n",
+	})
+	response := send(string(request))
+	assert.Empty(t, response.Error)
+	require.Len(t, response.Results, 1)
+	assert.Equal(t, "service.yaml", response.Results[0].Filename)
+	assert.EqualValues(t, statusInvalid, response.Results[0].Status)
+	cached, _ := server.validator.schemaCache.load("v1/Service")
+	assert.NotNil(t, cached)
+
+	// A second request on the same connection reuses the cached schema, and
+	// falls back to the configured file name
+	request, _ = json.Marshal(ServeRequest{
+		Content: "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n",
+	})
+	response = send(string(request))
+	assert.Empty(t, response.Error)
+	require.Len(t, response.Results, 1)
+	assert.Equal(t, "editor", response.Results[0].Filename)
+	assert.EqualValues(t, statusValid, response.Results[0].Status)
+
+	// Requests which cannot be decoded are answered rather than closing
+	// the connection
+	response = send("not json")
+	assert.Contains(t, response.Error, "invalid request")
+	assert.Empty(t, response.Results)
+}
@@ -0,0 +1,218 @@
+package kubeval
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// The structural schema rules below are those the API server enforces for
+// the schemas embedded in a CustomResourceDefinition, which are a restricted
+// subset of OpenAPI v3. See
+// https://kubernetes.io/docs/tasks/extend-kubernetes/custom-resources/custom-resource-definitions/#specifying-a-structural-schema
+
+// structuralJunctors are the logical junctors, within which a structural
+// schema may only repeat what is specified outside of them
+var structuralJunctors = []string{"allOf", "anyOf", "oneOf", "not"}
+
+// structuralForbiddenKeys may not be used anywhere in a structural schema
+var structuralForbiddenKeys = []string{"$ref", "$schema", "additionalItems", "definitions", "dependencies", "id", "patternProperties"}
+
+// structuralJunctorForbiddenKeys may not be used within a logical junctor
+var structuralJunctorForbiddenKeys = []string{"additionalProperties", "default", "description", "nullable", "type"}
+
+// crdSchema is a schema embedded in a CustomResourceDefinition
+type crdSchema struct {
+	path   []string
+	schema map[string]interface{}
+}
+
+// crdSchemas returns the schemas embedded in a CustomResourceDefinition, in
+// both the apiextensions.k8s.io/v1 form and the older v1beta1 one
+func crdSchemas(body map[string]interface{}) []crdSchema {
+	var schemas []crdSchema
+	validationPath := []string{"spec", "validation", "openAPIV3Schema"}
+	if schema, ok := lookupPath(body, validationPath).(map[string]interface{}); ok {
+		schemas = append(schemas, crdSchema{path: validationPath, schema: schema})
+	}
+	versions, _ := lookupPath(body, []string{"spec", "versions"}).([]interface{})
+	for i, version := range versions {
+		typed, _ := version.(map[string]interface{})
+		if schema, ok := lookupPath(typed, []string{"schema", "openAPIV3Schema"}).(map[string]interface{}); ok {
+			path := []string{"spec", "versions", strconv.Itoa(i), "schema", "openAPIV3Schema"}
+			schemas = append(schemas, crdSchema{path: path, schema: schema})
+		}
+	}
+	return schemas
+}
+
+// structuralChecker collects the structural schema rules a schema breaks
+type structuralChecker struct {
+	errors []gojsonschema.ResultError
+}
+
+func (c *structuralChecker) violation(path []string, value interface{}, rule string) {
+	c.errors = append(c.errors, newCheckError("structural_schema", path, value, "Not a structural schema: "+rule))
+}
+
+// checkStructuralSchemas checks that the schemas embedded in a
+// CustomResourceDefinition are structural, as the API server requires
+func checkStructuralSchemas(body map[string]interface{}) []gojsonschema.ResultError {
+	c := &structuralChecker{}
+	for _, embedded := range crdSchemas(body) {
+		root, path := embedded.schema, embedded.path
+		if root["type"] != "object" {
+			c.violation(append(path, "type"), root["type"], "the type at the root must be object")
+		}
+		c.checkNode(root, path, nil, false)
+		c.checkMetadata(root, path)
+	}
+	return c.errors
+}
+
+// checkNode checks the schema node at path. Within a logical junctor, outer
+// is the node specified outside of the junctors at the same position, which
+// must specify every field the node does.
+func (c *structuralChecker) checkNode(node map[string]interface{}, path []string, outer map[string]interface{}, inJunctor bool) {
+	for _, key := range structuralForbiddenKeys {
+		if _, found := node[key]; found {
+			c.violation(append(path, key), node[key], fmt.Sprintf("%s is not supported", key))
+		}
+	}
+	if node["uniqueItems"] == true {
+		c.violation(append(path, "uniqueItems"), true, "uniqueItems cannot be set to true")
+	}
+	if _, found := node["properties"]; found {
+		if _, found := node["additionalProperties"]; found {
+			c.violation(append(path, "additionalProperties"), node["additionalProperties"], "additionalProperties and properties are mutually exclusive")
+		}
+	}
+	if node["x-kubernetes-int-or-string"] == true {
+		if _, found := node["type"]; found {
+			c.violation(append(path, "type"), node["type"], "type must be empty when x-kubernetes-int-or-string is true")
+		}
+	}
+
+	if inJunctor {
+		for _, key := range structuralJunctorForbiddenKeys {
+			if _, found := node[key]; found {
+				c.violation(append(path, key), node[key], fmt.Sprintf("%s must not be set within allOf, anyOf, oneOf or not", key))
+			}
+		}
+	}
+
+	properties, _ := node["properties"].(map[string]interface{})
+	for _, name := range sortedKeys(properties) {
+		child, ok := properties[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		childPath := append(append([]string{}, path...), "properties", name)
+		if inJunctor {
+			outerChild, found := lookupPath(outer, []string{"properties", name}).(map[string]interface{})
+			if !found {
+				c.violation(childPath, nil, fmt.Sprintf("the field %s must also be specified outside of allOf, anyOf, oneOf or not", name))
+			}
+			c.checkNode(child, childPath, outerChild, true)
+			continue
+		}
+		c.checkType(child, childPath)
+		c.checkNode(child, childPath, nil, false)
+	}
+
+	if child, ok := node["additionalProperties"].(map[string]interface{}); ok && !inJunctor {
+		childPath := append(append([]string{}, path...), "additionalProperties")
+		c.checkType(child, childPath)
+		c.checkNode(child, childPath, nil, false)
+	}
+
+	switch items := node["items"].(type) {
+	case []interface{}:
+		c.violation(append(path, "items"), nil, "items must be a single schema rather than a list")
+	case map[string]interface{}:
+		childPath := append(append([]string{}, path...), "items")
+		if inJunctor {
+			outerItems, found := lookupPath(outer, []string{"items"}).(map[string]interface{})
+			if !found {
+				c.violation(childPath, nil, "items must also be specified outside of allOf, anyOf, oneOf or not")
+			}
+			c.checkNode(items, childPath, outerItems, true)
+		} else {
+			c.checkType(items, childPath)
+			c.checkNode(items, childPath, nil, false)
+		}
+	}
+
+	// Within the junctors, the node is compared against the outer node, or
+	// this node itself when it is not already within a junctor
+	if !inJunctor {
+		outer = node
+	}
+	intOrString := node["x-kubernetes-int-or-string"] == true
+	for _, junctor := range structuralJunctors {
+		var schemas []interface{}
+		if junctor == "not" {
+			schemas = []interface{}{node[junctor]}
+		} else {
+			schemas, _ = node[junctor].([]interface{})
+		}
+		for i, schema := range schemas {
+			child, ok := schema.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			childPath := append(append([]string{}, path...), junctor)
+			if junctor != "not" {
+				childPath = append(childPath, strconv.Itoa(i))
+			}
+			// x-kubernetes-int-or-string allows its type to be described by
+			// anyOf or allOf, such as anyOf: [{type: integer}, {type: string}]
+			if intOrString && (junctor == "anyOf" || junctor == "allOf") && isIntOrStringJunctor(child) {
+				continue
+			}
+			c.checkNode(child, childPath, outer, true)
+		}
+	}
+}
+
+// isIntOrStringJunctor returns whether a schema within a logical junctor
+// only gives the integer or string type of an x-kubernetes-int-or-string
+func isIntOrStringJunctor(schema map[string]interface{}) bool {
+	if len(schema) != 1 {
+		return false
+	}
+	return schema["type"] == "integer" || schema["type"] == "string"
+}
+
+// checkType checks that a field or array item specifies a type, unless it
+// is an x-kubernetes-int-or-string or x-kubernetes-preserve-unknown-fields
+func (c *structuralChecker) checkType(node map[string]interface{}, path []string) {
+	if node["x-kubernetes-int-or-string"] == true || node["x-kubernetes-preserve-unknown-fields"] == true {
+		return
+	}
+	if t, _ := node["type"].(string); t == "" {
+		c.violation(path, nil, "type must not be empty, unless x-kubernetes-int-or-string or x-kubernetes-preserve-unknown-fields is true")
+	}
+}
+
+// checkMetadata checks that the schema of metadata, if given, only
+// restricts the name and generateName
+func (c *structuralChecker) checkMetadata(root map[string]interface{}, path []string) {
+	metadata, ok := lookupPath(root, []string{"properties", "metadata"}).(map[string]interface{})
+	if !ok {
+		return
+	}
+	metadataPath := append(append([]string{}, path...), "properties", "metadata")
+	for _, key := range sortedKeys(metadata) {
+		if key != "type" && key != "properties" {
+			c.violation(append(metadataPath, key), metadata[key], "only restrictions on metadata.name and metadata.generateName are allowed")
+		}
+	}
+	properties, _ := metadata["properties"].(map[string]interface{})
+	for _, name := range sortedKeys(properties) {
+		if name != "name" && name != "generateName" {
+			c.violation(append(metadataPath, "properties", name), nil, "only restrictions on metadata.name and metadata.generateName are allowed")
+		}
+	}
+}
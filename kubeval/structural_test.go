@@ -0,0 +1,70 @@
+package kubeval
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckStructuralSchemas(t *testing.T) {
+	filePath, _ := filepath.Abs("../fixtures/crd_non_structural.yaml")
+	fileContents, _ := ioutil.ReadFile(filePath)
+	config := NewDefaultConfig()
+	config.FileName = "crd_non_structural.yaml"
+	config.SchemaLocation = localSchemaLocation()
+	config.IgnoreMissingSchemas = true
+	config.CheckStructuralSchemas = true
+
+	results, err := Validate(fileContents, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	errors := []string{}
+	for _, e := range results[0].Errors {
+		assert.Equal(t, "structural_schema", e.Type())
+		errors = append(errors, e.String())
+	}
+	schema := "spec.versions.0.schema.openAPIV3Schema."
+	assert.Equal(t, []string{
+		schema + "properties.spec.properties.color: Not a structural schema: type must not be empty, unless x-kubernetes-int-or-string or x-kubernetes-preserve-unknown-fields is true",
+		schema + "properties.spec.properties.tags.uniqueItems: Not a structural schema: uniqueItems cannot be set to true",
+		schema + "properties.spec.anyOf.0.properties.shape: Not a structural schema: the field shape must also be specified outside of allOf, anyOf, oneOf or not",
+		schema + "properties.spec.anyOf.0.properties.shape.type: Not a structural schema: type must not be set within allOf, anyOf, oneOf or not",
+		schema + "properties.spec.anyOf.0.properties.size.description: Not a structural schema: description must not be set within allOf, anyOf, oneOf or not",
+		schema + "properties.metadata.properties.labels: Not a structural schema: only restrictions on metadata.name and metadata.generateName are allowed",
+		"spec.versions.1.schema.openAPIV3Schema.properties.spec: Not a structural schema: type must not be empty, unless x-kubernetes-int-or-string or x-kubernetes-preserve-unknown-fields is true",
+		"spec.versions.1.schema.openAPIV3Schema.properties.spec.$ref: Not a structural schema: $ref is not supported",
+	}, errors)
+
+	config.CheckStructuralSchemas = false
+	results, _ = Validate(fileContents, config)
+	assert.Empty(t, results[0].Errors)
+}
+
+func TestCheckStructuralSchemasLegacyValidation(t *testing.T) {
+	body := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"validation": map[string]interface{}{
+				"openAPIV3Schema": map[string]interface{}{
+					"properties": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"type":                 "object",
+							"properties":           map[string]interface{}{},
+							"additionalProperties": map[string]interface{}{"type": "string"},
+						},
+					},
+				},
+			},
+		},
+	}
+	errors := []string{}
+	for _, e := range checkStructuralSchemas(body) {
+		errors = append(errors, e.String())
+	}
+	assert.Equal(t, []string{
+		"spec.validation.openAPIV3Schema.type: Not a structural schema: the type at the root must be object",
+		"spec.validation.openAPIV3Schema.properties.spec.additionalProperties: Not a structural schema: additionalProperties and properties are mutually exclusive",
+	}, errors)
+}
@@ -2,9 +2,13 @@ package kubeval
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"runtime"
+	"sort"
 	"strings"
+
+	"sigs.k8s.io/yaml"
 )
 
 func getObject(body map[string]interface{}, key string) (map[string]interface{}, error) {
@@ -81,3 +85,54 @@ func in(set []string, key string) bool {
 	}
 	return false
 }
+
+// sortedKeys returns the keys of the map in a stable order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// errorStrings returns the messages of each of the errors
+func errorStrings(errs []error) []string {
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = e.Error()
+	}
+	return messages
+}
+
+// isCommentOnly returns whether the document contains nothing but
+// YAML comments and blank lines
+func isCommentOnly(document []byte) bool {
+	for _, line := range strings.Split(string(document), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) > 0 && !strings.HasPrefix(line, "#") {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveNamespace returns the namespace, or the default namespace if
+// unset, as happens when applying resources with `kubectl apply --namespace`
+func resolveNamespace(namespace string, config *Config) string {
+	if len(namespace) > 0 {
+		return namespace
+	}
+	return config.DefaultNamespace
+}
+
+// unmarshalDocument decodes a single document, which may be either JSON
+// or YAML. Documents which look like JSON are decoded as such, as JSON
+// allows some things YAML does not, such as escaping `/` as `\/`.
+func unmarshalDocument(document []byte, v interface{}) error {
+	trimmed := bytes.TrimSpace(document)
+	if (bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("["))) && json.Valid(trimmed) {
+		return json.Unmarshal(trimmed, v)
+	}
+	return yaml.Unmarshal(document, v)
+}
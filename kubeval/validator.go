@@ -0,0 +1,63 @@
+package kubeval
+
+// Validator validates manifests with a single Config, keeping the schemas
+// it loads in a cache of its own. Validators share no state, so several with
+// different configurations can be used side by side in one process, and
+// each is safe for concurrent use.
+type Validator struct {
+	config *Config
+
+	// schemaCache is shared by all the validations of the Validator, which
+	// run concurrently, compiling each schema once
+	schemaCache *SchemaCache
+}
+
+// NewValidator returns a Validator using a copy of config, or the default
+// configuration if none is given, so that changing config afterwards has no
+// effect on it
+func NewValidator(conf ...*Config) *Validator {
+	config := NewDefaultConfig()
+	if len(conf) == 1 {
+		copied := *conf[0]
+		config = &copied
+	}
+	return &Validator{
+		config:      config,
+		schemaCache: NewSharedSchemaCache(),
+	}
+}
+
+// Validate validates the resources in input, as ValidateWithCache does,
+// reporting them against fileName, or Config.FileName if it is empty
+func (v *Validator) Validate(input []byte, fileName string) ([]ValidationResult, error) {
+	return ValidateWithSharedCache(input, v.schemaCache, v.fileConfig(fileName))
+}
+
+// ValidateFiles validates each of the files found by discoverer, as the
+// package level ValidateFiles does
+func (v *Validator) ValidateFiles(discoverer FileDiscoverer) ([]ValidationResult, error) {
+	return validateFiles(discoverer, v.schemaCache, v.config)
+}
+
+// CheckResourceSet runs the checks across the resources in results with
+// the Validator's Config, see the package level CheckResourceSet
+func (v *Validator) CheckResourceSet(results []ValidationResult) error {
+	return CheckResourceSet(results, v.config)
+}
+
+// CheckResourceSetWarnings runs the checks across the resources in results
+// which only warn, see the package level CheckResourceSetWarnings
+func (v *Validator) CheckResourceSetWarnings(results []ValidationResult) error {
+	return CheckResourceSetWarnings(results, v.config)
+}
+
+// fileConfig returns the Validator's Config, with the file name set to
+// fileName if it is not empty
+func (v *Validator) fileConfig(fileName string) *Config {
+	if fileName == "" {
+		return v.config
+	}
+	config := *v.config
+	config.FileName = fileName
+	return &config
+}
@@ -0,0 +1,64 @@
+package kubeval
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+func TestValidatorsAreIsolated(t *testing.T) {
+	input := []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: web\ndata:\n  key: c2VjcmV0\n")
+
+	config := NewDefaultConfig()
+	config.SchemaLocation = localSchemaLocation()
+	plain := NewValidator(config)
+
+	config.FormatCheckers = map[string]gojsonschema.FormatChecker{"byte": upperCaseFormat{}}
+	custom := NewValidator(config)
+
+	// Changing the Config afterwards affects neither Validator
+	config.SchemaLocation = "file:///nonexistent"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			results, err := plain.Validate(input, "plain.yaml")
+			if assert.NoError(t, err) && assert.Len(t, results, 1) {
+				assert.Equal(t, "plain.yaml", results[0].FileName)
+				assert.Empty(t, results[0].Errors)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			results, err := custom.Validate(input, "")
+			if assert.NoError(t, err) && assert.Len(t, results, 1) {
+				assert.Equal(t, "stdin", results[0].FileName)
+				assert.Len(t, results[0].Errors, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 1, plain.schemaCache.Len())
+	require.Equal(t, 1, custom.schemaCache.Len())
+	plainSchema, _ := plain.schemaCache.load("v1/Secret")
+	customSchema, _ := custom.schemaCache.load("v1/Secret")
+	assert.True(t, plainSchema != customSchema)
+}
+
+func TestValidateWithCacheLeavesConfigUntouched(t *testing.T) {
+	config := NewDefaultConfig()
+	config.FileName = "chart"
+	config.IgnoreMissingSchemas = true
+
+	results, err := Validate([]byte("---\n# Source: chart/templates/widget.yaml\napiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: thing\n"), config)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "chart/templates/widget.yaml", results[0].FileName)
+	assert.Equal(t, "chart", config.FileName)
+}
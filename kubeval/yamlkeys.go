@@ -0,0 +1,149 @@
+package kubeval
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/xeipuuv/gojsonschema"
+	yamlv2 "gopkg.in/yaml.v2"
+)
+
+// yamlKeyTypeName describes the type YAML gave a mapping key which is not
+// a string
+func yamlKeyTypeName(key interface{}) string {
+	switch key.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a boolean"
+	case int, int64, uint64:
+		return "an integer"
+	case float64:
+		return "a float"
+	default:
+		return fmt.Sprintf("a %T", key)
+	}
+}
+
+// yamlSpelling holds the keys of a YAML mapping as they are written, such
+// as on rather than the true it is read as, with the same for the mappings
+// nested in it, including those in sequences. yaml.v2 only keeps how a
+// scalar is written when decoding it into a string.
+type yamlSpelling struct {
+	keys  map[string]*yamlSpelling
+	items []*yamlSpelling
+}
+
+// UnmarshalYAML meets the yaml.Unmarshaler interface. Keys which cannot be
+// decoded into strings, such as sequences, are left out.
+func (s *yamlSpelling) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var keys map[string]*yamlSpelling
+	if unmarshal(&keys); keys != nil {
+		s.keys = keys
+		return nil
+	}
+	var items []*yamlSpelling
+	if unmarshal(&items); items != nil {
+		s.items = items
+	}
+	return nil
+}
+
+// spell returns how each key of mapping, other than strings, is written,
+// matching them to the keys of the spelling which YAML reads as the same
+// value. Keys written in more than one way which read the same, such as on
+// and yes, cannot be told apart, so are left out.
+func (s *yamlSpelling) spell(mapping yamlv2.MapSlice) map[int]string {
+	spelled := make(map[int]string)
+	if s == nil {
+		return spelled
+	}
+	for i, item := range mapping {
+		if _, ok := item.Key.(string); ok {
+			continue
+		}
+		var candidates []string
+		for key := range s.keys {
+			var value interface{}
+			if yamlv2.Unmarshal([]byte(key), &value) == nil && reflect.DeepEqual(value, item.Key) {
+				candidates = append(candidates, key)
+			}
+		}
+		if len(candidates) == 1 {
+			spelled[i] = candidates[0]
+		}
+	}
+	return spelled
+}
+
+// child returns the spelling of the value under the key written as key, or
+// of the i-th item of a sequence
+func (s *yamlSpelling) child(key string, i int) *yamlSpelling {
+	switch {
+	case s == nil:
+		return nil
+	case s.keys != nil:
+		return s.keys[key]
+	case i < len(s.items):
+		return s.items[i]
+	}
+	return nil
+}
+
+// checkYAMLKeys reports every mapping key in a YAML document which is not a
+// string, such as 80 or on. The conversion to JSON turns these into strings
+// silently, so the schema never sees them, while the API server rejects
+// them. JSON documents, whose keys are always strings, are not checked.
+func checkYAMLKeys(document []byte) []gojsonschema.ResultError {
+	trimmed := bytes.TrimSpace(document)
+	if (bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("["))) && json.Valid(trimmed) {
+		return nil
+	}
+
+	// Decoding into a MapSlice keeps the keys, and nested mappings, in the
+	// order they were written, so errors are reported in that order
+	var root yamlv2.MapSlice
+	if err := yamlv2.Unmarshal(document, &root); err != nil {
+		return nil
+	}
+	// Keys are reported as written, such as on, rather than as the value
+	// YAML reads them as
+	spelling := &yamlSpelling{}
+	yamlv2.Unmarshal(document, spelling)
+
+	var errors []gojsonschema.ResultError
+	var walk func(value interface{}, spelling *yamlSpelling, path []string)
+	walk = func(value interface{}, spelling *yamlSpelling, path []string) {
+		switch typed := value.(type) {
+		case yamlv2.MapSlice:
+			spelled := spelling.spell(typed)
+			for i, item := range typed {
+				written, found := spelled[i]
+				if _, ok := item.Key.(string); ok {
+					written, found = item.Key.(string), true
+				}
+				key := written
+				switch {
+				case item.Key == nil:
+					key = "null"
+				case !found:
+					key = fmt.Sprint(item.Key)
+				}
+				itemPath := append(append([]string{}, path...), key)
+				if _, ok := item.Key.(string); !ok {
+					errors = append(errors, newCheckError("yaml_key", itemPath, key, fmt.Sprintf("Key %s is %s rather than a string, which Kubernetes does not allow; quote it", key, yamlKeyTypeName(item.Key))))
+				}
+				walk(item.Value, spelling.child(written, i), itemPath)
+			}
+		case []interface{}:
+			for i, item := range typed {
+				walk(item, spelling.child("", i), append(append([]string{}, path...), strconv.Itoa(i)))
+			}
+		}
+	}
+	walk(root, spelling, nil)
+	return errors
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"sigs.k8s.io/yaml"
+)
+
+// buildKustomization builds the kustomization in the given directory with
+// `kustomize build`, returning the resulting manifests. Kustomize applies
+// any bases, overlays and components referenced by the kustomization, and
+// runs its generators, such as configMapGenerator and secretGenerator, so
+// the manifests are those which would be applied to the cluster, including
+// the generated ConfigMaps and Secrets.
+//
+// Remote bases, such as git repositories, are fetched by kustomize over
+// the network, so a kustomization referencing any is only built when
+// allowRemoteBases is set.
+func buildKustomization(dir string, allowRemoteBases bool) ([]byte, error) {
+	remotes, err := remoteBases(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(remotes) > 0 && !allowRemoteBases {
+		var errors *multierror.Error
+		for _, remote := range remotes {
+			errors = multierror.Append(errors, fmt.Errorf("Kustomization %s references remote base %s, pass --allow-remote-bases to fetch it", dir, remote))
+		}
+		return nil, errors
+	}
+
+	kustomize, err := kustomizeCommand(dir)
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	kustomize.Stdout = &stdout
+	kustomize.Stderr = &stderr
+	if err := kustomize.Run(); err != nil {
+		if stderr.Len() == 0 {
+			return nil, fmt.Errorf("Failed to build kustomization %s: %s", dir, err)
+		}
+		return nil, kustomizeBuildErrors(dir, stderr.String(), remotes)
+	}
+	return stdout.Bytes(), nil
+}
+
+// kustomizeCommand returns the command building the kustomization in dir:
+// `kustomize build`, or `kubectl kustomize`, which embeds kustomize, where
+// only kubectl is installed
+func kustomizeCommand(dir string) (*exec.Cmd, error) {
+	if _, err := exec.LookPath("kustomize"); err == nil {
+		return exec.Command("kustomize", "build", dir), nil
+	}
+	if _, err := exec.LookPath("kubectl"); err == nil {
+		return exec.Command("kubectl", "kustomize", dir), nil
+	}
+	return nil, fmt.Errorf("Failed to build kustomization %s: neither kustomize nor kubectl was found on the PATH", dir)
+}
+
+// kustomizeBuildErrors converts the output of a failed `kustomize build`
+// into errors reported against the kustomization. Lines which mention one
+// of remotes are reported as a failure to fetch that base, as they are
+// usually down to the network or the repository.
+func kustomizeBuildErrors(dir string, output string, remotes []string) error {
+	var errors *multierror.Error
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		// kubectl reports errors with a lower case prefix
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(line, "Error:"), "error:"))
+		if line == "" {
+			continue
+		}
+		if remote := mentionedRemote(line, remotes); remote != "" {
+			errors = multierror.Append(errors, fmt.Errorf("Failed to fetch remote base %s of kustomization %s: %s", remote, dir, line))
+			continue
+		}
+		errors = multierror.Append(errors, fmt.Errorf("Failed to build kustomization %s: %s", dir, line))
+	}
+	return errors.ErrorOrNil()
+}
+
+// mentionedRemote returns the first of remotes mentioned in line, with or
+// without a query such as ?ref=v1, or an empty string if there is none
+func mentionedRemote(line string, remotes []string) string {
+	for _, remote := range remotes {
+		if strings.Contains(line, strings.SplitN(remote, "?", 2)[0]) {
+			return remote
+		}
+	}
+	return ""
+}
+
+// kustomizationFileNames are the names kustomize looks for in a directory
+var kustomizationFileNames = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// kustomizationFile holds the fields of a kustomization which reference
+// other kustomizations, or files which kustomize may fetch
+type kustomizationFile struct {
+	Resources             []string             `json:"resources"`
+	Bases                 []string             `json:"bases"`
+	Components            []string             `json:"components"`
+	Generators            []string             `json:"generators"`
+	Transformers          []string             `json:"transformers"`
+	Patches               []kustomizationPatch `json:"patches"`
+	PatchesStrategicMerge []string             `json:"patchesStrategicMerge"`
+	PatchesJSON6902       []kustomizationPatch `json:"patchesJson6902"`
+}
+
+// kustomizationPatch is an entry of patches, which may refer to a file
+type kustomizationPatch struct {
+	Path string `json:"path"`
+}
+
+// references returns the entries of the kustomization which may refer to
+// a kustomization or a file, local or remote. Inline generator,
+// transformer and patch configs are left out.
+func (k kustomizationFile) references() []string {
+	var entries []string
+	for _, list := range [][]string{k.Resources, k.Bases, k.Components, k.Generators, k.Transformers, k.PatchesStrategicMerge} {
+		entries = append(entries, list...)
+	}
+	for _, patch := range append(append([]kustomizationPatch{}, k.Patches...), k.PatchesJSON6902...) {
+		entries = append(entries, patch.Path)
+	}
+	var references []string
+	for _, entry := range entries {
+		if entry != "" && !strings.Contains(entry, "\n") {
+			references = append(references, entry)
+		}
+	}
+	return references
+}
+
+// remoteBases returns the remote bases, components, generators,
+// transformers and patches referenced by the kustomization in dir, or by
+// any local kustomization it references in turn
+func remoteBases(dir string) ([]string, error) {
+	var remotes []string
+	visited := make(map[string]bool)
+
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		if visited[abs] {
+			return nil
+		}
+		visited[abs] = true
+
+		var contents []byte
+		for _, name := range kustomizationFileNames {
+			contents, err = ioutil.ReadFile(filepath.Join(dir, name))
+			if err == nil {
+				break
+			}
+		}
+		if contents == nil {
+			return fmt.Errorf("Failed to build kustomization %s: no kustomization.yaml found", dir)
+		}
+		var k kustomizationFile
+		if err := yaml.Unmarshal(contents, &k); err != nil {
+			return fmt.Errorf("Failed to build kustomization %s: %s", dir, err)
+		}
+
+		for _, entry := range k.references() {
+			path := filepath.Join(dir, entry)
+			info, err := os.Stat(path)
+			switch {
+			case err == nil && info.IsDir():
+				if err := walk(path); err != nil {
+					return err
+				}
+			case err != nil && isRemoteReference(entry):
+				remotes = append(remotes, entry)
+			}
+		}
+		return nil
+	}
+
+	if err := walk(dir); err != nil {
+		return nil, err
+	}
+	return remotes, nil
+}
+
+// isRemoteReference returns whether an entry of a kustomization which is
+// not a local path is a remote base, such as a URL, git@host:org/repo, or
+// github.com/org/repo//path?ref=v1
+func isRemoteReference(entry string) bool {
+	if strings.Contains(entry, "://") || strings.HasPrefix(entry, "git@") {
+		return true
+	}
+	host := strings.SplitN(entry, "/", 2)[0]
+	return strings.Contains(host, ".") && !strings.HasPrefix(host, ".") && strings.Contains(entry, "/")
+}
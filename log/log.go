@@ -2,29 +2,64 @@ package log
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/fatih/color"
 	multierror "github.com/hashicorp/go-multierror"
 )
 
-func Success(message ...string) {
-	green := color.New(color.FgGreen).SprintFunc()
-	fmt.Printf("%s - %v\n", green("PASS"), strings.Join(message, " "))
+// Printer prints messages in the same way as Success, Warn and Error, but
+// colors them according to its own NoColor rather than the global setting
+// of the color package
+type Printer struct {
+	NoColor bool
 }
 
-func Warn(message ...string) {
-	yellow := color.New(color.FgYellow).SprintFunc()
-	fmt.Printf("%s - %v\n", yellow("WARN"), strings.Join(message, " "))
+// sprint returns s in the color attr, unless color is disabled
+func (p Printer) sprint(attr color.Attribute, s string) string {
+	c := color.New(attr)
+	if p.NoColor {
+		c.DisableColor()
+	} else {
+		c.EnableColor()
+	}
+	return c.Sprint(s)
 }
 
-func Error(message error) {
+func (p Printer) Success(message ...string) {
+	fmt.Printf("%s - %v\n", p.sprint(color.FgGreen, "PASS"), strings.Join(message, " "))
+}
+
+func (p Printer) Warn(message ...string) {
+	fmt.Printf("%s - %v\n", p.sprint(color.FgYellow, "WARN"), strings.Join(message, " "))
+}
+
+func (p Printer) Error(message error) {
 	if merr, ok := message.(*multierror.Error); ok {
 		for _, serr := range merr.Errors {
-			Error(serr)
+			p.Error(serr)
 		}
 	} else {
-		red := color.New(color.FgRed).SprintFunc()
-		fmt.Printf("%s - %v\n", red("ERR "), message)
+		fmt.Printf("%s - %v\n", p.sprint(color.FgRed, "ERR "), message)
 	}
 }
+
+func Success(message ...string) {
+	Printer{NoColor: color.NoColor}.Success(message...)
+}
+
+func Warn(message ...string) {
+	Printer{NoColor: color.NoColor}.Warn(message...)
+}
+
+func Error(message error) {
+	Printer{NoColor: color.NoColor}.Error(message)
+}
+
+// Fix reports a change made, or to be made, by kubeval --fix. It prints to
+// stderr, leaving stdout for the fixed manifests when they are written there.
+func Fix(message ...string) {
+	p := Printer{NoColor: color.NoColor}
+	fmt.Fprintf(os.Stderr, "%s - %v\n", p.sprint(color.FgCyan, "FIX "), strings.Join(message, " "))
+}
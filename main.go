@@ -9,30 +9,90 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"os/signal"
+	"os/user"
 	"path/filepath"
-	"regexp"
-	"runtime"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/fatih/color"
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/xeipuuv/gojsonschema"
 
 	"github.com/instrumenta/kubeval/kubeval"
 	"github.com/instrumenta/kubeval/log"
 )
 
 var (
-	version                 = "dev"
-	commit                  = "none"
-	date                    = "unknown"
-	directories             = []string{}
+	version             = "dev"
+	commit              = "none"
+	date                = "unknown"
+	directories         = []string{}
 	ignoredPathPatterns = []string{}
 
-	// forceColor tells kubeval to use colored output even if
-	// stdout is not a TTY
-	forceColor bool
+	// failOnNoFiles tells kubeval to fail if no files were found to
+	// validate, for example because a directory contains no YAML
+	failOnNoFiles bool
+
+	// helmChart is the path to a Helm chart to render and validate, using
+	// the values files in helmValues
+	helmChart  string
+	helmValues = []string{}
+
+	// kustomization is the path to a directory holding a kustomization to
+	// build and validate
+	kustomization string
+
+	// environment selects the overlay of the kustomization, or the values
+	// file of the Helm chart, for an environment such as prod, found by
+	// following envOverlayPattern or envValuesPattern
+	environment       string
+	envOverlayPattern string
+	envValuesPattern  string
+
+	// allowRemoteBases lets kustomize fetch the remote bases, such as git
+	// repositories, referenced by the kustomization
+	allowRemoteBases bool
+
+	// diffInput tells kubeval its input is a unified diff, such as the
+	// output of kubectl diff, rather than manifests
+	diffInput bool
+
+	// gitURL is a git repository to clone and validate the gitPaths of,
+	// at gitRef or the default branch
+	gitURL   string
+	gitRef   string
+	gitPaths = []string{}
+
+	// metricsFile is the path to write metrics about the run to, in the
+	// Prometheus text format
+	metricsFile string
+
+	// changedOnly is the path of a manifest of the hashes of files which
+	// passed a previous run, see kubeval.HashManifest
+	changedOnly string
+
+	// auditLog is the path of an append-only log to which a record of the
+	// run is appended, as a line of JSON
+	auditLog string
+
+	// cleanups are run before kubeval exits, see addCleanup and exit
+	cleanups     []func()
+	cleanupsLock sync.Mutex
+
+	// fix makes safe, mechanical fixes to the manifests validated, writing
+	// them back, while fixDryRun only reports the fixes it would make. See
+	// kubeval.FixManifests
+	fix       bool
+	fixDryRun bool
+
+	// ciPreset enables a stable set of defaults suited to running kubeval
+	// in continuous integration, see applyCIPreset
+	ciPreset bool
 
 	config = kubeval.NewDefaultConfig()
 )
@@ -42,7 +102,36 @@ var RootCmd = &cobra.Command{
 	Short:   "Validate a Kubernetes YAML file against the relevant schema",
 	Long:    `Validate a Kubernetes YAML file against the relevant schema`,
 	Version: fmt.Sprintf("Version: %s\nCommit: %s\nDate: %s\n", version, commit, date),
+	// Arguments are files to validate rather than subcommands, such as serve
+	Args: cobra.ArbitraryArgs,
 	Run: func(cmd *cobra.Command, args []string) {
+		if ciPreset {
+			applyCIPreset(cmd)
+		}
+
+		// make sure any cleanups, such as removing a git clone, also run
+		// when kubeval is interrupted
+		interrupts := make(chan os.Signal, 1)
+		signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-interrupts
+			exit(1)
+		}()
+
+		if err := checkExitOn(); err != nil {
+			log.Error(err)
+			exit(1)
+		}
+		if err := checkFixFlags(args); err != nil {
+			log.Error(err)
+			exit(1)
+		}
+
+		if err := config.CheckKindFilters(); err != nil {
+			log.Error(err)
+			exit(1)
+		}
+
 		if config.IgnoreMissingSchemas && !config.Quiet {
 			log.Warn("Set to ignore missing schemas")
 		}
@@ -57,167 +146,492 @@ var RootCmd = &cobra.Command{
 			}
 		}
 
+		start := time.Now()
+		var allResults []kubeval.ValidationResult
 		success := true
-		windowsStdinIssue := false
-		outputManager := kubeval.GetOutputManager(config.OutputFormat)
-
-		stat, err := os.Stdin.Stat()
-		if err != nil {
-			// Stat() will return an error on Windows in both Powershell and
-			// console until go1.9 when nothing is passed on stdin.
-			// See https://github.com/golang/go/issues/14853.
-			if runtime.GOOS != "windows" {
-				log.Error(err)
-				os.Exit(1)
-			} else {
-				windowsStdinIssue = true
-			}
-		}
-		// Assert that colors will definitely be used if requested
-		if forceColor {
+		outputManager := kubeval.GetOutputManager(config.OutputFormat, config)
+		// Assert that colors will definitely be used if requested. The
+		// output managers follow config.ForceColor, this covers the log
+		// messages of the command itself
+		if config.ForceColor {
 			color.NoColor = false
 		}
 		// We detect whether we have anything on stdin to process if we have no arguments
-		// or if the argument is a -
-		notty := (stat.Mode() & os.ModeCharDevice) == 0
-		noFileOrDirArgs := (len(args) < 1 || args[0] == "-") && len(directories) < 1
-		if noFileOrDirArgs && !windowsStdinIssue && notty {
+		// or if the only argument is a -. A - among other files or directories is
+		// validated in its place among them
+		noFileOrDirArgs := (len(args) < 1 || (len(args) == 1 && args[0] == "-")) && len(directories) < 1 && gitURL == ""
+		if helmChart != "" && kustomization != "" {
+			log.Error(errors.New("Only one of --helm-chart and --kustomize can be used"))
+			exit(1)
+		}
+		if environment != "" && helmChart == "" && kustomization == "" {
+			log.Error(errors.New("--env selects an overlay of --kustomize or a values file of --helm-chart, so needs one of them"))
+			exit(1)
+		}
+		if helmChart != "" || kustomization != "" {
+			var rendered []byte
+			var err error
+			if helmChart != "" {
+				values := helmValues
+				if environment != "" {
+					var envValues string
+					envValues, err = environmentValues(helmChart, envValuesPattern, environment)
+					values = append(append([]string{}, helmValues...), envValues)
+				}
+				if err == nil {
+					rendered, err = renderHelmChart(helmChart, values)
+				}
+				config.FileName = helmChart
+			} else {
+				dir := kustomization
+				if environment != "" {
+					dir, err = environmentOverlay(kustomization, envOverlayPattern, environment)
+				}
+				if err == nil {
+					rendered, err = buildKustomization(dir, allowRemoteBases)
+				}
+				config.FileName = dir
+			}
+			config.Environment = environment
+			if err != nil {
+				log.Error(err)
+				exit(1)
+			}
+			schemaCache := kubeval.NewSchemaCache()
+			if config.Prefetch {
+				kubeval.PrefetchSchemas([][]byte{rendered}, schemaCache, config)
+			}
+			results, err := kubeval.ValidateWithCache(rendered, schemaCache, config)
+			if err != nil {
+				log.Error(err)
+				success = false
+			}
+			success = success && !hasFailures(results)
+			allResults = results
+
+			for _, r := range results {
+				err = outputManager.Put(r)
+				if err != nil {
+					log.Error(err)
+					exit(1)
+				}
+			}
+
+			err = kubeval.CheckResourceSet(results, config)
+			if err != nil {
+				log.Error(err)
+				success = false
+			}
+			warnResourceSet(results)
+		} else if noFileOrDirArgs && readsStdin(args) {
 			buffer := new(bytes.Buffer)
 			_, err := io.Copy(buffer, os.Stdin)
 			if err != nil {
 				log.Error(err)
-				os.Exit(1)
+				exit(1)
 			}
 			schemaCache := kubeval.NewSchemaCache()
 			config.FileName = viper.GetString("filename")
-			results, err := kubeval.ValidateWithCache(buffer.Bytes(), schemaCache, config)
+			// the fixed manifests are written to stdout, so are not validated
+			if fix || fixDryRun {
+				count, err := fixStdin(buffer.Bytes())
+				if err != nil {
+					log.Error(err)
+					exit(1)
+				}
+				if fixDryRun {
+					exit(fixDryRunExitCode(count))
+				}
+				exit(0)
+			}
+			if config.Prefetch && !diffInput {
+				kubeval.PrefetchSchemas([][]byte{buffer.Bytes()}, schemaCache, config)
+			}
+			results, err := validateContents(buffer.Bytes(), schemaCache)
 			if err != nil {
 				log.Error(err)
-				os.Exit(1)
+				exit(1)
 			}
-			success = !hasErrors(results)
+			success = !hasFailures(results)
+			allResults = results
 
 			for _, r := range results {
 				err = outputManager.Put(r)
 				if err != nil {
 					log.Error(err)
-					os.Exit(1)
+					exit(1)
 				}
 			}
+
+			err = kubeval.CheckResourceSet(results, config)
+			if err != nil {
+				log.Error(err)
+				success = false
+			}
+			warnResourceSet(results)
 		} else {
-			if len(args) < 1 && len(directories) < 1 {
+			if len(args) < 1 && len(directories) < 1 && gitURL == "" {
 				log.Error(errors.New("You must pass at least one file as an argument, or at least one directory to the directories flag"))
-				os.Exit(1)
+				exit(1)
+			}
+			discoverer := kubeval.NewFilesystemDiscoverer(args, directories, ignoredPathPatterns)
+			if gitURL != "" {
+				if len(args) > 0 || len(directories) > 0 {
+					log.Error(errors.New("Files and directories cannot be passed along with --git, use --path to select paths within the repository"))
+					exit(1)
+				}
+				dir, err := gitCloneDir(gitURL)
+				if err != nil {
+					log.Error(err)
+					exit(1)
+				}
+				addCleanup(func() {
+					os.RemoveAll(dir)
+				})
+				if err := cloneGitRepository(dir, gitURL, gitRef); err != nil {
+					log.Error(err)
+					exit(1)
+				}
+				discoverer = &gitDiscoverer{dir: dir, paths: gitPaths, ignoredPathPatterns: ignoredPathPatterns}
 			}
 			schemaCache := kubeval.NewSchemaCache()
-			files, err := aggregateFiles(args)
+			files, err := discoverer.Discover()
 			if err != nil {
 				log.Error(err)
 				success = false
 			}
+			files = withStdinFile(files)
+			if fix || fixDryRun {
+				var count int
+				files, count, err = fixFiles(files)
+				if err != nil {
+					log.Error(err)
+					exit(1)
+				}
+				if fixDryRun {
+					exit(fixDryRunExitCode(count))
+				}
+			}
+			if len(files) == 0 && failOnNoFiles {
+				log.Error(errors.New("No files were found to validate"))
+				success = false
+			}
+			var hashManifest *kubeval.HashManifest
+			var fileHashes map[string]string
+			if changedOnly != "" {
+				hashManifest, err = kubeval.ReadHashManifest(changedOnly)
+				if err != nil {
+					log.Error(err)
+					exit(1)
+				}
+				files, fileHashes = kubeval.SelectChangedFiles(files, hashManifest, config)
+			}
+			// With --diff, each file is prefetched as it is validated
+			if config.Prefetch && !diffInput {
+				files = kubeval.PrefetchFiles(files, schemaCache, config)
+			}
 
 			var aggResults []kubeval.ValidationResult
-			for _, fileName := range files {
-				filePath, _ := filepath.Abs(fileName)
-				fileContents, err := ioutil.ReadFile(filePath)
+			failedFiles := make(map[string]bool)
+			for _, file := range files {
+				fileContents, err := file.Read()
 				if err != nil {
-					log.Error(fmt.Errorf("Could not open file %v", fileName))
+					log.Error(fmt.Errorf("Could not open file %v", file.Name))
 					earlyExit()
 					success = false
+					failedFiles[file.Name] = true
 					continue
 				}
-				config.FileName = fileName
-				results, err := kubeval.ValidateWithCache(fileContents, schemaCache, config)
+				config.FileName = file.Name
+				results, err := validateContents(fileContents, schemaCache)
+				if _, untrusted := err.(*kubeval.SchemaChecksumError); untrusted {
+					log.Error(err)
+					exit(1)
+				}
 				if err != nil {
 					log.Error(err)
 					earlyExit()
 					success = false
+					failedFiles[file.Name] = true
 					continue
 				}
+				if hasFailures(results) {
+					failedFiles[file.Name] = true
+				}
 
 				for _, r := range results {
 					err := outputManager.Put(r)
 					if err != nil {
 						log.Error(err)
-						os.Exit(1)
+						exit(1)
 					}
 				}
 
 				aggResults = append(aggResults, results...)
 			}
 
-			// only use result of hasErrors check if `success` is currently truthy
-			success = success && !hasErrors(aggResults)
+			err = kubeval.CheckResourceSet(aggResults, config)
+			if err != nil {
+				log.Error(err)
+				success = false
+			}
+			warnResourceSet(aggResults)
+
+			// only use result of hasFailures check if `success` is currently truthy
+			success = success && !hasFailures(aggResults)
+			allResults = aggResults
+
+			if changedOnly != "" {
+				updated := kubeval.UpdateHashManifest(hashManifest, fileHashes, failedFiles, config)
+				if err := kubeval.WriteHashManifest(changedOnly, updated); err != nil {
+					log.Error(err)
+					exit(1)
+				}
+			}
+		}
+
+		warnSkippedAPIGroups(allResults)
+
+		if metricsFile != "" {
+			if err := writeMetricsFile(metricsFile, allResults, time.Since(start)); err != nil {
+				log.Error(err)
+				exit(1)
+			}
+		}
+
+		if auditLog != "" {
+			if err := appendAuditRecord(auditLog, allResults, start, success); err != nil {
+				log.Error(err)
+				exit(1)
+			}
 		}
 
 		// flush any final logs which may be sitting in the buffer
-		err = outputManager.Flush()
-		if err != nil {
+		if err := outputManager.Flush(); err != nil {
 			log.Error(err)
-			os.Exit(1)
+			exit(1)
 		}
 
 		if !success {
-			os.Exit(1)
+			exit(1)
 		}
+		exit(0)
 	},
 }
 
-// hasErrors returns truthy if any of the provided results
-// contain errors.
-func hasErrors(res []kubeval.ValidationResult) bool {
-	for _, r := range res {
-		if len(r.Errors) > 0 {
-			return true
+// readsStdin returns whether to read manifests from stdin, which is when -
+// is passed, or when stdin is anything other than a terminal. That includes
+// pipes, named pipes and the process substitution of shells, as well as
+// redirected files.
+func readsStdin(args []string) bool {
+	if len(args) > 0 && args[0] == "-" {
+		return true
+	}
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		// Stat() will return an error on Windows in both Powershell and
+		// console until go1.9 when nothing is passed on stdin, and wherever
+		// stdin has been closed.
+		// See https://github.com/golang/go/issues/14853.
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice == 0
+}
+
+// withStdinFile replaces any file named - with one which reads manifests
+// from stdin, reported under the name given by --filename, so that stdin
+// can be validated along with other files, in the order they were passed.
+// Stdin is only read once, however many times - is passed.
+func withStdinFile(files []kubeval.File) []kubeval.File {
+	var contents []byte
+	var readErr error
+	var read sync.Once
+	stdin := kubeval.File{
+		Name: viper.GetString("filename"),
+		Read: func() ([]byte, error) {
+			// Files may be read concurrently, such as when prefetching
+			read.Do(func() {
+				contents, readErr = ioutil.ReadAll(os.Stdin)
+			})
+			return contents, readErr
+		},
+		Stdin: true,
+	}
+	for i, file := range files {
+		if file.Name == "-" {
+			files[i] = stdin
 		}
 	}
-	return false
+	return files
+}
+
+// validateContents validates the manifests in contents or, with --diff,
+// the new side of each file in the diff
+func validateContents(contents []byte, schemaCache map[string]*gojsonschema.Schema) ([]kubeval.ValidationResult, error) {
+	if !diffInput {
+		return kubeval.ValidateWithCache(contents, schemaCache, config)
+	}
+	files, warnings, err := kubeval.ParseDiff(contents)
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range warnings {
+		log.Warn(warning)
+	}
+	return kubeval.ValidateFiles(kubeval.StaticDiscoverer(files), schemaCache, config)
+}
+
+// appendAuditRecord appends a record of the run to the audit log at path,
+// creating it if needed. The log is only ever appended to.
+func appendAuditRecord(path string, results []kubeval.ValidationResult, start time.Time, success bool) error {
+	record := kubeval.NewAuditRecord(results, config, start, currentUser(), version, success)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("Failed to write audit log %s: %s", path, err)
+	}
+	err = kubeval.WriteAuditRecord(file, record)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to write audit log %s: %s", path, err)
+	}
+	return nil
+}
+
+// currentUser returns the name of the user running kubeval, for the audit
+// log, falling back to the environment where the user cannot be looked up
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
+
+// writeMetricsFile writes metrics about the run to path. The metrics are
+// written to a temporary file which is then renamed, so that a collector
+// never reads a partially written file.
+func writeMetricsFile(path string, results []kubeval.ValidationResult, duration time.Duration) error {
+	temp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("Failed to write metrics file %s: %s", path, err)
+	}
+	defer os.Remove(temp.Name())
+
+	err = kubeval.WriteMetrics(temp, results, duration, config)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(temp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to write metrics file %s: %s", path, err)
+	}
+	return nil
 }
 
-// isIgnored returns whether the specified filename should be ignored.
-func isIgnored(path string) (bool, error) {
-	for _, p := range ignoredPathPatterns {
-		m, err := regexp.MatchString(p, path)
-		if err != nil {
-			return false, err
+// applyCIPreset enables the defaults used by the --ci flag. Any of these
+// flags set explicitly on the command line take precedence:
+//
+//	--strict
+//	--quiet
+//	--output junit
+//	--fail-on-no-files
+func applyCIPreset(cmd *cobra.Command) {
+	if !cmd.Flags().Changed("strict") {
+		config.Strict = true
+	}
+	if !cmd.Flags().Changed("quiet") {
+		config.Quiet = true
+	}
+	if !cmd.Flags().Changed("output") {
+		config.OutputFormat = "junit"
+	}
+	if !cmd.Flags().Changed("fail-on-no-files") {
+		failOnNoFiles = true
+	}
+}
+
+// hasFailures returns truthy if any of the provided results
+// have one of the statuses passed to --exit-on.
+func hasFailures(res []kubeval.ValidationResult) bool {
+	return kubeval.HasFailures(res, config)
+}
+
+// checkExitOn returns an error if --exit-on contains an unknown status
+func checkExitOn() error {
+	for _, status := range config.ExitOn {
+		known := false
+		for _, s := range kubeval.ValidStatuses() {
+			known = known || s == status
 		}
-		if m {
-			return true, nil
+		if !known {
+			return fmt.Errorf("Unknown status '%s' passed to --exit-on. Options are: %v", status, kubeval.ValidStatuses())
 		}
 	}
-	return false, nil
+	return nil
 }
 
-func aggregateFiles(args []string) ([]string, error) {
-	files := make([]string, len(args))
-	copy(files, args)
+// warnResourceSet reports the problems found by the checks across
+// resources which are only warnings, and so do not fail the run
+func warnResourceSet(results []kubeval.ValidationResult) {
+	err := kubeval.CheckResourceSetWarnings(results, config)
+	if merr, ok := err.(*multierror.Error); ok {
+		for _, e := range merr.Errors {
+			log.Warn(e.Error())
+		}
+	} else if err != nil {
+		log.Warn(err.Error())
+	}
+}
 
-	var allErrors *multierror.Error
-	for _, directory := range directories {
-		err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			ignored, err := isIgnored(path)
-			if err != nil {
-				return err
-			}
-			if !info.IsDir() && (strings.HasSuffix(info.Name(), ".yaml") || strings.HasSuffix(info.Name(), ".yml")) && !ignored {
-				files = append(files, path)
-			}
-			return nil
-		})
-		if err != nil {
-			allErrors = multierror.Append(allErrors, err)
+// warnSkippedAPIGroups reports how many resources were skipped for being
+// outside the API groups passed to --api-groups
+func warnSkippedAPIGroups(results []kubeval.ValidationResult) {
+	if len(config.APIGroups) == 0 || config.Quiet {
+		return
+	}
+	skipped := 0
+	for _, r := range results {
+		if r.Skipped && !config.SelectsAPIGroup(r.APIVersion) {
+			skipped++
 		}
 	}
+	if skipped > 0 {
+		log.Warn(fmt.Sprintf("Resources skipped as outside the API groups passed to --api-groups (%s): %d", strings.Join(config.APIGroups, ", "), skipped))
+	}
+}
 
-	return files, allErrors.ErrorOrNil()
+// addCleanup registers a cleanup to run before kubeval exits. It is safe to
+// call while exit may be called concurrently, such as on an interrupt.
+func addCleanup(cleanup func()) {
+	cleanupsLock.Lock()
+	defer cleanupsLock.Unlock()
+	cleanups = append(cleanups, cleanup)
+}
+
+// exit runs any cleanups and then exits with the given code. It must be
+// used in place of os.Exit, which would skip them. The lock is held until
+// kubeval exits, so a concurrent call, such as on an interrupt, waits
+// rather than running the cleanups again.
+func exit(code int) {
+	cleanupsLock.Lock()
+	for _, cleanup := range cleanups {
+		cleanup()
+	}
+	os.Exit(code)
 }
 
 func earlyExit() {
 	if config.ExitOnError {
-		os.Exit(1)
+		exit(1)
 	}
 }
 
@@ -237,12 +651,31 @@ func init() {
 	}
 	RootCmd.Use = fmt.Sprintf("%s <file> [file...]", rootCmdName)
 	kubeval.AddKubevalFlags(RootCmd, config)
-	RootCmd.Flags().BoolVarP(&forceColor, "force-color", "", false, "Force colored output even if stdout is not a TTY")
+	RootCmd.Flags().BoolVarP(&config.ForceColor, "force-color", "", false, "Force colored output even if stdout is not a TTY")
+	RootCmd.Flags().BoolVar(&failOnNoFiles, "fail-on-no-files", false, "Fail if no files were found to validate")
+	RootCmd.Flags().StringVar(&helmChart, "helm-chart", "", "Path to a Helm chart to render with helm template and validate")
+	RootCmd.Flags().StringSliceVar(&helmValues, "values", []string{}, "A comma-separated list of values files to use when rendering the Helm chart")
+	RootCmd.Flags().BoolVar(&allowRemoteBases, "allow-remote-bases", false, "Allow the kustomization passed to --kustomize to reference remote bases, such as git repositories, which kustomize fetches over the network")
+	RootCmd.Flags().StringVar(&kustomization, "kustomize", "", "Path to a directory holding a kustomization to build with kustomize build and validate, including any bases, overlays and components")
+	RootCmd.Flags().StringVar(&environment, "env", "", "Environment, such as prod, to validate the --kustomize overlay or --helm-chart values file of, found by --env-overlay-pattern or --env-values-pattern")
+	RootCmd.Flags().StringVar(&envOverlayPattern, "env-overlay-pattern", "overlays/{env}", "Path of the overlay for the environment passed to --env, relative to the directory passed to --kustomize")
+	RootCmd.Flags().StringVar(&envValuesPattern, "env-values-pattern", "values-{env}.yaml", "Path of the values file for the environment passed to --env, relative to the chart passed to --helm-chart. Used after any --values")
+	RootCmd.Flags().StringSliceVar(&config.ExitOn, "exit-on", kubeval.DefaultExitOn(), fmt.Sprintf("A comma-separated list of result statuses which cause a non-zero exit code. Options are: %v", kubeval.ValidStatuses()))
+	RootCmd.Flags().BoolVar(&diffInput, "diff", false, "Treat the input as a unified diff, such as the output of kubectl diff, and validate the new side of each file")
+	RootCmd.Flags().StringVar(&gitURL, "git", "", "URL of a git repository to shallow clone and validate, instead of local files")
+	RootCmd.Flags().StringVar(&gitRef, "git-ref", "", "Branch, tag or commit of the git repository to validate. Defaults to the default branch")
+	RootCmd.Flags().StringSliceVar(&gitPaths, "path", []string{"."}, "A comma-separated list of files or directories within the git repository to validate")
+	RootCmd.Flags().StringVar(&metricsFile, "metrics-file", "", "Path to write metrics about the run to, in the Prometheus text format read by the node_exporter textfile collector")
+	RootCmd.Flags().StringVar(&changedOnly, "changed-only", "", "Path of a manifest of file hashes from a previous run. Only files whose contents have changed since are validated, and the manifest is updated afterwards")
+	RootCmd.Flags().StringVar(&auditLog, "audit-log", "", "Path of a log to append a record of the run to, as a line of JSON holding the time, user, number of files, outcome, kubeval version and a hash of the config")
+	RootCmd.Flags().BoolVar(&fix, "fix", false, "Fix trivially fixable issues, writing the fixed manifests back to their files, or to stdout when read from stdin, before validating them: lower case apiVersions, remove the status of built-in kinds and add --default-namespace to namespaced resources without one")
+	RootCmd.Flags().BoolVar(&fixDryRun, "fix-dry-run", false, "Report the fixes --fix would make without making them, failing if there are any")
+	RootCmd.Flags().BoolVar(&ciPreset, "ci", false, "Use defaults suited to continuous integration: --strict --quiet --output junit --fail-on-no-files. Explicitly set flags take precedence")
 	RootCmd.SetVersionTemplate(`{{.Version}}`)
 	RootCmd.Flags().StringSliceVarP(&directories, "directories", "d", []string{}, "A comma-separated list of directories to recursively search for YAML documents")
 	RootCmd.Flags().StringSliceVarP(&ignoredPathPatterns, "ignored-path-patterns", "i", []string{}, "A comma-separated list of regular expressions specifying paths to ignore")
 	RootCmd.Flags().StringSliceVarP(&ignoredPathPatterns, "ignored-filename-patterns", "", []string{}, "An alias for ignored-path-patterns")
-	
+
 	viper.SetEnvPrefix("KUBEVAL")
 	viper.AutomaticEnv()
 	viper.BindPFlag("schema_location", RootCmd.Flags().Lookup("schema-location"))
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/instrumenta/kubeval/kubeval"
+	"github.com/instrumenta/kubeval/log"
+)
+
+// socketPath is the path of the Unix domain socket to serve on
+var socketPath string
+
+// serveCmd runs kubeval as a long lived process, validating manifests sent
+// to it over a Unix domain socket, so that editors can get results without
+// starting kubeval and loading schemas for every change
+var serveCmd = &cobra.Command{
+	Use:   "serve --socket <path>",
+	Short: "Validate manifests sent over a Unix domain socket",
+	Long: `Validate manifests sent over a Unix domain socket.
+
+Each request is a single line of JSON of the form
+{"filename": "deployment.yaml", "content": "<manifest>"}, and is answered
+with a single line of JSON of the form {"results": [...], "error": "..."},
+where each result has the fields of the json output. Schemas are cached for
+the life of the process.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if socketPath == "" {
+			log.Error(fmt.Errorf("A path must be passed to --socket"))
+			exit(1)
+		}
+
+		if err := config.CheckKindFilters(); err != nil {
+			log.Error(err)
+			exit(1)
+		}
+
+		if config.InsecureSkipTLSVerify {
+			http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{
+				InsecureSkipVerify: true,
+			}
+		}
+
+		listener, err := listenUnix(socketPath)
+		if err != nil {
+			log.Error(err)
+			exit(1)
+		}
+		// The socket is removed on exit rather than by closing the listener,
+		// which would end Serve with an error
+		addCleanup(func() {
+			os.Remove(socketPath)
+		})
+
+		interrupts := make(chan os.Signal, 1)
+		signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-interrupts
+			exit(0)
+		}()
+
+		if !config.Quiet {
+			fmt.Printf("Listening on %s\n", socketPath)
+		}
+		if err := kubeval.NewServer(config).Serve(listener); err != nil {
+			log.Error(err)
+			exit(1)
+		}
+	},
+}
+
+// listenUnix listens on a Unix domain socket at path, first removing a
+// socket left behind by a previous run. Any other file at path is left in
+// place and reported as an error.
+func listenUnix(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to listen on %s: %s", path, err)
+	}
+	return listener, nil
+}
+
+func init() {
+	kubeval.AddKubevalFlags(serveCmd, config)
+	serveCmd.Flags().StringVar(&socketPath, "socket", "", "Path of the Unix domain socket to listen on")
+	RootCmd.AddCommand(serveCmd)
+}